@@ -0,0 +1,50 @@
+// Command apikey generates an API key and prints the entry to add to the server's API_KEYS_FILE
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"agent/internal/auth"
+)
+
+func main() {
+	id := flag.String("id", "", "client ID recorded on the emergencies it reports, e.g. mobile-app")
+	name := flag.String("name", "", "human-readable client name")
+	scopes := flag.String("scopes", string(auth.ScopeCaller), "comma-separated scopes: caller, dispatcher, clinician, admin")
+	flag.Parse()
+
+	if *id == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entry := auth.APIKey{ID: *id, Name: *name}
+	for _, value := range strings.Split(*scopes, ",") {
+		scope, err := auth.ParseScope(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		entry.Scopes = append(entry.Scopes, scope)
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	entry.KeyHash = auth.HashKey(key)
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The key goes to the client once; only the hash is kept on the server
+	fmt.Fprintf(os.Stderr, "API key (shown once, give it to the client): %s\n\n", key)
+	fmt.Fprintln(os.Stderr, "Add this entry to the API_KEYS_FILE array:")
+	fmt.Println(string(data))
+}
@@ -7,12 +7,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"agent/internal/ai"
 	"agent/internal/api"
+	"agent/internal/auth"
 	"agent/internal/config"
+	"agent/internal/metrics"
+	"agent/internal/redact"
 	"agent/internal/tools"
 	"agent/internal/tools/ambulance"
 	"agent/internal/tools/booking"
@@ -46,11 +50,17 @@ func main() {
 		log.Fatalf("Failed to set up components: %v", err)
 	}
 
+	// Delete caller audio left with third-party file stores by failed or interrupted requests
+	components.audioProcessor.ModelProvider().StartFileSweeper(ctx,
+		time.Duration(config.GetInt("FILE_SWEEP_INTERVAL_MINUTES", 5))*time.Minute,
+		time.Duration(config.GetInt("FILE_SWEEP_MAX_AGE_MINUTES", 15))*time.Minute,
+	)
+
 	// Create HTTP server
 	port := config.GetInt("PORT", defaultPort)
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      components.mux,
+		Handler:      components.handler,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -76,16 +86,21 @@ func main() {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
 
+	// Cancel background jobs; their results would not survive the restart anyway
+	components.jobs.Close()
+
 	log.Println("Server gracefully stopped")
 }
 
 // Components holds all the application components
 type Components struct {
 	mux              *http.ServeMux
+	handler          http.Handler
 	toolRegistry     *tools.DefaultToolRegistry
 	locationTool     *location.LocationTool
 	audioProcessor   *api.AudioProcessor
 	emergencyHandler *api.EmergencyHandler
+	jobs             *api.JobQueue
 }
 
 // setupComponents initializes all application components
@@ -123,21 +138,42 @@ func setupComponents() (*Components, error) {
 	}
 	classifier := triage.NewRuleBasedClassifier(classifierConfig)
 
+	// Load the AI model settings shared by all processors
+	settings := loadModelSettings()
+
 	// Create audio processor with AI model configuration
-	audioProcessor, err := createAudioProcessor()
+	audioProcessor, err := createAudioProcessor(settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create audio processor: %w", err)
 	}
 
 	// Create text processor with AI model configuration
-	textProcessor, err := createTextProcessor()
+	textProcessor, err := createTextProcessor(settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create text processor: %w", err)
 	}
 
+	// Create image processor for wound and scene photos
+	imageProcessor, err := createImageProcessor(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image processor: %w", err)
+	}
+
 	// Create summary generator
 	summaryGenerator := &api.DefaultSummaryGenerator{}
 
+	// Persist every coordinated emergency; an empty directory keeps them in memory only
+	incidents, err := api.NewFileIncidentStore(config.Get("INCIDENT_STORE_DIR", "data/incidents"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open incident store: %w", err)
+	}
+
+	// Patient profiles referenced by emergency requests
+	profiles, err := api.NewFileProfileStore(config.Get("PROFILE_STORE_DIR", "data/profiles"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open profile store: %w", err)
+	}
+
 	// Create coordinator
 	coordinatorConfig := api.CoordinatorConfig{
 		MaxConcurrentTools: config.GetInt("MAX_CONCURRENT_TOOLS", 5),
@@ -149,6 +185,12 @@ func setupComponents() (*Components, error) {
 			RetryInterval: 5 * time.Second,
 		},
 		DefaultTimeout: time.Duration(config.GetInt("API_TIMEOUT_SECONDS", 30)) * time.Second,
+		Agent: api.AgentConfig{
+			Enabled:  config.GetBool("AGENT_MODE_ENABLED", false),
+			Model:    textProcessor.ModelProvider().DefaultModel(),
+			MaxSteps: config.GetInt("AGENT_MAX_STEPS", 4),
+		},
+		Store: incidents,
 	}
 	coordinator := api.NewEmergencyCoordinator(
 		classifier,
@@ -158,148 +200,415 @@ func setupComponents() (*Components, error) {
 		coordinatorConfig,
 	)
 
-	// Create API handler with both audio and text processors
+	// Long recordings can be triaged as background jobs on a bounded worker pool
+	jobs := api.NewJobQueue(api.JobQueueConfig{
+		Workers:   config.GetInt("JOB_WORKERS", 4),
+		QueueSize: config.GetInt("JOB_QUEUE_SIZE", 32),
+		Timeout:   time.Duration(config.GetInt("JOB_TIMEOUT_SECONDS", 300)) * time.Second,
+		ResultTTL: time.Duration(config.GetInt("JOB_RESULT_TTL_MINUTES", 15)) * time.Minute,
+	})
+
+	// Create API handler with the audio, text and image processors
 	maxSize := config.GetInt("MAX_AUDIO_SIZE_MB", 20) * 1024 * 1024
-	emergencyHandler := api.NewEmergencyHandler(audioProcessor, textProcessor, coordinator, int64(maxSize))
+	emergencyHandler := api.NewEmergencyHandler(audioProcessor, textProcessor, imageProcessor, coordinator, jobs, int64(maxSize))
+
+	// Create conversation handler backed by an in-memory session store
+	sessions := api.NewSessionStore(time.Duration(config.GetInt("CHAT_SESSION_TTL_MINUTES", 30)) * time.Minute)
+	conversationHandler := api.NewConversationHandler(textProcessor, coordinator, sessions)
 
 	// Create and configure HTTP mux
 	mux := http.NewServeMux()
 	emergencyHandler.RegisterRoutes(mux)
+	conversationHandler.RegisterRoutes(mux)
+	api.NewJobsHandler(jobs).RegisterRoutes(mux)
+	api.NewIncidentsHandler(incidents, textProcessor, audioProcessor, coordinator, int64(maxSize)).RegisterRoutes(mux)
+	api.NewProfilesHandler(profiles).RegisterRoutes(mux)
+	if settings.shadow != nil {
+		api.NewShadowHandler(settings.shadow.Log).RegisterRoutes(mux)
+	}
+	api.NewMetricsHandler(metrics.Default).RegisterRoutes(mux)
+
+	// Readiness probes the models, tools and stores; results are cached so probes don't hit the providers
+	providers := []*ai.Provider{audioProcessor.ModelProvider(), textProcessor.ModelProvider()}
+	if imageProcessor != nil {
+		providers = append(providers, imageProcessor.ModelProvider())
+	}
+	checks := api.ModelChecks(providers...)
+	checks = append(checks, api.ToolChecks(toolRegistry)...)
+	checks = append(checks, api.StoreCheck("incidents", incidents.Ping), api.StoreCheck("profiles", profiles.Ping))
+	readiness := api.NewReadinessChecker(api.ReadinessConfig{
+		Checks:   checks,
+		CacheTTL: time.Duration(config.GetInt("READINESS_CACHE_SECONDS", 30)) * time.Second,
+		Timeout:  time.Duration(config.GetInt("READINESS_CHECK_TIMEOUT_SECONDS", 5)) * time.Second,
+	})
+	api.NewReadinessHandler(readiness).RegisterRoutes(mux)
+
+	// The OpenAPI document describes every route and is the contract JSON request bodies are checked against
+	document := api.BuildOpenAPI()
+	openAPIHandler, err := api.NewOpenAPIHandler(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+	openAPIHandler.RegisterRoutes(mux)
+
+	// Every request gets an ID for error reports; trusted callers may pin a model; requests may reference
+	// a patient profile
+	var handler http.Handler = api.ModelPreferenceMiddleware(config.Get("MODEL_OVERRIDE_TOKEN", ""),
+		api.PatientProfileMiddleware(profiles, mux))
+
+	// Malformed JSON bodies are rejected with every invalid field before any handler or model sees them
+	handler = api.RequestValidationMiddleware(document, handler)
+
+	// Clients over their rate limit are still served, but triaged without the AI model
+	if config.GetBool("RATE_LIMIT_ENABLED", true) {
+		rateLimits, err := loadRateLimits()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rate limits: %w", err)
+		}
+		handler = api.RateLimitMiddleware(rateLimits, handler)
+	}
+
+	// A retried request gets its first response instead of dispatching responders again. Retries are
+	// answered before rate limiting so they never use up the client's allowance.
+	idempotency := api.NewIdempotencyStore(time.Duration(config.GetInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour)
+	handler = api.IdempotencyMiddleware(idempotency, api.IdempotencyConfig{
+		MaxBodySize: int64(maxSize) + 1024*1024, // The largest upload plus its form fields
+	}, handler)
+
+	// Clients authenticate before anything else runs on their behalf
+	if config.GetBool("AUTH_DISABLED", false) {
+		log.Println("Warning: authentication is disabled; every endpoint is open to anyone who can reach the port")
+	} else {
+		authenticator, err := setupAuthenticator()
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up authentication (set AUTH_DISABLED=true only for local development): %w", err)
+		}
+		handler = api.AuthMiddleware(authenticator, handler)
+	}
+	handler = api.RequestIDMiddleware(handler)
 
 	return &Components{
 		mux:              mux,
+		handler:          handler,
 		toolRegistry:     toolRegistry,
 		locationTool:     locationTool,
 		audioProcessor:   audioProcessor,
 		emergencyHandler: emergencyHandler,
+		jobs:             jobs,
 	}, nil
 }
 
-// createAudioProcessor creates and configures an audio processor with AI models
-func createAudioProcessor() (*api.AudioProcessor, error) {
-	// Get model configuration from environment
-	modelTypeStr := config.Get("AI_MODEL_TYPE", "gemini")
-	var modelType ai.ModelType
-	switch modelTypeStr {
-	case "gemini", "GEMINI":
-		modelType = ai.ModelGemini
-	case "claude", "CLAUDE":
-		modelType = ai.ModelClaude
-	case "gpt4", "GPT4", "openai", "OPENAI":
-		modelType = ai.ModelGPT4
-	case "llama", "LLAMA":
-		modelType = ai.ModelLlama
-	default:
-		modelType = ai.ModelGemini
-	}
+// modelSettings holds the AI model selection shared by all processors
+type modelSettings struct {
+	modelType ai.ModelType
+	endpoint  string
+	apiKey    string
+	modelName string
+	fastModel string
+	redactor  *redact.Redactor
+	shadow    *ai.ShadowConfig
+	hedge     *ai.HedgeConfig
+}
 
-	// Set up audio processor configuration
-	modelConfig := api.AudioProcessorConfig{
-		ModelEndpoint:  config.Get("AI_MODEL_ENDPOINT", ""),
-		APIKey:         config.Get("AI_MODEL_API_KEY", ""),
-		ModelType:      modelType,
-		ModelName:      config.Get("AI_MODEL_NAME", ""),
-		Timeout:        time.Duration(config.GetInt("API_TIMEOUT_SECONDS", 30)) * time.Second,
-		MaxAudioLength: 600, // 10 minutes
-		Temperature:    0.7,
-		MaxTokens:      4096,
+// loadModelSettings reads the AI model configuration from the environment
+func loadModelSettings() modelSettings {
+	// Get model configuration from environment
+	modelType := parseModelType(config.Get("AI_MODEL_TYPE", "gemini"))
+
+	settings := modelSettings{
+		modelType: modelType,
+		endpoint:  config.Get("AI_MODEL_ENDPOINT", ""),
+		apiKey:    config.Get("AI_MODEL_API_KEY", ""),
+		modelName: config.Get("AI_MODEL_NAME", ""),
+		fastModel: config.Get("AI_FAST_MODEL_NAME", ""),
+		redactor:  loadRedactor(),
 	}
 
 	// Use model-specific environment variables if the general ones aren't set
-	if modelConfig.ModelEndpoint == "" {
+	if settings.endpoint == "" {
 		switch modelType {
 		case ai.ModelGemini:
-			modelConfig.ModelEndpoint = config.Get("GEMINI_ENDPOINT", "https://generativelanguage.googleapis.com/v1")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("GEMINI_API_KEY", "")
+			settings.endpoint = config.Get("GEMINI_ENDPOINT", "https://generativelanguage.googleapis.com/v1")
+			if settings.apiKey == "" {
+				settings.apiKey = config.Get("GEMINI_API_KEY", "")
 			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("GEMINI_MODEL", "gemini-1.5-pro")
+			if settings.modelName == "" {
+				settings.modelName = config.Get("GEMINI_MODEL", "gemini-1.5-pro")
 			}
 		case ai.ModelClaude:
-			modelConfig.ModelEndpoint = config.Get("CLAUDE_ENDPOINT", "https://api.anthropic.com/v1/messages")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("CLAUDE_API_KEY", "")
+			settings.endpoint = config.Get("CLAUDE_ENDPOINT", "https://api.anthropic.com/v1/messages")
+			if settings.apiKey == "" {
+				settings.apiKey = config.Get("CLAUDE_API_KEY", "")
 			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("CLAUDE_MODEL", "claude-3-opus-20240229")
+			if settings.modelName == "" {
+				settings.modelName = config.Get("CLAUDE_MODEL", "claude-3-opus-20240229")
 			}
 		case ai.ModelGPT4:
-			modelConfig.ModelEndpoint = config.Get("OPENAI_ENDPOINT", "https://api.openai.com/v1")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("OPENAI_API_KEY", "")
+			settings.endpoint = config.Get("OPENAI_ENDPOINT", "https://api.openai.com/v1")
+			if settings.apiKey == "" {
+				settings.apiKey = config.Get("OPENAI_API_KEY", "")
 			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("OPENAI_MODEL", "gpt-4o")
+			if settings.modelName == "" {
+				settings.modelName = config.Get("OPENAI_MODEL", "gpt-4o")
 			}
 		}
 	}
 
-	return api.NewAudioProcessor(modelConfig)
+	settings.shadow = loadShadowConfig(settings)
+	settings.hedge = loadHedgeConfig(settings)
+
+	return settings
 }
 
-// createTextProcessor creates and configures a text processor with AI models
-func createTextProcessor() (*api.TextProcessor, error) {
-	// Get model configuration from environment (reusing same config as audio processor)
-	modelTypeStr := config.Get("AI_MODEL_TYPE", "gemini")
-	var modelType ai.ModelType
+// parseModelType maps a configured model type name to an ai.ModelType, defaulting to Gemini
+func parseModelType(modelTypeStr string) ai.ModelType {
 	switch modelTypeStr {
 	case "gemini", "GEMINI":
-		modelType = ai.ModelGemini
+		return ai.ModelGemini
 	case "claude", "CLAUDE":
-		modelType = ai.ModelClaude
+		return ai.ModelClaude
 	case "gpt4", "GPT4", "openai", "OPENAI":
-		modelType = ai.ModelGPT4
+		return ai.ModelGPT4
 	case "llama", "LLAMA":
-		modelType = ai.ModelLlama
+		return ai.ModelLlama
 	default:
-		modelType = ai.ModelGemini
+		return ai.ModelGemini
+	}
+}
+
+// escalationThreshold returns the confidence below which fast-tier results escalate, or 0 for the default
+func escalationThreshold() float64 {
+	threshold, err := strconv.ParseFloat(config.Get("AI_ESCALATION_THRESHOLD", "0"), 64)
+	if err != nil {
+		fmt.Printf("Warning: invalid AI_ESCALATION_THRESHOLD, using default: %v\n", err)
+		return 0
+	}
+	return threshold
+}
+
+// loadSecondaryModel creates an extra model from <prefix>_MODEL_* variables, or returns nil if no model name
+// is set. It defaults to the primary's provider; credentials are only inherited when the provider matches.
+func loadSecondaryModel(prefix string, settings modelSettings) (ai.Model, error) {
+	modelName := config.Get(prefix+"_MODEL_NAME", "")
+	if modelName == "" {
+		return nil, nil
+	}
+
+	modelType := settings.modelType
+	if typeStr := config.Get(prefix+"_MODEL_TYPE", ""); typeStr != "" {
+		modelType = parseModelType(typeStr)
+	}
+
+	apiKey, endpoint := "", ""
+	if modelType == settings.modelType {
+		apiKey, endpoint = settings.apiKey, settings.endpoint
+	}
+
+	return ai.GetModel(modelType, ai.ModelConfig{
+		APIKey:      config.Get(prefix+"_MODEL_API_KEY", apiKey),
+		Endpoint:    config.Get(prefix+"_MODEL_ENDPOINT", endpoint),
+		ModelName:   modelName,
+		Temperature: 0.7,
+		MaxTokens:   4096,
+		Timeout:     config.GetInt("API_TIMEOUT_SECONDS", 30),
+	})
+}
+
+// loadShadowConfig creates the shadow model under evaluation, or returns nil if shadowing is disabled
+func loadShadowConfig(settings modelSettings) *ai.ShadowConfig {
+	sampleRate, err := strconv.ParseFloat(config.Get("SHADOW_SAMPLE_RATE", "0"), 64)
+	if err != nil {
+		fmt.Printf("Warning: invalid SHADOW_SAMPLE_RATE, shadow evaluation disabled: %v\n", err)
+		return nil
+	}
+	if sampleRate <= 0 {
+		return nil
+	}
+
+	model, err := loadSecondaryModel("SHADOW", settings)
+	if err != nil {
+		fmt.Printf("Warning: failed to create shadow model, shadow evaluation disabled: %v\n", err)
+		return nil
+	}
+	if model == nil {
+		return nil
+	}
+
+	log.Printf("Shadow model %s enabled on %.0f%% of requests", model.Name(), sampleRate*100)
+
+	return &ai.ShadowConfig{
+		Model:      model,
+		SampleRate: sampleRate,
+		Timeout:    time.Duration(config.GetInt("SHADOW_TIMEOUT_SECONDS", 60)) * time.Second,
+		Log:        ai.NewShadowLog(config.GetInt("SHADOW_LOG_SIZE", 1000)),
+	}
+}
+
+// loadHedgeConfig creates the second-provider model for hedged requests, or returns nil if hedging is disabled
+func loadHedgeConfig(settings modelSettings) *ai.HedgeConfig {
+	model, err := loadSecondaryModel("HEDGE", settings)
+	if err != nil {
+		fmt.Printf("Warning: failed to create hedge model, hedging disabled: %v\n", err)
+		return nil
+	}
+	if model == nil {
+		return nil
+	}
+
+	percentile, err := strconv.ParseFloat(config.Get("HEDGE_PERCENTILE", "0.9"), 64)
+	if err != nil {
+		fmt.Printf("Warning: invalid HEDGE_PERCENTILE, using default: %v\n", err)
+		percentile = 0
+	}
+
+	log.Printf("Hedging suspected critical requests to %s", model.Name())
+
+	return &ai.HedgeConfig{
+		Secondary:  model,
+		Percentile: percentile,
+		MinDelay:   time.Duration(config.GetInt("HEDGE_MIN_DELAY_MS", 500)) * time.Millisecond,
+		MaxDelay:   time.Duration(config.GetInt("HEDGE_MAX_DELAY_MS", 10000)) * time.Millisecond,
 	}
+}
+
+// loadRedactor creates the PII redactor from the deployment's policy, or returns nil if redaction is disabled
+func loadRedactor() *redact.Redactor {
+	if !config.GetBool("PII_REDACTION_ENABLED", true) {
+		return nil
+	}
+
+	policy, err := redact.ParsePolicy(config.Get("PII_ALLOWED_ENTITIES", ""))
+	if err != nil {
+		fmt.Printf("Warning: invalid PII_ALLOWED_ENTITIES, redacting all entity types: %v\n", err)
+		policy = redact.Policy{}
+	}
+
+	return redact.New(policy)
+}
+
+// loadRateLimits reads the per-route limits from RATE_LIMITS, defaulting to the routes that call the AI model
+func loadRateLimits() (api.RateLimitConfig, error) {
+	rateLimits := api.RateLimitConfig{
+		Routes:            api.DefaultRouteLimits(),
+		TrustForwardedFor: config.GetBool("RATE_LIMIT_TRUST_FORWARDED_FOR", false),
+	}
+
+	if spec := config.Get("RATE_LIMITS", ""); spec != "" {
+		routes, err := api.ParseRouteLimits(spec)
+		if err != nil {
+			return rateLimits, err
+		}
+		rateLimits.Routes = routes
+	}
+
+	for _, route := range rateLimits.Routes {
+		log.Printf("Rate limiting %s: per client %s, per IP %s", route.Path, route.PerClient, route.PerIP)
+	}
+	return rateLimits, nil
+}
+
+// setupAuthenticator loads the API keys from API_KEYS_FILE and the JWT settings
+func setupAuthenticator() (*auth.Authenticator, error) {
+	authConfig := auth.Config{
+		JWTSecret:   []byte(config.Get("JWT_SECRET", "")),
+		JWTIssuer:   config.Get("JWT_ISSUER", ""),
+		JWTAudience: config.Get("JWT_AUDIENCE", ""),
+	}
+
+	if path := config.Get("API_KEYS_FILE", ""); path != "" {
+		keys, err := auth.LoadAPIKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		authConfig.APIKeys = keys
+	}
+
+	authenticator, err := auth.NewAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Authentication enabled with %d API keys; JWTs %s", len(authConfig.APIKeys),
+		map[bool]string{true: "accepted", false: "not accepted"}[len(authConfig.JWTSecret) > 0])
+	return authenticator, nil
+}
+
+// createAudioProcessor creates and configures an audio processor with AI models
+func createAudioProcessor(settings modelSettings) (*api.AudioProcessor, error) {
+	// Long enough for background jobs; synchronous requests are cut short by the handler
+	timeout := time.Duration(config.GetInt("AUDIO_TIMEOUT_SECONDS", 180)) * time.Second
 
+	// Set up audio processor configuration
+	modelConfig := api.AudioProcessorConfig{
+		ModelEndpoint:  settings.endpoint,
+		APIKey:         settings.apiKey,
+		ModelType:      settings.modelType,
+		ModelName:      settings.modelName,
+		Timeout:        timeout,
+		MaxAudioLength: 600, // 10 minutes
+		Temperature:    0.7,
+		MaxTokens:      4096,
+		Redactor:       settings.redactor,
+		Shadow:         settings.shadow,
+
+		FastModelName:       settings.fastModel,
+		EscalationThreshold: escalationThreshold(),
+
+		ChunkLength:      time.Duration(config.GetInt("AUDIO_CHUNK_SECONDS", 60)) * time.Second,
+		ChunkConcurrency: config.GetInt("AUDIO_CHUNK_CONCURRENCY", 4),
+
+		LiveSegmentLength: time.Duration(config.GetInt("AUDIO_LIVE_SEGMENT_SECONDS", 5)) * time.Second,
+	}
+
+	return api.NewAudioProcessor(modelConfig)
+}
+
+// createTextProcessor creates and configures a text processor with AI models
+func createTextProcessor(settings modelSettings) (*api.TextProcessor, error) {
 	// Set up text processor configuration
 	modelConfig := api.TextProcessorConfig{
-		ModelEndpoint: config.Get("AI_MODEL_ENDPOINT", ""),
-		APIKey:        config.Get("AI_MODEL_API_KEY", ""),
-		ModelType:     modelType,
-		ModelName:     config.Get("AI_MODEL_NAME", ""),
+		ModelEndpoint: settings.endpoint,
+		APIKey:        settings.apiKey,
+		ModelType:     settings.modelType,
+		ModelName:     settings.modelName,
 		Timeout:       time.Duration(config.GetInt("API_TIMEOUT_SECONDS", 30)) * time.Second,
 		Temperature:   0.7,
 		MaxTokens:     4096,
-	}
+		Redactor:      settings.redactor,
+		Shadow:        settings.shadow,
 
-	// Use model-specific environment variables if the general ones aren't set
-	if modelConfig.ModelEndpoint == "" {
-		switch modelType {
-		case ai.ModelGemini:
-			modelConfig.ModelEndpoint = config.Get("GEMINI_ENDPOINT", "https://generativelanguage.googleapis.com/v1")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("GEMINI_API_KEY", "")
-			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("GEMINI_MODEL", "gemini-1.5-pro")
-			}
-		case ai.ModelClaude:
-			modelConfig.ModelEndpoint = config.Get("CLAUDE_ENDPOINT", "https://api.anthropic.com/v1/messages")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("CLAUDE_API_KEY", "")
-			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("CLAUDE_MODEL", "claude-3-opus-20240229")
-			}
-		case ai.ModelGPT4:
-			modelConfig.ModelEndpoint = config.Get("OPENAI_ENDPOINT", "https://api.openai.com/v1")
-			if modelConfig.APIKey == "" {
-				modelConfig.APIKey = config.Get("OPENAI_API_KEY", "")
-			}
-			if modelConfig.ModelName == "" {
-				modelConfig.ModelName = config.Get("OPENAI_MODEL", "gpt-4o")
-			}
-		}
+		FastModelName:       settings.fastModel,
+		EscalationThreshold: escalationThreshold(),
+		Hedge:               settings.hedge,
 	}
 
 	return api.NewTextProcessor(modelConfig)
 }
 
+// createImageProcessor creates and configures an image processor, or returns nil if image analysis is disabled
+func createImageProcessor(settings modelSettings) (*api.ImageProcessor, error) {
+	if !config.GetBool("ENABLE_IMAGE_ANALYSIS", true) {
+		return nil, nil
+	}
+
+	// Set up image processor configuration
+	modelConfig := api.ImageProcessorConfig{
+		ModelEndpoint: settings.endpoint,
+		APIKey:        settings.apiKey,
+		ModelType:     settings.modelType,
+		ModelName:     config.Get("IMAGE_MODEL_NAME", settings.modelName),
+		Timeout:       time.Duration(config.GetInt("API_TIMEOUT_SECONDS", 30)) * time.Second,
+		Temperature:   0.4,
+		MaxTokens:     4096,
+		Redactor:      settings.redactor,
+		Shadow:        settings.shadow,
+
+		FastModelName:       settings.fastModel,
+		EscalationThreshold: escalationThreshold(),
+	}
+
+	return api.NewImageProcessor(modelConfig)
+}
+
 // createLocationTool creates and configures a location tool
 func createLocationTool(client *mockHTTPClient) *location.LocationTool {
 	config := location.Config{
@@ -3,6 +3,7 @@ package ai
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -230,6 +231,295 @@ func (m *ClaudeModel) ProcessAudio(ctx context.Context, input *AudioInput, promp
 	return nil, ErrUnsupportedRequestType
 }
 
+// ProcessImage analyzes a base64-encoded image together with a text prompt
+func (m *ClaudeModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	if !SupportsRequestType(m, ImageRequest) {
+		return nil, ErrUnsupportedRequestType
+	}
+
+	imageData, err := io.ReadAll(input.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+
+	mimeType := input.MIMEType
+	if mimeType == "" {
+		mimeType = DetectImageMIMEType(imageData)
+		if mimeType == "" {
+			return nil, fmt.Errorf("%w: could not determine image MIME type", ErrUnsupportedRequestType)
+		}
+	}
+
+	payload := map[string]interface{}{
+		"model": m.modelName,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{
+						"type": "image",
+						"source": map[string]interface{}{
+							"type":       "base64",
+							"media_type": mimeType,
+							"data":       base64.StdEncoding.EncodeToString(imageData),
+						},
+					},
+					{
+						"type": "text",
+						"text": prompt,
+					},
+				},
+			},
+		},
+		"max_tokens":  m.config.MaxTokens,
+		"temperature": m.config.Temperature,
+	}
+
+	response, err := m.sendMessages(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	modelResponse := m.newModelResponse(response, FormatText)
+	modelResponse.Metadata["image_mime_type"] = mimeType
+	return modelResponse, nil
+}
+
+// ProcessConversation sends a multi-turn conversation to the Messages API, passing system
+// instructions through the top-level system field
+func (m *ClaudeModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	system, dialogue := splitSystemMessages(messages)
+	if len(dialogue) == 0 || dialogue[0].Role != RoleUser {
+		return nil, fmt.Errorf("conversation must start with a user message")
+	}
+
+	claudeMessages := make([]map[string]interface{}, 0, len(dialogue))
+	for _, message := range dialogue {
+		claudeMessages = append(claudeMessages, map[string]interface{}{
+			"role": message.Role,
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": message.Content,
+				},
+			},
+		})
+	}
+
+	payload := map[string]interface{}{
+		"model":       m.modelName,
+		"messages":    claudeMessages,
+		"max_tokens":  m.config.MaxTokens,
+		"temperature": m.config.Temperature,
+	}
+	if system != "" {
+		payload["system"] = system
+	}
+
+	response, err := m.sendMessages(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	modelResponse := m.newModelResponse(response, FormatText)
+	modelResponse.Metadata["turns"] = len(dialogue)
+	return modelResponse, nil
+}
+
+// ProcessWithFunctions runs a conversation in which Claude may answer with tool_use blocks
+func (m *ClaudeModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	system, dialogue := splitSystemMessages(messages)
+	if len(dialogue) == 0 || dialogue[0].Role != RoleUser {
+		return nil, fmt.Errorf("conversation must start with a user message")
+	}
+
+	var claudeMessages []map[string]interface{}
+	for _, message := range dialogue {
+		var role string
+		var content []map[string]interface{}
+
+		switch message.Role {
+		case RoleAssistant:
+			role = "assistant"
+			if message.Content != "" {
+				content = append(content, map[string]interface{}{"type": "text", "text": message.Content})
+			}
+			for _, call := range message.FunctionCalls {
+				input := call.Arguments
+				if input == nil {
+					input = map[string]interface{}{}
+				}
+				content = append(content, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Name,
+					"input": input,
+				})
+			}
+		case RoleFunction:
+			role = "user"
+			content = []map[string]interface{}{{
+				"type":        "tool_result",
+				"tool_use_id": message.FunctionCallID,
+				"content":     message.Content,
+			}}
+			// Results for parallel tool calls must share a single user turn
+			if n := len(claudeMessages); n > 0 && claudeMessages[n-1]["role"] == "user" {
+				if previous, ok := claudeMessages[n-1]["content"].([]map[string]interface{}); ok && previous[0]["type"] == "tool_result" {
+					claudeMessages[n-1]["content"] = append(previous, content...)
+					continue
+				}
+			}
+		default:
+			role = "user"
+			content = []map[string]interface{}{{"type": "text", "text": message.Content}}
+		}
+
+		claudeMessages = append(claudeMessages, map[string]interface{}{"role": role, "content": content})
+	}
+
+	claudeTools := make([]map[string]interface{}, 0, len(functions))
+	for _, function := range functions {
+		claudeTools = append(claudeTools, map[string]interface{}{
+			"name":         function.Name,
+			"description":  function.Description,
+			"input_schema": function.Parameters,
+		})
+	}
+
+	payload := map[string]interface{}{
+		"model":       m.modelName,
+		"messages":    claudeMessages,
+		"tools":       claudeTools,
+		"max_tokens":  m.config.MaxTokens,
+		"temperature": 0.2, // Tool selection should be predictable
+	}
+	if system != "" {
+		payload["system"] = system
+	}
+
+	response, err := m.sendMessages(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newModelResponse(response, FormatText), nil
+}
+
+// claudeMessageResponse is the response body of the Messages API
+type claudeMessageResponse struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Role         string `json:"role"`
+	Model        string `json:"model"`
+	StopReason   string `json:"stop_reason"`
+	StopSequence string `json:"stop_sequence"`
+	Usage        struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+
+		// Set on tool_use blocks
+		ID    string                 `json:"id,omitempty"`
+		Name  string                 `json:"name,omitempty"`
+		Input map[string]interface{} `json:"input,omitempty"`
+	} `json:"content"`
+}
+
+// sendMessages posts a payload to the Messages API and returns the parsed, non-empty response
+func (m *ClaudeModel) sendMessages(ctx context.Context, payload map[string]interface{}) (*claudeMessageResponse, error) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.config.Endpoint, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", m.config.APIKey)
+	req.Header.Set("Anthropic-Version", "2023-06-01")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrContextDeadlineExceeded
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return nil, ErrRateLimitExceeded
+		case http.StatusServiceUnavailable, 529: // 529: Anthropic API overloaded
+			return nil, ErrModelUnavailable
+		}
+
+		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+			return nil, fmt.Errorf("%w: %s", ErrAPICallFailed, errorResponse.Error.Message)
+		}
+		return nil, fmt.Errorf("%w: status code %d", ErrAPICallFailed, resp.StatusCode)
+	}
+
+	var response claudeMessageResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(response.Content) == 0 {
+		return nil, fmt.Errorf("empty response from model")
+	}
+
+	return &response, nil
+}
+
+// newModelResponse converts a Messages API response into a standardized response
+func (m *ClaudeModel) newModelResponse(response *claudeMessageResponse, format string) *ModelResponse {
+	var sb strings.Builder
+	var calls []FunctionCall
+	for _, content := range response.Content {
+		switch content.Type {
+		case "text":
+			sb.WriteString(content.Text)
+		case "tool_use":
+			calls = append(calls, FunctionCall{ID: content.ID, Name: content.Name, Arguments: content.Input})
+		}
+	}
+
+	return &ModelResponse{
+		Content:       sb.String(),
+		Raw:           *response,
+		Format:        format,
+		FunctionCalls: calls,
+		Metadata: map[string]interface{}{
+			"model":         response.Model,
+			"stop_reason":   response.StopReason,
+			"input_tokens":  response.Usage.InputTokens,
+			"output_tokens": response.Usage.OutputTokens,
+			"message_id":    response.ID,
+		},
+	}
+}
+
 // ProcessTextWithJson processes a text prompt and returns structured JSON
 func (m *ClaudeModel) ProcessTextWithJson(ctx context.Context, prompt string, jsonSchema string) (*ModelResponse, error) {
 	// Create a combined prompt that instructs Claude to respond with valid JSON
@@ -30,4 +30,7 @@ var (
 
 	// ErrRateLimitExceeded is returned when the API rate limit is exceeded
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+	// ErrAuthenticationFailed is returned when the model API rejects the configured credentials
+	ErrAuthenticationFailed = errors.New("model API rejected the credentials")
 )
@@ -3,12 +3,15 @@ package ai
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io" // Note: ioutil is deprecated, but keeping for consistency with existing code
 	"net/http"
 	"strings"
 	"time"
+
+	"agent/internal/models"
 )
 
 // Default configuration values for Gemini
@@ -128,8 +131,31 @@ func (m *GeminiModel) SupportedRequestTypes() []RequestType {
 // -- Request/Response Structures --
 
 type GeminiGenerateRequest struct {
-	Contents         []GeminiContent         `json:"contents"`
-	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiTool groups the function declarations offered to the model
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type GeminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
 }
 
 type GeminiContent struct {
@@ -137,10 +163,20 @@ type GeminiContent struct {
 	Parts []GeminiPart `json:"parts"`
 }
 
-// GeminiPart can be text or file data
+// GeminiPart can be text, file data or inline data
 type GeminiPart struct {
-	Text     string          `json:"text,omitempty"`
-	FileData *GeminiFileData `json:"file_data,omitempty"` // Correct key: file_data
+	Text       string            `json:"text,omitempty"`
+	FileData   *GeminiFileData   `json:"file_data,omitempty"`   // Correct key: file_data
+	InlineData *GeminiInlineData `json:"inline_data,omitempty"` // Base64 payloads such as images
+
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiInlineData carries small binary payloads directly in the request
+type GeminiInlineData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"` // Base64-encoded bytes
 }
 
 // GeminiFileData references an uploaded file for generateContent
@@ -194,6 +230,10 @@ type GeminiFileInfo struct {
 	UpdateTime  string `json:"updateTime"`
 	Sha256Hash  string `json:"sha256Hash"`
 	DisplayName string `json:"displayName"`
+	State       string `json:"state"` // PROCESSING, ACTIVE or FAILED
+	Error       *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
 }
 
 type GeminiErrorResponse struct {
@@ -216,29 +256,8 @@ func (m *GeminiModel) doRequest(ctx context.Context, url string, method string,
 		req.Header.Set(key, value)
 	}
 
-	fmt.Printf("DEBUG: Sending %s request to: %s\n", method, url)
-	if method == "POST" && headers["Content-Type"] == "application/json" && body != nil {
-		// Log JSON body carefully (could be large)
-		buf := new(bytes.Buffer)
-		if _, readErr := buf.ReadFrom(req.Body); readErr == nil {
-			fmt.Printf("DEBUG: Request Body (JSON): %s\n", buf.String())
-			// Restore the body for the actual request
-			req.Body = io.NopCloser(buf)
-		} else {
-			// If reading fails, log that and proceed
-			fmt.Println("DEBUG: Could not read request body for logging.")
-			// Ensure req.Body is still valid if it was a simple buffer initially
-			if origBody, ok := body.(*bytes.Buffer); ok {
-				req.Body = io.NopCloser(origBody)
-			} else {
-				// Fix: removed incorrect type assertion with io.NopCloser
-				// Just ensure we have a valid body for the request
-				if body != nil {
-					req.Body = io.NopCloser(bytes.NewBuffer([]byte{}))
-				}
-			}
-		}
-	}
+	// Only the method and path are logged: request bodies carry caller text and the query carries the API key
+	fmt.Printf("DEBUG: Sending %s request to: %s\n", method, logSafeURL(url))
 
 	resp, err := m.client.Do(req)
 	if err != nil {
@@ -257,8 +276,6 @@ func (m *GeminiModel) doRequest(ctx context.Context, url string, method string,
 		return resp, nil, fmt.Errorf("failed to read response body from %s: %w", url, err)
 	}
 
-	fmt.Printf("DEBUG: Response body: %s\n", string(respBodyBytes))
-
 	return resp, respBodyBytes, nil
 }
 
@@ -396,46 +413,57 @@ func (m *GeminiModel) ProcessAudio(ctx context.Context, input *AudioInput, promp
 		return nil, fmt.Errorf("failed to upload audio file: %w", err)
 	}
 
-	// Step 2: Send the analysis request with the file reference (fileInfo.Name)
+	// Caller audio must not outlive the analysis; anything this misses is left for the sweeper
+	defer m.deleteFileDetached(ctx, fileInfo.Name)
+
+	// Step 2: Wait until the file has been processed and can be referenced
+	if err := m.waitForFileActive(ctx, fileInfo); err != nil {
+		return nil, fmt.Errorf("uploaded audio file never became active: %w", err)
+	}
+
+	// Step 3: Send the analysis request with the file reference (fileInfo.Name)
 	return m.generateContentFromFileUri(ctx, fileInfo.Name, mimeType, prompt)
 }
 
-// uploadAudioFile uploads an audio file to the Gemini Files API
-func (m *GeminiModel) uploadAudioFile(ctx context.Context, audioData []byte, mimeType string) (*GeminiFileInfo, error) {
-	// Construct the correct URL for the File API upload endpoint
-	uploadUrl := fmt.Sprintf("%s/upload/v1beta/files?key=%s", fileUploadHost, m.config.APIKey)
+// geminiTranscriptionPrompt asks for a diarised, timed transcript
+const geminiTranscriptionPrompt = `
+Transcribe this emergency call recording verbatim.
+Split it into segments at each change of speaker or pause, and label each speaker as "caller" (the person who placed the call),
+"bystander" (anyone else audible, including the patient) or "unknown".
+Write [inaudible] for speech that cannot be made out.
 
-	headers := map[string]string{
-		"Content-Type":     mimeType,
-		"x-goog-file-name": fmt.Sprintf("audio-upload-%d.tmp", time.Now().UnixNano()), // Temporary unique name
-	}
+Respond only with JSON of the form:
+{"segments": [{"start_seconds": 0.0, "end_seconds": 4.2, "speaker": "caller", "text": "..."}]}`
 
-	resp, bodyBytes, err := m.doRequest(ctx, uploadUrl, "POST", bytes.NewBuffer(audioData), headers)
+// Transcribe returns a timed transcript of the recording with speakers labelled
+func (m *GeminiModel) Transcribe(ctx context.Context, input *AudioInput) (*models.Transcript, error) {
+	response, err := m.ProcessAudio(ctx, input, geminiTranscriptionPrompt)
 	if err != nil {
-		return nil, err // Error already formatted
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResponse GeminiErrorResponse
-		if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil && errorResponse.Error.Message != "" {
-			return nil, fmt.Errorf("%w: %s (status: %d, url: %s)", ErrAPICallFailed, errorResponse.Error.Message, resp.StatusCode, uploadUrl)
-		}
-		return nil, fmt.Errorf("%w: status code %d from %s. Response: %s", ErrAPICallFailed, resp.StatusCode, uploadUrl, string(bodyBytes))
+	var parsed struct {
+		Segments []struct {
+			StartSeconds float64 `json:"start_seconds"`
+			EndSeconds   float64 `json:"end_seconds"`
+			Speaker      string  `json:"speaker"`
+			Text         string  `json:"text"`
+		} `json:"segments"`
 	}
-
-	// Parse the successful response
-	var fileResponse GeminiFileUploadResponse
-	if err := json.Unmarshal(bodyBytes, &fileResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse successful file upload response: %w. Body: %s", err, string(bodyBytes))
+	if err := json.Unmarshal([]byte(extractJSONFromText(response.Content)), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
 	}
 
-	// The 'Name' field (e.g., "files/xyz") is the reference needed for generateContent
-	if fileResponse.File.Name == "" {
-		return nil, fmt.Errorf("file upload response did not contain a file reference ('name'). Response: %+v", fileResponse)
+	transcript := &models.Transcript{Diarized: true}
+	for _, segment := range parsed.Segments {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{
+			StartSeconds: segment.StartSeconds,
+			EndSeconds:   segment.EndSeconds,
+			Speaker:      models.ParseSpeaker(segment.Speaker),
+			Text:         strings.TrimSpace(segment.Text),
+		})
 	}
-
-	fmt.Printf("DEBUG: Successfully uploaded file. File reference: %s\n", fileResponse.File.Name)
-	return &fileResponse.File, nil
+	return transcript, nil
 }
 
 // generateContentFromFileUri sends a request to analyze audio using a file URI
@@ -448,8 +476,6 @@ func (m *GeminiModel) generateContentFromFileUri(ctx context.Context, fileRef st
 		m.modelName,
 		m.config.APIKey)
 
-	fmt.Printf("DEBUG: Content generation URL: %s\n", url)
-
 	// Create the request payload using structs for clarity and correctness
 	payload := GeminiGenerateRequest{
 		Contents: []GeminiContent{
@@ -514,7 +540,6 @@ func (m *GeminiModel) generateContentFromFileUri(ctx context.Context, fileRef st
 
 	// Extract the generated text
 	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
-		fmt.Printf("DEBUG: Empty or unexpected response structure. Full response: %+v\n", response)
 		return nil, fmt.Errorf("empty or unexpected response structure from model: no candidates or text parts found")
 	}
 
@@ -546,6 +571,267 @@ func (m *GeminiModel) generateContentFromFileUri(ctx context.Context, fileRef st
 	}, nil
 }
 
+// ProcessImage analyzes an inline image together with a text prompt
+func (m *GeminiModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	if !SupportsRequestType(m, ImageRequest) {
+		return nil, ErrUnsupportedRequestType
+	}
+
+	imageData, err := io.ReadAll(input.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+
+	mimeType := input.MIMEType
+	if mimeType == "" {
+		mimeType = DetectImageMIMEType(imageData)
+		if mimeType == "" {
+			return nil, fmt.Errorf("%w: could not determine image MIME type", ErrUnsupportedRequestType)
+		}
+	}
+
+	payload := GeminiGenerateRequest{
+		Contents: []GeminiContent{
+			{
+				Role: "user",
+				Parts: []GeminiPart{
+					{Text: prompt},
+					{
+						InlineData: &GeminiInlineData{
+							MimeType: mimeType,
+							Data:     base64.StdEncoding.EncodeToString(imageData),
+						},
+					},
+				},
+			},
+		},
+		GenerationConfig: &GeminiGenerationConfig{
+			Temperature:     m.config.Temperature,
+			MaxOutputTokens: m.config.MaxTokens,
+			TopP:            0.95,
+			TopK:            40,
+		},
+	}
+
+	response, err := m.generateContent(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := m.responseMetadata(response)
+	metadata["image_mime_type"] = mimeType
+
+	return &ModelResponse{
+		Content:  response.Candidates[0].Content.Parts[0].Text,
+		Raw:      *response,
+		Format:   FormatText,
+		Metadata: metadata,
+	}, nil
+}
+
+// ProcessConversation sends a multi-turn conversation using Gemini's native user/model roles
+func (m *GeminiModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	system, dialogue := splitSystemMessages(messages)
+	if len(dialogue) == 0 {
+		return nil, fmt.Errorf("conversation must contain at least one user or assistant message")
+	}
+
+	contents := make([]GeminiContent, 0, len(dialogue))
+	for _, message := range dialogue {
+		role := "user"
+		if message.Role == RoleAssistant {
+			role = "model"
+		}
+		contents = append(contents, GeminiContent{
+			Role:  role,
+			Parts: []GeminiPart{{Text: message.Content}},
+		})
+	}
+
+	payload := GeminiGenerateRequest{
+		Contents: contents,
+		GenerationConfig: &GeminiGenerationConfig{
+			Temperature:     m.config.Temperature,
+			MaxOutputTokens: m.config.MaxTokens,
+			TopP:            0.95,
+			TopK:            40,
+		},
+	}
+	if system != "" {
+		payload.SystemInstruction = &GeminiContent{Parts: []GeminiPart{{Text: system}}}
+	}
+
+	response, err := m.generateContent(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := m.responseMetadata(response)
+	metadata["turns"] = len(dialogue)
+
+	return &ModelResponse{
+		Content:  response.Candidates[0].Content.Parts[0].Text,
+		Raw:      *response,
+		Format:   FormatText,
+		Metadata: metadata,
+	}, nil
+}
+
+// ProcessWithFunctions runs a conversation in which Gemini may answer with functionCall parts
+func (m *GeminiModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	system, dialogue := splitSystemMessages(messages)
+	if len(dialogue) == 0 {
+		return nil, fmt.Errorf("conversation must contain at least one user or assistant message")
+	}
+
+	var contents []GeminiContent
+	for _, message := range dialogue {
+		var content GeminiContent
+		switch message.Role {
+		case RoleAssistant:
+			content.Role = "model"
+			if message.Content != "" {
+				content.Parts = append(content.Parts, GeminiPart{Text: message.Content})
+			}
+			for _, call := range message.FunctionCalls {
+				content.Parts = append(content.Parts, GeminiPart{
+					FunctionCall: &GeminiFunctionCall{Name: call.Name, Args: call.Arguments},
+				})
+			}
+		case RoleFunction:
+			content.Role = "user"
+			content.Parts = []GeminiPart{{
+				FunctionResponse: &GeminiFunctionResponse{
+					Name:     message.FunctionName,
+					Response: map[string]interface{}{"result": message.Content},
+				},
+			}}
+			// Responses to parallel calls belong in the same turn
+			if n := len(contents); n > 0 && contents[n-1].Parts[0].FunctionResponse != nil {
+				contents[n-1].Parts = append(contents[n-1].Parts, content.Parts...)
+				continue
+			}
+		default:
+			content.Role = "user"
+			content.Parts = []GeminiPart{{Text: message.Content}}
+		}
+		contents = append(contents, content)
+	}
+
+	declarations := make([]GeminiFunctionDeclaration, 0, len(functions))
+	for _, function := range functions {
+		declarations = append(declarations, GeminiFunctionDeclaration{
+			Name:        function.Name,
+			Description: function.Description,
+			Parameters:  function.Parameters,
+		})
+	}
+
+	payload := GeminiGenerateRequest{
+		Contents: contents,
+		Tools:    []GeminiTool{{FunctionDeclarations: declarations}},
+		GenerationConfig: &GeminiGenerationConfig{
+			Temperature:     0.2, // Tool selection should be predictable
+			MaxOutputTokens: m.config.MaxTokens,
+		},
+	}
+	if system != "" {
+		payload.SystemInstruction = &GeminiContent{Parts: []GeminiPart{{Text: system}}}
+	}
+
+	response, err := m.generateContent(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var calls []FunctionCall
+	for i, part := range response.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			calls = append(calls, FunctionCall{
+				ID:        fmt.Sprintf("call-%d", i), // Gemini does not assign call IDs
+				Name:      part.FunctionCall.Name,
+				Arguments: part.FunctionCall.Args,
+			})
+			continue
+		}
+		text.WriteString(part.Text)
+	}
+
+	return &ModelResponse{
+		Content:       text.String(),
+		Raw:           *response,
+		Format:        FormatText,
+		Metadata:      m.responseMetadata(response),
+		FunctionCalls: calls,
+	}, nil
+}
+
+// generateContent sends a generateContent request and returns the parsed, non-empty response
+func (m *GeminiModel) generateContent(ctx context.Context, payload GeminiGenerateRequest) (*GeminiGenerateResponse, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s",
+		m.baseEndpoint, m.modelName, m.config.APIKey)
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, bodyBytes, err := m.doRequest(ctx, url, "POST", bytes.NewBuffer(jsonPayload), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse GeminiErrorResponse
+		if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+			switch resp.StatusCode {
+			case http.StatusTooManyRequests:
+				return nil, fmt.Errorf("%w: %s", ErrRateLimitExceeded, errorResponse.Error.Message)
+			case http.StatusServiceUnavailable:
+				return nil, fmt.Errorf("%w: %s", ErrModelUnavailable, errorResponse.Error.Message)
+			default:
+				return nil, fmt.Errorf("%w: %s (status: %d)", ErrAPICallFailed, errorResponse.Error.Message, resp.StatusCode)
+			}
+		}
+		return nil, fmt.Errorf("%w: status code %d from generateContent", ErrAPICallFailed, resp.StatusCode)
+	}
+
+	var response GeminiGenerateResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse successful response: %w", err)
+	}
+
+	if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+		return nil, fmt.Errorf("request blocked by API, reason: %s", response.PromptFeedback.BlockReason)
+	}
+
+	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("empty or unexpected response structure from model: no candidates or parts found")
+	}
+
+	return &response, nil
+}
+
+// responseMetadata builds the standard metadata map for a generateContent response
+func (m *GeminiModel) responseMetadata(response *GeminiGenerateResponse) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"model":         m.modelName,
+		"finish_reason": response.Candidates[0].FinishReason,
+	}
+
+	if len(response.Candidates[0].SafetyRatings) > 0 {
+		safetyRatings := make(map[string]string)
+		for _, rating := range response.Candidates[0].SafetyRatings {
+			safetyRatings[rating.Category] = rating.Probability
+		}
+		metadata["safety_ratings"] = safetyRatings
+	}
+
+	return metadata
+}
+
 // ProcessTextWithJson processes a text prompt and returns structured JSON
 func (m *GeminiModel) ProcessTextWithJson(ctx context.Context, prompt string, jsonSchema string) (*ModelResponse, error) {
 	// Ensure we use the v1beta endpoint
@@ -614,8 +900,7 @@ func (m *GeminiModel) ProcessTextWithJson(ctx context.Context, prompt string, js
 	// Basic validation: Check if it's valid JSON
 	var jsonObj interface{}
 	if err := json.Unmarshal([]byte(jsonStr), &jsonObj); err != nil {
-		// Log the raw string that failed validation
-		fmt.Printf("DEBUG: Failed JSON validation. String was: %s\n", jsonStr)
+		fmt.Printf("DEBUG: Failed JSON validation (%d bytes)\n", len(jsonStr))
 		return nil, fmt.Errorf("%w: model response is not valid JSON: %s", ErrInvalidJSONSchema, err.Error())
 	}
 
@@ -660,6 +945,6 @@ func extractJSONFromText(text string) string {
 	}
 
 	// If none of the above, return the text as is, validation will catch it later if it's not JSON
-	fmt.Printf("WARN: Could not extract JSON from code block, returning raw text: %s\n", text)
+	fmt.Printf("WARN: Could not extract JSON from code block, returning raw text (%d bytes)\n", len(text))
 	return text
 }
@@ -0,0 +1,298 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent/internal/metrics"
+)
+
+const (
+	// geminiFilePrefix marks files uploaded by this service so the sweeper never touches anything else
+	geminiFilePrefix = "rapidtriage-"
+
+	// geminiUploadChunkSize is the resumable upload chunk size; it must be a multiple of 256 KiB
+	geminiUploadChunkSize = 8 * 1024 * 1024
+
+	// geminiUploadRetries bounds how often a failed chunk is resumed
+	geminiUploadRetries = 3
+)
+
+var geminiFilesSwept = metrics.Default.Counter("ai_gemini_files_swept_total", "Leftover Gemini uploads deleted by the sweeper")
+
+// FileSweeper is implemented by models that store uploads with a third party and can clean them up
+type FileSweeper interface {
+	// SweepFiles deletes this service's uploads older than maxAge and returns how many were deleted
+	SweepFiles(ctx context.Context, maxAge time.Duration) (int, error)
+}
+
+// GeminiFileListResponse is a page of the Files API listing
+type GeminiFileListResponse struct {
+	Files         []GeminiFileInfo `json:"files"`
+	NextPageToken string           `json:"nextPageToken"`
+}
+
+// uploadAudioFile uploads an audio file to the Gemini Files API using the resumable upload protocol
+func (m *GeminiModel) uploadAudioFile(ctx context.Context, audioData []byte, mimeType string) (*GeminiFileInfo, error) {
+	uploadURL, err := m.startResumableUpload(ctx, len(audioData), mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, retries := 0, 0
+	for {
+		end := offset + geminiUploadChunkSize
+		if end > len(audioData) {
+			end = len(audioData)
+		}
+
+		command := "upload"
+		if end == len(audioData) {
+			command = "upload, finalize"
+		}
+
+		headers := map[string]string{
+			"X-Goog-Upload-Command": command,
+			"X-Goog-Upload-Offset":  strconv.Itoa(offset),
+		}
+		resp, bodyBytes, err := m.doRequest(ctx, uploadURL, "POST", bytes.NewReader(audioData[offset:end]), headers)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			if end == len(audioData) {
+				return parseUploadedFile(bodyBytes)
+			}
+			offset, retries = end, 0
+			continue
+		}
+
+		if err == ErrContextDeadlineExceeded || ctx.Err() != nil {
+			return nil, fmt.Errorf("upload interrupted at byte %d: %w", offset, ctx.Err())
+		}
+		if retries >= geminiUploadRetries {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: upload chunk at byte %d failed with status %d", ErrAPICallFailed, offset, resp.StatusCode)
+		}
+		retries++
+
+		// Ask the server how much it has and resume from there
+		received, queryErr := m.queryUploadOffset(ctx, uploadURL)
+		if queryErr != nil {
+			return nil, fmt.Errorf("failed to resume upload: %w", queryErr)
+		}
+		offset = received
+
+		backoff := time.NewTimer(time.Duration(retries) * 500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			backoff.Stop()
+			return nil, ErrContextDeadlineExceeded
+		case <-backoff.C:
+		}
+	}
+}
+
+// startResumableUpload opens a resumable upload session and returns its upload URL
+func (m *GeminiModel) startResumableUpload(ctx context.Context, size int, mimeType string) (string, error) {
+	startURL := fmt.Sprintf("%s/upload/v1beta/files?key=%s", fileUploadHost, m.config.APIKey)
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"file": map[string]string{
+			"display_name": fmt.Sprintf("%saudio-%d", geminiFilePrefix, time.Now().UnixNano()),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal upload metadata: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type":                        "application/json",
+		"X-Goog-Upload-Protocol":              "resumable",
+		"X-Goog-Upload-Command":               "start",
+		"X-Goog-Upload-Header-Content-Length": strconv.Itoa(size),
+		"X-Goog-Upload-Header-Content-Type":   mimeType,
+	}
+	resp, bodyBytes, err := m.doRequest(ctx, startURL, "POST", bytes.NewReader(metadata), headers)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", geminiFileError("start upload", resp.StatusCode, bodyBytes)
+	}
+
+	uploadURL := resp.Header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return "", fmt.Errorf("%w: upload start response did not include an upload URL", ErrAPICallFailed)
+	}
+	return uploadURL, nil
+}
+
+// queryUploadOffset asks the server how many bytes of a resumable upload it has received
+func (m *GeminiModel) queryUploadOffset(ctx context.Context, uploadURL string) (int, error) {
+	resp, bodyBytes, err := m.doRequest(ctx, uploadURL, "POST", nil, map[string]string{"X-Goog-Upload-Command": "query"})
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, geminiFileError("query upload", resp.StatusCode, bodyBytes)
+	}
+
+	received, err := strconv.Atoi(resp.Header.Get("X-Goog-Upload-Size-Received"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid upload size received: %w", err)
+	}
+	return received, nil
+}
+
+// waitForFileActive polls an uploaded file until the Files API has finished processing it
+func (m *GeminiModel) waitForFileActive(ctx context.Context, file *GeminiFileInfo) error {
+	interval := 500 * time.Millisecond
+	for {
+		switch file.State {
+		case "", "ACTIVE":
+			// Files that report no state are usable immediately
+			return nil
+		case "FAILED":
+			if file.Error != nil && file.Error.Message != "" {
+				return fmt.Errorf("%w: file processing failed: %s", ErrAPICallFailed, file.Error.Message)
+			}
+			return fmt.Errorf("%w: file processing failed", ErrAPICallFailed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrContextDeadlineExceeded
+		case <-time.After(interval):
+		}
+		if interval < 5*time.Second {
+			interval *= 2
+		}
+
+		latest, err := m.getFile(ctx, file.Name)
+		if err != nil {
+			return err
+		}
+		file = latest
+	}
+}
+
+// getFile fetches the current metadata for an uploaded file
+func (m *GeminiModel) getFile(ctx context.Context, name string) (*GeminiFileInfo, error) {
+	url := fmt.Sprintf("%s/%s?key=%s", m.baseEndpoint, name, m.config.APIKey)
+	resp, bodyBytes, err := m.doRequest(ctx, url, "GET", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, geminiFileError("get file", resp.StatusCode, bodyBytes)
+	}
+
+	var file GeminiFileInfo
+	if err := json.Unmarshal(bodyBytes, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse file metadata: %w", err)
+	}
+	return &file, nil
+}
+
+// deleteFile removes an uploaded file from the Files API
+func (m *GeminiModel) deleteFile(ctx context.Context, name string) error {
+	url := fmt.Sprintf("%s/%s?key=%s", m.baseEndpoint, name, m.config.APIKey)
+	resp, bodyBytes, err := m.doRequest(ctx, url, "DELETE", nil, nil)
+	if err != nil {
+		return err
+	}
+	// Already gone is as good as deleted
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return geminiFileError("delete file", resp.StatusCode, bodyBytes)
+	}
+	return nil
+}
+
+// deleteFileDetached deletes a file even if the request that uploaded it has been cancelled
+func (m *GeminiModel) deleteFileDetached(ctx context.Context, name string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 15*time.Second)
+	defer cancel()
+
+	if err := m.deleteFile(ctx, name); err != nil {
+		fmt.Printf("Warning: failed to delete uploaded file %s, leaving it for the sweeper: %v\n", name, err)
+	}
+}
+
+// SweepFiles deletes this service's uploads older than maxAge
+func (m *GeminiModel) SweepFiles(ctx context.Context, maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	deleted := 0
+	pageToken := ""
+
+	for {
+		url := fmt.Sprintf("%s/files?key=%s&pageSize=100", m.baseEndpoint, m.config.APIKey)
+		if pageToken != "" {
+			url += "&pageToken=" + pageToken
+		}
+
+		resp, bodyBytes, err := m.doRequest(ctx, url, "GET", nil, nil)
+		if err != nil {
+			return deleted, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return deleted, geminiFileError("list files", resp.StatusCode, bodyBytes)
+		}
+
+		var page GeminiFileListResponse
+		if err := json.Unmarshal(bodyBytes, &page); err != nil {
+			return deleted, fmt.Errorf("failed to parse file list: %w", err)
+		}
+
+		for _, file := range page.Files {
+			if !strings.HasPrefix(file.DisplayName, geminiFilePrefix) {
+				continue
+			}
+			created, err := time.Parse(time.RFC3339Nano, file.CreateTime)
+			if err == nil && created.After(cutoff) {
+				continue
+			}
+			if err := m.deleteFile(ctx, file.Name); err != nil {
+				fmt.Printf("Warning: sweeper failed to delete %s: %v\n", file.Name, err)
+				continue
+			}
+			deleted++
+			geminiFilesSwept.Inc()
+		}
+
+		if page.NextPageToken == "" {
+			return deleted, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// parseUploadedFile reads the file reference from a finalized upload response
+func parseUploadedFile(bodyBytes []byte) (*GeminiFileInfo, error) {
+	var fileResponse GeminiFileUploadResponse
+	if err := json.Unmarshal(bodyBytes, &fileResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse successful file upload response: %w", err)
+	}
+
+	// The 'Name' field (e.g., "files/xyz") is the reference needed for generateContent
+	if fileResponse.File.Name == "" {
+		return nil, fmt.Errorf("file upload response did not contain a file reference ('name')")
+	}
+
+	fmt.Printf("DEBUG: Successfully uploaded file. File reference: %s\n", fileResponse.File.Name)
+	return &fileResponse.File, nil
+}
+
+// geminiFileError converts a Files API error response into an error
+func geminiFileError(operation string, statusCode int, bodyBytes []byte) error {
+	var errorResponse GeminiErrorResponse
+	if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+		return fmt.Errorf("%w: %s: %s (status: %d)", ErrAPICallFailed, operation, errorResponse.Error.Message, statusCode)
+	}
+	return fmt.Errorf("%w: %s: status code %d", ErrAPICallFailed, operation, statusCode)
+}
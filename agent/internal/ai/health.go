@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Pinger is implemented by models that can check their API is reachable and accepts their credentials
+// without spending tokens
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks the first model in a chain of wrappers that can be pinged. Models that cannot are assumed
+// to be reachable.
+func Ping(ctx context.Context, model Model) error {
+	for model != nil {
+		if pinger, ok := model.(Pinger); ok {
+			return pinger.Ping(ctx)
+		}
+		wrapper, ok := model.(unwrapper)
+		if !ok {
+			return nil
+		}
+		model = wrapper.Unwrap()
+	}
+	return nil
+}
+
+// Ping checks the API key and model name by looking the model up
+func (m *OpenAIModel) Ping(ctx context.Context) error {
+	return pingEndpoint(ctx, m.client, fmt.Sprintf("%s/models/%s", m.baseEndpoint, m.modelName), map[string]string{
+		"Authorization": "Bearer " + m.config.APIKey,
+	})
+}
+
+// Ping checks the API key and model name by looking the model up
+func (m *ClaudeModel) Ping(ctx context.Context) error {
+	base := strings.TrimSuffix(strings.TrimSuffix(m.config.Endpoint, "/"), "/messages")
+	return pingEndpoint(ctx, m.client, fmt.Sprintf("%s/models/%s", base, m.modelName), map[string]string{
+		"X-API-Key":         m.config.APIKey,
+		"Anthropic-Version": "2023-06-01",
+	})
+}
+
+// Ping checks the API key and model name by looking the model up
+func (m *GeminiModel) Ping(ctx context.Context) error {
+	return pingEndpoint(ctx, m.client, fmt.Sprintf("%s/models/%s?key=%s", m.baseEndpoint, m.modelName, m.config.APIKey), nil)
+}
+
+// pingEndpoint makes a GET request and maps its status to the standard errors. The URL may carry an API
+// key, so errors name only the host.
+func pingEndpoint(ctx context.Context, client *http.Client, url string, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: endpoint is not a valid URL", ErrInvalidConfiguration)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrContextDeadlineExceeded
+		}
+		return fmt.Errorf("%w: %s is unreachable", ErrModelUnavailable, req.URL.Host)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w (status %d)", ErrAuthenticationFailed, resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: model not found (status 404)", ErrAPICallFailed)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimitExceeded
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == 529:
+		return ErrModelUnavailable
+	}
+	return fmt.Errorf("%w: status code %d", ErrAPICallFailed, resp.StatusCode)
+}
+
+// ErrorType names the kind of a model error for metrics and health reports
+func ErrorType(err error) string {
+	switch {
+	case errors.Is(err, ErrRateLimitExceeded):
+		return "rate_limited"
+	case errors.Is(err, ErrModelUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrContextDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrAuthenticationFailed):
+		return "authentication"
+	case errors.Is(err, ErrAPICallFailed):
+		return "api_error"
+	case errors.Is(err, ErrInvalidJSONSchema):
+		return "invalid_response"
+	case errors.Is(err, ErrUnsupportedRequestType), errors.Is(err, ErrInvalidAudioFormat):
+		return "unsupported_input"
+	case errors.Is(err, ErrInvalidConfiguration), errors.Is(err, ErrUnsupportedModel):
+		return "configuration"
+	}
+	return "other"
+}
+
+// ModelDependency is a model a provider may call and the part it plays
+type ModelDependency struct {
+	Model Model
+	Role  string // "default", "additional", "routed", "shadow" or "hedge"
+}
+
+// Dependencies lists each distinct model the provider may call, the default model first
+func (p *Provider) Dependencies() []ModelDependency {
+	dependencies := []ModelDependency{{Model: p.defaultModel, Role: "default"}}
+	seen := map[Model]bool{p.defaultModel: true}
+	add := func(model Model, role string) {
+		if model != nil && !seen[model] {
+			seen[model] = true
+			dependencies = append(dependencies, ModelDependency{Model: model, Role: role})
+		}
+	}
+
+	types := make([]string, 0, len(p.models))
+	for modelType := range p.models {
+		types = append(types, modelType)
+	}
+	sort.Strings(types)
+	for _, modelType := range types {
+		add(p.models[modelType], "additional")
+	}
+	for _, name := range p.routeOrder {
+		add(p.routed[name].model, "routed")
+	}
+	if p.shadow != nil {
+		add(p.shadow.Model, "shadow")
+	}
+	if p.hedge != nil {
+		add(p.hedge.Secondary, "hedge")
+	}
+	return dependencies
+}
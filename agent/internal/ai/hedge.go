@@ -0,0 +1,290 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"agent/internal/metrics"
+)
+
+// HedgeConfig configures hedged requests to a second provider
+type HedgeConfig struct {
+	// Secondary is the model on a second provider that receives the hedged request
+	Secondary Model
+
+	// Percentile of recent primary latencies after which the hedge is sent, e.g. 0.9
+	Percentile float64
+
+	// MinDelay and MaxDelay bound the hedge delay
+	MinDelay time.Duration
+	MaxDelay time.Duration
+
+	// InitialDelay is used until enough latency samples have been observed
+	InitialDelay time.Duration
+
+	// Window is the number of recent primary latencies kept
+	Window int
+
+	// Metrics receives hedge counters; defaults to metrics.Default
+	Metrics *metrics.Registry
+}
+
+// hedgingKey is the context key marking a request as latency-critical
+type hedgingKey struct{}
+
+// WithHedging marks a request as latency-critical so hedged models may send it to a second provider
+func WithHedging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hedgingKey{}, true)
+}
+
+// HedgingRequested reports whether the request was marked latency-critical
+func HedgingRequested(ctx context.Context) bool {
+	requested, _ := ctx.Value(hedgingKey{}).(bool)
+	return requested
+}
+
+// HedgedModel sends latency-critical text requests to a secondary model when the primary is slower than
+// its recent percentile latency. The first valid response wins and the other call is cancelled.
+// Audio and image requests are never hedged.
+type HedgedModel struct {
+	Model
+	config HedgeConfig
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+
+	requests      *metrics.Counter
+	hedged        *metrics.Counter
+	primaryWins   *metrics.Counter
+	secondaryWins *metrics.Counter
+	failures      *metrics.Counter
+	delayGauge    *metrics.Gauge
+}
+
+// NewHedgedModel wraps a primary model with hedging to a secondary model
+func NewHedgedModel(primary Model, config HedgeConfig) *HedgedModel {
+	if config.Percentile <= 0 || config.Percentile >= 1 {
+		config.Percentile = 0.9
+	}
+	if config.MinDelay == 0 {
+		config.MinDelay = 500 * time.Millisecond
+	}
+	if config.MaxDelay == 0 {
+		config.MaxDelay = 10 * time.Second
+	}
+	if config.InitialDelay == 0 {
+		config.InitialDelay = 3 * time.Second
+	}
+	if config.Window == 0 {
+		config.Window = 200
+	}
+	if config.Metrics == nil {
+		config.Metrics = metrics.Default
+	}
+
+	registry := config.Metrics
+	return &HedgedModel{
+		Model:         primary,
+		config:        config,
+		latencies:     make([]time.Duration, 0, config.Window),
+		requests:      registry.Counter("ai_hedge_eligible_requests_total", "Latency-critical model requests eligible for hedging"),
+		hedged:        registry.Counter("ai_hedge_sent_total", "Requests for which a hedge was sent to the secondary provider"),
+		primaryWins:   registry.Counter("ai_hedge_primary_wins_total", "Hedged requests won by the primary provider"),
+		secondaryWins: registry.Counter("ai_hedge_secondary_wins_total", "Hedged requests won by the secondary provider"),
+		failures:      registry.Counter("ai_hedge_failures_total", "Hedged requests where neither provider returned a valid response"),
+		delayGauge:    registry.GaugeVec("ai_hedge_delay_seconds", "Current hedge delay derived from primary latency, by primary model", "model").With(primary.Name()),
+	}
+}
+
+// Unwrap returns the primary model
+func (m *HedgedModel) Unwrap() Model {
+	return m.Model
+}
+
+// ProcessText hedges latency-critical text requests
+func (m *HedgedModel) ProcessText(ctx context.Context, prompt string) (*ModelResponse, error) {
+	return m.hedge(ctx, func(ctx context.Context, model Model) (*ModelResponse, error) {
+		return model.ProcessText(ctx, prompt)
+	})
+}
+
+// ProcessTextWithJson hedges latency-critical structured requests
+func (m *HedgedModel) ProcessTextWithJson(ctx context.Context, prompt string, schema string) (*ModelResponse, error) {
+	return m.hedge(ctx, func(ctx context.Context, model Model) (*ModelResponse, error) {
+		return model.ProcessTextWithJson(ctx, prompt, schema)
+	})
+}
+
+// ProcessConversation hedges latency-critical conversation turns
+func (m *HedgedModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	return m.hedge(ctx, func(ctx context.Context, model Model) (*ModelResponse, error) {
+		return model.ProcessConversation(ctx, messages)
+	})
+}
+
+// ProcessWithFunctions hedges latency-critical tool selection
+func (m *HedgedModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	return m.hedge(ctx, func(ctx context.Context, model Model) (*ModelResponse, error) {
+		return model.ProcessWithFunctions(ctx, messages, functions)
+	})
+}
+
+// hedge runs call against the primary and, after the hedge delay or a primary failure, the secondary
+func (m *HedgedModel) hedge(ctx context.Context, call func(ctx context.Context, model Model) (*ModelResponse, error)) (*ModelResponse, error) {
+	if !HedgingRequested(ctx) || m.config.Secondary == nil {
+		start := time.Now()
+		response, err := call(ctx, m.Model)
+		if err == nil {
+			m.observe(time.Since(start))
+		}
+		return response, err
+	}
+
+	m.requests.Inc()
+
+	// Cancelling this context stops whichever call loses
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		response  *ModelResponse
+		err       error
+		secondary bool
+	}
+	results := make(chan result, 2)
+	launch := func(model Model, secondary bool) {
+		go func() {
+			response, err := call(ctx, model)
+			results <- result{response: response, err: err, secondary: secondary}
+		}()
+	}
+
+	start := time.Now()
+	launch(m.Model, false)
+
+	delay := m.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	inflight, hedged := 1, false
+	sendHedge := func() {
+		if !hedged {
+			hedged = true
+			inflight++
+			m.hedged.Inc()
+			launch(m.config.Secondary, true)
+		}
+	}
+
+	var firstErr error
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			sendHedge()
+
+		case r := <-results:
+			inflight--
+			if !r.secondary && r.err == nil {
+				m.observe(time.Since(start))
+			}
+
+			if r.err == nil && validResponse(r.response) {
+				winner := m.Model
+				if r.secondary {
+					winner = m.config.Secondary
+					m.secondaryWins.Inc()
+					// The primary took at least this long; record it so the percentile isn't skewed low
+					m.observe(time.Since(start))
+				} else if hedged {
+					m.primaryWins.Inc()
+				}
+
+				if hedged {
+					if r.response.Metadata == nil {
+						r.response.Metadata = make(map[string]interface{})
+					}
+					r.response.Metadata["hedged"] = true
+					r.response.Metadata["hedge_winner"] = winner.Name()
+					r.response.Metadata["hedge_delay_ms"] = delay.Milliseconds()
+				}
+				return r.response, nil
+			}
+
+			if firstErr == nil {
+				firstErr = r.err
+				if firstErr == nil {
+					firstErr = fmt.Errorf("empty response from %s", m.modelName(r.secondary))
+				}
+			}
+
+			// A failed primary shouldn't wait out the delay
+			sendHedge()
+
+		case <-ctx.Done():
+			if hedged {
+				m.failures.Inc()
+			}
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrContextDeadlineExceeded
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	m.failures.Inc()
+	return nil, firstErr
+}
+
+// delay returns the configured percentile of recent primary latencies, clamped to the configured bounds
+func (m *HedgedModel) delay() time.Duration {
+	m.mu.Lock()
+	samples := append([]time.Duration(nil), m.latencies...)
+	m.mu.Unlock()
+
+	delay := m.config.InitialDelay
+	if len(samples) >= 20 {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		index := int(math.Ceil(m.config.Percentile*float64(len(samples)))) - 1
+		delay = samples[index]
+	}
+
+	if delay < m.config.MinDelay {
+		delay = m.config.MinDelay
+	}
+	if delay > m.config.MaxDelay {
+		delay = m.config.MaxDelay
+	}
+
+	m.delayGauge.Set(delay.Seconds())
+	return delay
+}
+
+// observe records a primary latency sample
+func (m *HedgedModel) observe(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.latencies) < m.config.Window {
+		m.latencies = append(m.latencies, latency)
+		return
+	}
+	m.latencies[m.next] = latency
+	m.next = (m.next + 1) % m.config.Window
+}
+
+func (m *HedgedModel) modelName(secondary bool) string {
+	if secondary {
+		return m.config.Secondary.Name()
+	}
+	return m.Model.Name()
+}
+
+// validResponse reports whether a response carries usable output
+func validResponse(response *ModelResponse) bool {
+	return response != nil && (response.Content != "" || len(response.FunctionCalls) > 0)
+}
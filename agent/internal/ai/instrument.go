@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"agent/internal/metrics"
+	"agent/internal/models"
+)
+
+var (
+	modelRequestDuration = metrics.Default.HistogramVec("ai_model_request_duration_seconds",
+		"Latency of model requests by model and kind of request", nil, "model", "request")
+	modelErrors = metrics.Default.CounterVec("ai_model_errors_total",
+		"Failed model requests by model and type of error", "model", "type")
+)
+
+// InstrumentedModel records the latency and errors of every request to the wrapped model
+type InstrumentedModel struct {
+	Model
+}
+
+// NewInstrumentedModel wraps a model with request metrics
+func NewInstrumentedModel(model Model) Model {
+	return &InstrumentedModel{Model: model}
+}
+
+// Unwrap returns the wrapped model
+func (m *InstrumentedModel) Unwrap() Model {
+	return m.Model
+}
+
+// ProcessText records the request's latency and any error
+func (m *InstrumentedModel) ProcessText(ctx context.Context, prompt string) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessText(ctx, prompt)
+	m.observe("text", start, err)
+	return response, err
+}
+
+// ProcessAudio records the request's latency and any error
+func (m *InstrumentedModel) ProcessAudio(ctx context.Context, input *AudioInput, prompt string) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessAudio(ctx, input, prompt)
+	m.observe("audio", start, err)
+	return response, err
+}
+
+// ProcessImage records the request's latency and any error
+func (m *InstrumentedModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessImage(ctx, input, prompt)
+	m.observe("image", start, err)
+	return response, err
+}
+
+// ProcessTextWithJson records the request's latency and any error
+func (m *InstrumentedModel) ProcessTextWithJson(ctx context.Context, prompt string, schema string) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessTextWithJson(ctx, prompt, schema)
+	m.observe("json", start, err)
+	return response, err
+}
+
+// ProcessConversation records the request's latency and any error
+func (m *InstrumentedModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessConversation(ctx, messages)
+	m.observe("conversation", start, err)
+	return response, err
+}
+
+// ProcessWithFunctions records the request's latency and any error
+func (m *InstrumentedModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	start := time.Now()
+	response, err := m.Model.ProcessWithFunctions(ctx, messages, functions)
+	m.observe("functions", start, err)
+	return response, err
+}
+
+// Transcribe records the latency and any error of a transcription. It returns ErrUnsupportedRequestType
+// when no model in the wrapped chain can transcribe.
+func (m *InstrumentedModel) Transcribe(ctx context.Context, input *AudioInput) (*models.Transcript, error) {
+	transcriber, ok := AsTranscriber(m.Model)
+	if !ok {
+		return nil, ErrUnsupportedRequestType
+	}
+	start := time.Now()
+	transcript, err := transcriber.Transcribe(ctx, input)
+	m.observe("transcribe", start, err)
+	return transcript, err
+}
+
+// observe records one request
+func (m *InstrumentedModel) observe(request string, start time.Time, err error) {
+	name := m.Model.Name()
+	modelRequestDuration.With(name, request).ObserveSince(start)
+	if err != nil {
+		modelErrors.With(name, ErrorType(err)).Inc()
+	}
+}
@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"io"
+	"strings"
 )
 
 // ModelType represents the type of AI model
@@ -52,6 +53,9 @@ type ModelResponse struct {
 
 	// Format indicates whether the response is plain text, structured JSON, etc.
 	Format string
+
+	// FunctionCalls lists the functions the model asked to call, if any
+	FunctionCalls []FunctionCall
 }
 
 // Common response formats
@@ -79,6 +83,49 @@ type AudioInput struct {
 	AudioFormat string
 }
 
+// Conversation roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	// RoleFunction marks a message carrying the result of a function call back to the model
+	RoleFunction = "function"
+)
+
+// Message represents a single turn in a conversation with a model
+type Message struct {
+	Role    string
+	Content string
+
+	// FunctionCalls holds the calls requested by the model in an assistant turn
+	FunctionCalls []FunctionCall
+
+	// FunctionCallID and FunctionName identify the call a RoleFunction message answers
+	FunctionCallID string
+	FunctionName   string
+}
+
+// FunctionDeclaration describes a function the model may call, with its parameters as a JSON schema object
+type FunctionDeclaration struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// FunctionCall is a model's request to invoke a declared function
+type FunctionCall struct {
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// ImageInput represents an image input to be processed
+type ImageInput struct {
+	Image    io.Reader
+	MIMEType string
+}
+
 // Model defines the interface for all AI model implementations
 type Model interface {
 	// Name returns the name of the model implementation
@@ -96,8 +143,49 @@ type Model interface {
 	// ProcessAudio processes audio input and returns a standardized response
 	ProcessAudio(ctx context.Context, input *AudioInput, prompt string) (*ModelResponse, error)
 
+	// ProcessImage processes an image together with a text prompt and returns a standardized response
+	ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error)
+
 	// ProcessTextWithJson processes a text prompt and returns structured JSON as a standardized response
 	ProcessTextWithJson(ctx context.Context, prompt string, jsonSchema string) (*ModelResponse, error)
+
+	// ProcessConversation processes a role-tagged message history and returns the model's next reply
+	ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error)
+
+	// ProcessWithFunctions runs a conversation in which the model may request calls to the declared functions
+	ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error)
+}
+
+// SupportsRequestType reports whether the model advertises support for the given request type
+func SupportsRequestType(model Model, requestType RequestType) bool {
+	for _, supported := range model.SupportedRequestTypes() {
+		if supported == requestType {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSystemMessages separates system instructions from the dialogue and merges consecutive
+// plain-text turns from the same role, which providers with strict user/assistant alternation require
+func splitSystemMessages(messages []Message) (string, []Message) {
+	var system []string
+	var dialogue []Message
+
+	for _, message := range messages {
+		if message.Role == RoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		if n := len(dialogue); n > 0 && dialogue[n-1].Role == message.Role && message.Role != RoleFunction &&
+			len(message.FunctionCalls) == 0 && len(dialogue[n-1].FunctionCalls) == 0 {
+			dialogue[n-1].Content += "\n\n" + message.Content
+			continue
+		}
+		dialogue = append(dialogue, message)
+	}
+
+	return strings.Join(system, "\n\n"), dialogue
 }
 
 // Factory function type for creating models
@@ -4,6 +4,7 @@ package ai
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +12,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"agent/internal/models"
 )
 
 // Default configuration values for OpenAI
@@ -114,6 +117,18 @@ type OpenAIMessage struct {
 		Name      string `json:"name"`
 		Arguments string `json:"arguments"`
 	} `json:"function_call,omitempty"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// OpenAIToolCall is a function call requested through the tools API
+type OpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded arguments
+	} `json:"function"`
 }
 
 type OpenAITextContent struct {
@@ -135,6 +150,8 @@ type OpenAIChatRequest struct {
 	Temperature  float64         `json:"temperature,omitempty"`
 	Functions    interface{}     `json:"functions,omitempty"`     // Renamed from Tools
 	FunctionCall interface{}     `json:"function_call,omitempty"` // Renamed from ToolChoice
+	Tools        interface{}     `json:"tools,omitempty"`
+	ToolChoice   interface{}     `json:"tool_choice,omitempty"`
 }
 
 type OpenAIChatResponse struct {
@@ -175,6 +192,16 @@ type OpenAIAudioTranscriptionResponse struct {
 	Text string `json:"text"`
 }
 
+// OpenAIVerboseTranscriptionResponse is a transcription with segment timings
+type OpenAIVerboseTranscriptionResponse struct {
+	Text     string `json:"text"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
 // -- Helper function for API calls --
 
 func (m *OpenAIModel) doRequest(ctx context.Context, url string, method string, body io.Reader, headers map[string]string) (*http.Response, []byte, error) {
@@ -190,17 +217,8 @@ func (m *OpenAIModel) doRequest(ctx context.Context, url string, method string,
 		req.Header.Set(key, value)
 	}
 
-	fmt.Printf("DEBUG: Sending %s request to: %s\n", method, url)
-	if method == "POST" && headers["Content-Type"] == "application/json" && body != nil {
-		// Log JSON body carefully (could be large)
-		buf := new(bytes.Buffer)
-		if _, readErr := buf.ReadFrom(req.Body); readErr == nil {
-			// Truncate large payloads in logs
-			bodyStr := buf.String()
-			// Restore the body for the actual request
-			req.Body = io.NopCloser(bytes.NewBufferString(bodyStr))
-		}
-	}
+	// Request bodies carry caller text, so only the method and path are logged
+	fmt.Printf("DEBUG: Sending %s request to: %s\n", method, logSafeURL(url))
 
 	resp, err := m.client.Do(req)
 	if err != nil {
@@ -326,7 +344,7 @@ func (m *OpenAIModel) ProcessAudio(ctx context.Context, input *AudioInput, promp
 	}
 
 	// Step 1: First use OpenAI's Audio API for transcription
-	transcription, err := m.transcribeAudio(ctx, audioData, input.MIMEType, input.Language)
+	transcription, err := m.transcribeAudio(ctx, audioData, input.AudioFormat, input.Language)
 	if err != nil {
 		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
 	}
@@ -344,6 +362,278 @@ func (m *OpenAIModel) ProcessAudio(ctx context.Context, input *AudioInput, promp
 	return m.generateEmergencyResponse(ctx, transcription, emotionAnalysisResp, prompt)
 }
 
+// ProcessImage analyzes an image sent as a data URL together with a text prompt
+func (m *OpenAIModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	if !SupportsRequestType(m, ImageRequest) {
+		return nil, ErrUnsupportedRequestType
+	}
+
+	imageData, err := io.ReadAll(input.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+
+	mimeType := input.MIMEType
+	if mimeType == "" {
+		mimeType = DetectImageMIMEType(imageData)
+		if mimeType == "" {
+			return nil, fmt.Errorf("%w: could not determine image MIME type", ErrUnsupportedRequestType)
+		}
+	}
+
+	imageContent := OpenAIImageContent{Type: "image_url"}
+	imageContent.ImageURL.URL = fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(imageData))
+
+	url := fmt.Sprintf("%s/chat/completions", m.baseEndpoint)
+	payload := OpenAIChatRequest{
+		Model: m.modelName,
+		Messages: []OpenAIMessage{
+			{
+				Role: "user",
+				Content: []interface{}{
+					OpenAITextContent{Type: "text", Text: prompt},
+					imageContent,
+				},
+			},
+		},
+		MaxTokens:   m.config.MaxTokens,
+		Temperature: m.config.Temperature,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, bodyBytes, err := m.doRequest(ctx, url, "POST", bytes.NewBuffer(jsonPayload), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, openAIStatusError(resp.StatusCode, bodyBytes)
+	}
+
+	var response OpenAIChatResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse successful response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty or unexpected response structure from model: no choices found")
+	}
+
+	textContent, ok := response.Choices[0].Message.Content.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected content format in response: %T", response.Choices[0].Message.Content)
+	}
+
+	return &ModelResponse{
+		Content: textContent,
+		Raw:     response,
+		Format:  FormatText,
+		Metadata: map[string]interface{}{
+			"model":             response.Model,
+			"finish_reason":     response.Choices[0].FinishReason,
+			"prompt_tokens":     response.Usage.PromptTokens,
+			"completion_tokens": response.Usage.CompletionTokens,
+			"total_tokens":      response.Usage.TotalTokens,
+			"image_mime_type":   mimeType,
+		},
+	}, nil
+}
+
+// ProcessConversation sends a multi-turn conversation to the chat completions API
+func (m *OpenAIModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("conversation must contain at least one message")
+	}
+
+	openAIMessages := make([]OpenAIMessage, 0, len(messages))
+	for _, message := range messages {
+		openAIMessages = append(openAIMessages, OpenAIMessage{Role: message.Role, Content: message.Content})
+	}
+
+	url := fmt.Sprintf("%s/chat/completions", m.baseEndpoint)
+	payload := OpenAIChatRequest{
+		Model:       m.modelName,
+		Messages:    openAIMessages,
+		MaxTokens:   m.config.MaxTokens,
+		Temperature: m.config.Temperature,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, bodyBytes, err := m.doRequest(ctx, url, "POST", bytes.NewBuffer(jsonPayload), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, openAIStatusError(resp.StatusCode, bodyBytes)
+	}
+
+	var response OpenAIChatResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse successful response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty or unexpected response structure from model: no choices found")
+	}
+
+	textContent, ok := response.Choices[0].Message.Content.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected content format in response: %T", response.Choices[0].Message.Content)
+	}
+
+	return &ModelResponse{
+		Content: textContent,
+		Raw:     response,
+		Format:  FormatText,
+		Metadata: map[string]interface{}{
+			"model":             response.Model,
+			"finish_reason":     response.Choices[0].FinishReason,
+			"prompt_tokens":     response.Usage.PromptTokens,
+			"completion_tokens": response.Usage.CompletionTokens,
+			"total_tokens":      response.Usage.TotalTokens,
+			"turns":             len(messages),
+		},
+	}, nil
+}
+
+// ProcessWithFunctions runs a conversation in which the model may answer with tool_calls
+func (m *OpenAIModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("conversation must contain at least one message")
+	}
+
+	openAIMessages := make([]OpenAIMessage, 0, len(messages))
+	for _, message := range messages {
+		switch message.Role {
+		case RoleFunction:
+			openAIMessages = append(openAIMessages, OpenAIMessage{
+				Role:       "tool",
+				Content:    message.Content,
+				ToolCallID: message.FunctionCallID,
+			})
+		case RoleAssistant:
+			openAIMessage := OpenAIMessage{Role: "assistant"}
+			if message.Content != "" {
+				openAIMessage.Content = message.Content
+			}
+			for _, call := range message.FunctionCalls {
+				arguments, err := json.Marshal(call.Arguments)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal arguments for %s: %w", call.Name, err)
+				}
+				toolCall := OpenAIToolCall{ID: call.ID, Type: "function"}
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments = string(arguments)
+				openAIMessage.ToolCalls = append(openAIMessage.ToolCalls, toolCall)
+			}
+			openAIMessages = append(openAIMessages, openAIMessage)
+		default:
+			openAIMessages = append(openAIMessages, OpenAIMessage{Role: message.Role, Content: message.Content})
+		}
+	}
+
+	openAITools := make([]map[string]interface{}, 0, len(functions))
+	for _, function := range functions {
+		openAITools = append(openAITools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        function.Name,
+				"description": function.Description,
+				"parameters":  function.Parameters,
+			},
+		})
+	}
+
+	url := fmt.Sprintf("%s/chat/completions", m.baseEndpoint)
+	payload := OpenAIChatRequest{
+		Model:       m.modelName,
+		Messages:    openAIMessages,
+		Tools:       openAITools,
+		ToolChoice:  "auto",
+		MaxTokens:   m.config.MaxTokens,
+		Temperature: 0.2, // Tool selection should be predictable
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, bodyBytes, err := m.doRequest(ctx, url, "POST", bytes.NewBuffer(jsonPayload), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, openAIStatusError(resp.StatusCode, bodyBytes)
+	}
+
+	var response OpenAIChatResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse successful response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty or unexpected response structure from model: no choices found")
+	}
+
+	message := response.Choices[0].Message
+	textContent, _ := message.Content.(string) // Content is null when the model only calls tools
+
+	var calls []FunctionCall
+	for _, toolCall := range message.ToolCalls {
+		var arguments map[string]interface{}
+		if toolCall.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &arguments); err != nil {
+				return nil, fmt.Errorf("%w: invalid arguments for %s: %s", ErrInvalidJSONSchema, toolCall.Function.Name, err.Error())
+			}
+		}
+		calls = append(calls, FunctionCall{ID: toolCall.ID, Name: toolCall.Function.Name, Arguments: arguments})
+	}
+
+	return &ModelResponse{
+		Content: textContent,
+		Raw:     response,
+		Format:  FormatText,
+		Metadata: map[string]interface{}{
+			"model":             response.Model,
+			"finish_reason":     response.Choices[0].FinishReason,
+			"prompt_tokens":     response.Usage.PromptTokens,
+			"completion_tokens": response.Usage.CompletionTokens,
+			"total_tokens":      response.Usage.TotalTokens,
+		},
+		FunctionCalls: calls,
+	}, nil
+}
+
+// openAIStatusError maps a non-200 chat completions response to one of the standard errors
+func openAIStatusError(statusCode int, bodyBytes []byte) error {
+	var errorResponse OpenAIErrorResponse
+	if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+		switch statusCode {
+		case http.StatusTooManyRequests:
+			return fmt.Errorf("%w: %s", ErrRateLimitExceeded, errorResponse.Error.Message)
+		case http.StatusServiceUnavailable:
+			return fmt.Errorf("%w: %s", ErrModelUnavailable, errorResponse.Error.Message)
+		default:
+			return fmt.Errorf("%w: %s (status: %d)", ErrAPICallFailed, errorResponse.Error.Message, statusCode)
+		}
+	}
+	return fmt.Errorf("%w: status code %d", ErrAPICallFailed, statusCode)
+}
+
 // analyzeEmotionsAndTone uses the completions API to analyze emotions and tone from transcribed text
 func (m *OpenAIModel) analyzeEmotionsAndTone(ctx context.Context, transcription string) (string, error) {
 	url := fmt.Sprintf("%s/chat/completions", m.baseEndpoint)
@@ -478,39 +768,90 @@ Based on this information, provide a comprehensive emergency response with appro
 }
 
 // transcribeAudio uses OpenAI's Audio API to convert speech to text
-func (m *OpenAIModel) transcribeAudio(ctx context.Context, audioData []byte, mimeType string, language string) (string, error) {
+func (m *OpenAIModel) transcribeAudio(ctx context.Context, audioData []byte, audioFormat string, language string) (string, error) {
+	bodyBytes, err := m.requestTranscription(ctx, audioData, audioFormat, language, "json")
+	if err != nil {
+		return "", err
+	}
+
+	var transcription OpenAIAudioTranscriptionResponse
+	if err := json.Unmarshal(bodyBytes, &transcription); err != nil {
+		return "", fmt.Errorf("failed to parse transcription response: %w", err)
+	}
+
+	return transcription.Text, nil
+}
+
+// Transcribe returns a timed transcript of the recording. Whisper does not separate speakers.
+func (m *OpenAIModel) Transcribe(ctx context.Context, input *AudioInput) (*models.Transcript, error) {
+	audioData, err := io.ReadAll(input.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio data: %w", err)
+	}
+
+	bodyBytes, err := m.requestTranscription(ctx, audioData, input.AudioFormat, input.Language, "verbose_json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	var transcription OpenAIVerboseTranscriptionResponse
+	if err := json.Unmarshal(bodyBytes, &transcription); err != nil {
+		return nil, fmt.Errorf("failed to parse transcription response: %w", err)
+	}
+
+	transcript := &models.Transcript{}
+	for _, segment := range transcription.Segments {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{
+			StartSeconds: segment.Start,
+			EndSeconds:   segment.End,
+			Speaker:      models.SpeakerUnknown,
+			Text:         strings.TrimSpace(segment.Text),
+		})
+	}
+	return transcript, nil
+}
+
+// requestTranscription uploads audio to OpenAI's Audio API and returns the raw response body
+func (m *OpenAIModel) requestTranscription(ctx context.Context, audioData []byte, audioFormat string, language string, responseFormat string) ([]byte, error) {
 	url := fmt.Sprintf("%s/audio/transcriptions", m.baseEndpoint)
 
 	// Create multipart form data
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	// Add the file part
-	part, err := writer.CreateFormFile("file", "audio.mp3")
+	// The transcription API identifies the container from the file extension
+	if audioFormat == "" {
+		audioFormat = "mp3"
+	}
+	part, err := writer.CreateFormFile("file", "audio."+audioFormat)
 	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
 	if _, err := part.Write(audioData); err != nil {
-		return "", fmt.Errorf("failed to write audio data: %w", err)
+		return nil, fmt.Errorf("failed to write audio data: %w", err)
 	}
 
 	// Add other fields
 	if err := writer.WriteField("model", "whisper-1"); err != nil {
-		return "", fmt.Errorf("failed to add model field: %w", err)
+		return nil, fmt.Errorf("failed to add model field: %w", err)
+	}
+
+	if err := writer.WriteField("response_format", responseFormat); err != nil {
+		return nil, fmt.Errorf("failed to add response format field: %w", err)
 	}
 
 	if language != "" {
 		if err := writer.WriteField("language", language); err != nil {
-			return "", fmt.Errorf("failed to add language field: %w", err)
+			return nil, fmt.Errorf("failed to add language field: %w", err)
 		}
 	}
 
 	if err := writer.WriteField("temperature", fmt.Sprintf("%.1f", m.config.Temperature)); err != nil {
-		return "", fmt.Errorf("failed to add temperature field: %w", err)
+		return nil, fmt.Errorf("failed to add temperature field: %w", err)
 	}
 
 	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("failed to close multipart writer: %w", err)
+		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
 	// Set the content type header
@@ -520,23 +861,18 @@ func (m *OpenAIModel) transcribeAudio(ctx context.Context, audioData []byte, mim
 
 	resp, bodyBytes, err := m.doRequest(ctx, url, "POST", body, headers)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errorResponse OpenAIErrorResponse
 		if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil && errorResponse.Error.Message != "" {
-			return "", fmt.Errorf("%w: %s (status: %d)", ErrAPICallFailed, errorResponse.Error.Message, resp.StatusCode)
+			return nil, fmt.Errorf("%w: %s (status: %d)", ErrAPICallFailed, errorResponse.Error.Message, resp.StatusCode)
 		}
-		return "", fmt.Errorf("%w: status code %d from %s", ErrAPICallFailed, resp.StatusCode, url)
-	}
-
-	var transcription OpenAIAudioTranscriptionResponse
-	if err := json.Unmarshal(bodyBytes, &transcription); err != nil {
-		return "", fmt.Errorf("failed to parse transcription response: %w", err)
+		return nil, fmt.Errorf("%w: status code %d from %s", ErrAPICallFailed, resp.StatusCode, url)
 	}
 
-	return transcription.Text, nil
+	return bodyBytes, nil
 }
 
 // ProcessTextWithJson processes a text prompt and returns structured JSON
@@ -595,9 +931,6 @@ func (m *OpenAIModel) ProcessTextWithJson(ctx context.Context, prompt string, js
 		return nil, fmt.Errorf("failed to parse successful response: %w", err)
 	}
 
-	// Debug output to help diagnose issues
-	fmt.Printf("DEBUG: Response from Model: %+v\n", response)
-
 	if len(response.Choices) == 0 {
 		return nil, fmt.Errorf("empty response from model when expecting function call")
 	}
@@ -613,7 +946,7 @@ func (m *OpenAIModel) ProcessTextWithJson(ctx context.Context, prompt string, js
 	// Basic validation: Check if it's valid JSON
 	var jsonObj interface{}
 	if err := json.Unmarshal([]byte(jsonStr), &jsonObj); err != nil {
-		fmt.Printf("DEBUG: Failed JSON validation. String was: %s\n", jsonStr)
+		fmt.Printf("DEBUG: Failed JSON validation (%d bytes)\n", len(jsonStr))
 		return nil, fmt.Errorf("%w: model response is not valid JSON: %s", ErrInvalidJSONSchema, err.Error())
 	}
 
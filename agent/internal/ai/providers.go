@@ -1,13 +1,31 @@
 package ai
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"agent/internal/redact"
 )
 
 // Provider manages AI model instances
 type Provider struct {
 	defaultModel Model
 	models       map[string]Model
+	redactor     *redact.Redactor
+	shadow       *ShadowConfig
+	hedge        *HedgeConfig
+
+	// hedgers keeps one hedged wrapper per model so latency history survives across requests
+	hedgers *sync.Map
+
+	// routed holds models registered for routing by model name, in registration order
+	routed     map[string]tieredModel
+	routeOrder []string
 }
 
 // NewProvider creates a new AI model provider
@@ -22,25 +40,69 @@ func NewProvider(defaultModelType ModelType, config ModelConfig) (*Provider, err
 	provider := &Provider{
 		defaultModel: defaultModel,
 		models:       make(map[string]Model),
+		routed:       make(map[string]tieredModel),
+		hedgers:      &sync.Map{},
 	}
 
 	// Add the default model to the models map
 	provider.models[string(defaultModelType)] = defaultModel
 
+	// The default model serves the strong tier
+	if config.ModelName != "" {
+		provider.routed[config.ModelName] = tieredModel{model: defaultModel, tier: TierStrong}
+		provider.routeOrder = append(provider.routeOrder, config.ModelName)
+	}
+
 	return provider, nil
 }
 
 // DefaultModel returns the default model
 func (p *Provider) DefaultModel() Model {
-	return p.defaultModel
+	return p.wrap(p.defaultModel)
 }
 
 // Model returns a specific model by type or the default model if not found
 func (p *Provider) Model(modelType ModelType) Model {
 	if model, ok := p.models[string(modelType)]; ok {
-		return model
+		return p.wrap(model)
 	}
-	return p.defaultModel
+	return p.wrap(p.defaultModel)
+}
+
+// SetRedactor enables PII redaction for every model handed out by the provider
+func (p *Provider) SetRedactor(redactor *redact.Redactor) {
+	p.redactor = redactor
+}
+
+// SetShadow runs a shadow model alongside every model handed out by the provider
+func (p *Provider) SetShadow(config ShadowConfig) {
+	p.shadow = &config
+}
+
+// SetHedge hedges latency-critical requests from every model handed out by the provider to a second provider
+func (p *Provider) SetHedge(config HedgeConfig) {
+	p.hedge = &config
+}
+
+// wrap applies the provider's request pipeline to a model. Redaction is outermost so
+// that the shadow and hedge models, like the primary, only ever see redacted input. Metrics
+// measure requests as callers see them, including any hedge.
+func (p *Provider) wrap(model Model) Model {
+	if p.hedge != nil && p.hedge.Secondary != nil && model != p.hedge.Secondary {
+		hedger, ok := p.hedgers.Load(model)
+		if !ok {
+			hedger, _ = p.hedgers.LoadOrStore(model, NewHedgedModel(model, *p.hedge))
+		}
+		model = hedger.(*HedgedModel)
+	}
+	if p.shadow != nil && p.shadow.Model != nil {
+		model = NewShadowModel(model, *p.shadow)
+	}
+	model = NewInstrumentedModel(model)
+	if p.redactor != nil {
+		model = NewRedactingModel(model, p.redactor)
+	}
+	return model
 }
 
 // AddModel adds a new model to the provider
@@ -67,6 +129,12 @@ func (p *Provider) WithDefaultModel(modelType ModelType) (*Provider, error) {
 		return &Provider{
 			defaultModel: model,
 			models:       p.models,
+			redactor:     p.redactor,
+			shadow:       p.shadow,
+			hedge:        p.hedge,
+			routed:       p.routed,
+			routeOrder:   p.routeOrder,
+			hedgers:      p.hedgers,
 		}, nil
 	}
 	return nil, fmt.Errorf("model %s not found", modelType)
@@ -89,3 +157,55 @@ func DetectMIMEType(format string) string {
 		return "audio/mpeg" // Default to MP3
 	}
 }
+
+// DetectImageMIMEType sniffs the MIME type of image data, returning an empty string if it is not an image
+func DetectImageMIMEType(data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ""
+	}
+	return mimeType
+}
+
+// logSafeURL strips the query string, which may carry an API key, from a URL before it is logged
+func logSafeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	parsed.RawQuery = ""
+	return parsed.String()
+}
+
+// StartFileSweeper periodically deletes leftover third-party uploads for every model that stores them,
+// until ctx is cancelled
+func (p *Provider) StartFileSweeper(ctx context.Context, interval, maxAge time.Duration) {
+	for _, dependency := range p.Dependencies() {
+		model := dependency.Model
+		sweeper, ok := model.(FileSweeper)
+		if !ok {
+			continue
+		}
+
+		go func(name string, sweeper FileSweeper) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				// Sweep once at startup to catch files left by a previous process
+				deleted, err := sweeper.SweepFiles(ctx, maxAge)
+				if err != nil {
+					fmt.Printf("Warning: file sweep for %s failed: %v\n", name, err)
+				} else if deleted > 0 {
+					fmt.Printf("INFO: Swept %d leftover uploads for %s\n", deleted, name)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(model.Name(), sweeper)
+	}
+}
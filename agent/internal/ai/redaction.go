@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+
+	"agent/internal/redact"
+)
+
+// RedactingModel tokenises PII in everything sent to the wrapped model and restores it in the output.
+// Audio and image payloads cannot be redacted; only their prompts are.
+type RedactingModel struct {
+	Model
+	redactor *redact.Redactor
+}
+
+// NewRedactingModel wraps a model with PII redaction
+func NewRedactingModel(model Model, redactor *redact.Redactor) Model {
+	return &RedactingModel{Model: model, redactor: redactor}
+}
+
+// Unwrap returns the wrapped model
+func (m *RedactingModel) Unwrap() Model {
+	return m.Model
+}
+
+// ProcessText redacts the prompt and restores placeholders in the reply
+func (m *RedactingModel) ProcessText(ctx context.Context, prompt string) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessText(ctx, session.Redact(prompt))
+	return restoreResponse(session, response), err
+}
+
+// ProcessAudio redacts the prompt; the audio itself is sent unchanged
+func (m *RedactingModel) ProcessAudio(ctx context.Context, input *AudioInput, prompt string) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessAudio(ctx, input, session.Redact(prompt))
+	return restoreResponse(session, response), err
+}
+
+// ProcessImage redacts the prompt; the image itself is sent unchanged
+func (m *RedactingModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessImage(ctx, input, session.Redact(prompt))
+	return restoreResponse(session, response), err
+}
+
+// ProcessTextWithJson redacts the prompt and restores placeholders inside the JSON reply
+func (m *RedactingModel) ProcessTextWithJson(ctx context.Context, prompt string, schema string) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessTextWithJson(ctx, session.Redact(prompt), schema)
+	return restoreResponse(session, response), err
+}
+
+// ProcessConversation redacts every turn with one session so placeholders stay consistent across the conversation
+func (m *RedactingModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessConversation(ctx, redactMessages(session, messages))
+	return restoreResponse(session, response), err
+}
+
+// ProcessWithFunctions redacts every turn and restores placeholders in the reply and in function call arguments
+func (m *RedactingModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	session := m.session(ctx)
+	response, err := m.Model.ProcessWithFunctions(ctx, redactMessages(session, messages), functions)
+	if response != nil {
+		for i := range response.FunctionCalls {
+			response.FunctionCalls[i].Arguments = mapStrings(response.FunctionCalls[i].Arguments, session.Restore)
+		}
+	}
+	return restoreResponse(session, response), err
+}
+
+// session starts a redaction session seeded with any known entities on the context
+func (m *RedactingModel) session(ctx context.Context) *redact.Session {
+	return m.redactor.NewSession(redact.KnownEntitiesFromContext(ctx)...)
+}
+
+// redactMessages returns redacted copies of the messages, leaving the caller's slice untouched
+func redactMessages(session *redact.Session, messages []Message) []Message {
+	redacted := make([]Message, len(messages))
+	for i, message := range messages {
+		message.Content = session.Redact(message.Content)
+		if len(message.FunctionCalls) > 0 {
+			calls := make([]FunctionCall, len(message.FunctionCalls))
+			for j, call := range message.FunctionCalls {
+				call.Arguments = mapStrings(call.Arguments, session.Redact)
+				calls[j] = call
+			}
+			message.FunctionCalls = calls
+		}
+		redacted[i] = message
+	}
+	return redacted
+}
+
+// restoreResponse puts the original values back into the response content and records how much was redacted
+func restoreResponse(session *redact.Session, response *ModelResponse) *ModelResponse {
+	if response == nil {
+		return nil
+	}
+
+	if response.Format == FormatJSON {
+		response.Content = session.RestoreJSON(response.Content)
+	} else {
+		response.Content = session.Restore(response.Content)
+	}
+
+	if count := session.Count(); count > 0 {
+		if response.Metadata == nil {
+			response.Metadata = make(map[string]interface{})
+		}
+		response.Metadata["pii_redacted"] = count
+	}
+
+	return response
+}
+
+// mapStrings applies fn to every string value in a decoded JSON object
+func mapStrings(args map[string]interface{}, fn func(string) string) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+
+	mapped := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		mapped[key] = mapValue(value, fn)
+	}
+	return mapped
+}
+
+func mapValue(value interface{}, fn func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		return mapStrings(v, fn)
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			mapped[i] = mapValue(item, fn)
+		}
+		return mapped
+	default:
+		return value
+	}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// ModelTier groups models by cost and capability for routing
+type ModelTier string
+
+const (
+	// TierFast is a cheaper, lower-latency model tried first (e.g. Flash, Haiku, 4o-mini)
+	TierFast ModelTier = "fast"
+
+	// TierStrong is the more capable model used directly or on escalation
+	TierStrong ModelTier = "strong"
+)
+
+// tieredModel is a model registered for routing
+type tieredModel struct {
+	model Model
+	tier  ModelTier
+}
+
+// modelPreferenceKey is the context key for a caller's model preference
+type modelPreferenceKey struct{}
+
+// WithModelPreference returns a context asking the provider to route to the named model.
+// The name may be a registered model name (e.g. "gemini-1.5-flash") or a model type (e.g. "claude").
+func WithModelPreference(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, modelPreferenceKey{}, name)
+}
+
+// ModelPreferenceFromContext returns the model preference attached to the context, if any
+func ModelPreferenceFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(modelPreferenceKey{}).(string)
+	return name
+}
+
+// RegisterModel creates a model and makes it available for routing under its model name and tier
+func (p *Provider) RegisterModel(tier ModelTier, modelType ModelType, config ModelConfig) error {
+	if config.ModelName == "" {
+		return fmt.Errorf("%w: a routed model needs a model name", ErrInvalidConfiguration)
+	}
+	if _, ok := p.routed[config.ModelName]; ok {
+		return fmt.Errorf("model %s already registered", config.ModelName)
+	}
+
+	model, err := GetModel(modelType, config)
+	if err != nil {
+		return err
+	}
+
+	p.routed[config.ModelName] = tieredModel{model: model, tier: tier}
+	p.routeOrder = append(p.routeOrder, config.ModelName)
+	return nil
+}
+
+// HasTier reports whether a model that supports the request type is registered in the tier
+func (p *Provider) HasTier(tier ModelTier, requestType RequestType) bool {
+	return p.findTier(tier, requestType) != nil
+}
+
+// Route chooses a model for a request. In order it tries: the caller's model preference on the context,
+// the first model in the requested tier, the default model, then any model that supports the request type.
+// An empty tier skips straight to the default model.
+func (p *Provider) Route(ctx context.Context, requestType RequestType, tier ModelTier) Model {
+	if name := ModelPreferenceFromContext(ctx); name != "" {
+		if model := p.findNamed(name); model != nil && SupportsRequestType(model, requestType) {
+			return p.wrap(model)
+		}
+		fmt.Printf("Warning: preferred model %q is unavailable for %s requests, routing normally\n", name, requestType)
+	}
+
+	if tier != "" {
+		if model := p.findTier(tier, requestType); model != nil {
+			return p.wrap(model)
+		}
+	}
+
+	if SupportsRequestType(p.defaultModel, requestType) {
+		return p.wrap(p.defaultModel)
+	}
+
+	for _, name := range p.routeOrder {
+		if model := p.routed[name].model; SupportsRequestType(model, requestType) {
+			return p.wrap(model)
+		}
+	}
+	for _, model := range p.models {
+		if SupportsRequestType(model, requestType) {
+			return p.wrap(model)
+		}
+	}
+
+	// Nothing advertises support; let the default model report the error
+	return p.wrap(p.defaultModel)
+}
+
+// findNamed looks a model up by registered name, then by model type
+func (p *Provider) findNamed(name string) Model {
+	if routed, ok := p.routed[name]; ok {
+		return routed.model
+	}
+	if model, ok := p.models[name]; ok {
+		return model
+	}
+	return nil
+}
+
+// findTier returns the first model in the tier that supports the request type
+func (p *Provider) findTier(tier ModelTier, requestType RequestType) Model {
+	for _, name := range p.routeOrder {
+		routed := p.routed[name]
+		if routed.tier == tier && SupportsRequestType(routed.model, requestType) {
+			return routed.model
+		}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"agent/internal/models"
+)
+
+// ShadowConfig configures a shadow model that runs alongside the primary on sampled traffic
+type ShadowConfig struct {
+	// Model is the candidate model under evaluation
+	Model Model
+
+	// SampleRate is the share of requests, from 0.0 to 1.0, that are also sent to the shadow model
+	SampleRate float64
+
+	// Timeout bounds each shadow call independently of the caller's request
+	Timeout time.Duration
+
+	// Log stores the paired outputs for review
+	Log *ShadowLog
+}
+
+// ShadowRecord pairs the primary and shadow outputs for one request
+type ShadowRecord struct {
+	ID               string            `json:"id"`
+	Timestamp        time.Time         `json:"timestamp"`
+	RequestType      string            `json:"request_type"`
+	PrimaryModel     string            `json:"primary_model"`
+	ShadowModel      string            `json:"shadow_model"`
+	PrimaryOutput    string            `json:"primary_output"`
+	ShadowOutput     string            `json:"shadow_output,omitempty"`
+	PrimaryCode      models.TriageCode `json:"primary_code,omitempty"`
+	ShadowCode       models.TriageCode `json:"shadow_code,omitempty"`
+	Disagreement     bool              `json:"disagreement"`
+	UnderTriage      bool              `json:"under_triage"`
+	PrimaryLatencyMs int64             `json:"primary_latency_ms"`
+	ShadowLatencyMs  int64             `json:"shadow_latency_ms"`
+	ShadowError      string            `json:"shadow_error,omitempty"`
+}
+
+// ShadowSummary aggregates the records currently held by a shadow log
+type ShadowSummary struct {
+	ShadowModel   string  `json:"shadow_model"`
+	Total         int     `json:"total"`
+	Compared      int     `json:"compared"`
+	Disagreements int     `json:"disagreements"`
+	UnderTriage   int     `json:"under_triage"`
+	OverTriage    int     `json:"over_triage"`
+	ShadowErrors  int     `json:"shadow_errors"`
+	AgreementRate float64 `json:"agreement_rate"`
+}
+
+// ShadowFilter selects records from a shadow log
+type ShadowFilter struct {
+	DisagreementsOnly bool
+	UnderTriageOnly   bool
+	Limit             int
+}
+
+// ShadowLog keeps the most recent shadow records in memory
+type ShadowLog struct {
+	mu       sync.RWMutex
+	records  []ShadowRecord
+	capacity int
+	next     int
+	total    int
+}
+
+// NewShadowLog creates a shadow log that retains up to capacity records
+func NewShadowLog(capacity int) *ShadowLog {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &ShadowLog{
+		records:  make([]ShadowRecord, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// Add stores a record, evicting the oldest once the log is full
+func (l *ShadowLog) Add(record ShadowRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total++
+	if record.ID == "" {
+		record.ID = fmt.Sprintf("shadow-%d", l.total)
+	}
+
+	if len(l.records) < l.capacity {
+		l.records = append(l.records, record)
+		return
+	}
+	l.records[l.next] = record
+	l.next = (l.next + 1) % l.capacity
+}
+
+// Records returns matching records, newest first
+func (l *ShadowLog) Records(filter ShadowFilter) []ShadowRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var matched []ShadowRecord
+	for i := len(l.records) - 1; i >= 0; i-- {
+		record := l.records[(l.next+i)%len(l.records)]
+		if filter.DisagreementsOnly && !record.Disagreement {
+			continue
+		}
+		if filter.UnderTriageOnly && !record.UnderTriage {
+			continue
+		}
+		matched = append(matched, record)
+		if filter.Limit > 0 && len(matched) >= filter.Limit {
+			break
+		}
+	}
+	return matched
+}
+
+// Summary reports agreement statistics over the retained records
+func (l *ShadowLog) Summary() ShadowSummary {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var summary ShadowSummary
+	for _, record := range l.records {
+		summary.Total++
+		summary.ShadowModel = record.ShadowModel
+		if record.ShadowError != "" {
+			summary.ShadowErrors++
+			continue
+		}
+		if record.PrimaryCode == "" || record.ShadowCode == "" {
+			continue
+		}
+		summary.Compared++
+		if record.Disagreement {
+			summary.Disagreements++
+			if record.UnderTriage {
+				summary.UnderTriage++
+			} else {
+				summary.OverTriage++
+			}
+		}
+	}
+
+	if summary.Compared > 0 {
+		summary.AgreementRate = float64(summary.Compared-summary.Disagreements) / float64(summary.Compared)
+	}
+	return summary
+}
+
+// ShadowModel serves every request from the primary model and, on sampled requests, replays it against
+// a shadow model in the background. The shadow result is only logged, never returned.
+type ShadowModel struct {
+	Model
+	config ShadowConfig
+}
+
+// NewShadowModel wraps a primary model with shadow evaluation
+func NewShadowModel(primary Model, config ShadowConfig) Model {
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+	return &ShadowModel{Model: primary, config: config}
+}
+
+// Unwrap returns the primary model
+func (m *ShadowModel) Unwrap() Model {
+	return m.Model
+}
+
+// ProcessText runs the primary and, if sampled, the shadow model
+func (m *ShadowModel) ProcessText(ctx context.Context, prompt string) (*ModelResponse, error) {
+	return m.run(ctx, "text", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessText(ctx, prompt)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessText(ctx, prompt)
+	})
+}
+
+// ProcessTextWithJson runs the primary and, if sampled, the shadow model
+func (m *ShadowModel) ProcessTextWithJson(ctx context.Context, prompt string, schema string) (*ModelResponse, error) {
+	return m.run(ctx, "json", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessTextWithJson(ctx, prompt, schema)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessTextWithJson(ctx, prompt, schema)
+	})
+}
+
+// ProcessConversation runs the primary and, if sampled, the shadow model
+func (m *ShadowModel) ProcessConversation(ctx context.Context, messages []Message) (*ModelResponse, error) {
+	return m.run(ctx, "conversation", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessConversation(ctx, messages)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessConversation(ctx, messages)
+	})
+}
+
+// ProcessWithFunctions runs the primary and, if sampled, the shadow model
+func (m *ShadowModel) ProcessWithFunctions(ctx context.Context, messages []Message, functions []FunctionDeclaration) (*ModelResponse, error) {
+	return m.run(ctx, "functions", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessWithFunctions(ctx, messages, functions)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessWithFunctions(ctx, messages, functions)
+	})
+}
+
+// ProcessAudio buffers sampled audio so both models can read it
+func (m *ShadowModel) ProcessAudio(ctx context.Context, input *AudioInput, prompt string) (*ModelResponse, error) {
+	if !m.sampled() {
+		return m.Model.ProcessAudio(ctx, input, prompt)
+	}
+
+	data, err := io.ReadAll(input.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer audio for shadow evaluation: %w", err)
+	}
+	withAudio := func(in AudioInput) *AudioInput {
+		in.Audio = bytes.NewReader(data)
+		return &in
+	}
+
+	return m.compare(ctx, "audio", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessAudio(ctx, withAudio(*input), prompt)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessAudio(ctx, withAudio(*input), prompt)
+	})
+}
+
+// ProcessImage buffers sampled images so both models can read them
+func (m *ShadowModel) ProcessImage(ctx context.Context, input *ImageInput, prompt string) (*ModelResponse, error) {
+	if !m.sampled() {
+		return m.Model.ProcessImage(ctx, input, prompt)
+	}
+
+	data, err := io.ReadAll(input.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer image for shadow evaluation: %w", err)
+	}
+	withImage := func(in ImageInput) *ImageInput {
+		in.Image = bytes.NewReader(data)
+		return &in
+	}
+
+	return m.compare(ctx, "image", func(ctx context.Context, shadow Model) (*ModelResponse, error) {
+		return shadow.ProcessImage(ctx, withImage(*input), prompt)
+	}, func(ctx context.Context) (*ModelResponse, error) {
+		return m.Model.ProcessImage(ctx, withImage(*input), prompt)
+	})
+}
+
+// shadowCall invokes the shadow model; primaryCall invokes the primary
+type (
+	shadowCall  func(ctx context.Context, shadow Model) (*ModelResponse, error)
+	primaryCall func(ctx context.Context) (*ModelResponse, error)
+)
+
+// run serves the request from the primary model and shadows it if the request is sampled
+func (m *ShadowModel) run(ctx context.Context, requestType string, shadow shadowCall, primary primaryCall) (*ModelResponse, error) {
+	if !m.sampled() {
+		return primary(ctx)
+	}
+	return m.compare(ctx, requestType, shadow, primary)
+}
+
+// compare starts the shadow call in the background, serves the primary, and logs both once the shadow finishes
+func (m *ShadowModel) compare(ctx context.Context, requestType string, shadow shadowCall, primary primaryCall) (*ModelResponse, error) {
+	type shadowResult struct {
+		response *ModelResponse
+		err      error
+		latency  time.Duration
+	}
+
+	// The shadow call must outlive the caller's request and never be cancelled by it
+	shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.config.Timeout)
+	results := make(chan shadowResult, 1)
+	go func() {
+		defer cancel()
+		start := time.Now()
+		response, err := shadow(shadowCtx, m.config.Model)
+		results <- shadowResult{response: response, err: err, latency: time.Since(start)}
+	}()
+
+	start := time.Now()
+	response, err := primary(ctx)
+	primaryLatency := time.Since(start)
+	if err != nil {
+		// Nothing to compare against; let the shadow finish on its own
+		return response, err
+	}
+
+	record := ShadowRecord{
+		Timestamp:        time.Now(),
+		RequestType:      requestType,
+		PrimaryModel:     m.Model.Name(),
+		ShadowModel:      m.config.Model.Name(),
+		PrimaryOutput:    shadowOutput(response),
+		PrimaryCode:      extractTriageCode(response),
+		PrimaryLatencyMs: primaryLatency.Milliseconds(),
+	}
+
+	go func() {
+		result := <-results
+		record.ShadowLatencyMs = result.latency.Milliseconds()
+		if result.err != nil {
+			record.ShadowError = result.err.Error()
+		} else {
+			record.ShadowOutput = shadowOutput(result.response)
+			record.ShadowCode = extractTriageCode(result.response)
+		}
+
+		if record.PrimaryCode != "" && record.ShadowCode != "" && record.PrimaryCode != record.ShadowCode {
+			record.Disagreement = true
+			record.UnderTriage = record.ShadowCode.Severity() < record.PrimaryCode.Severity()
+			if record.UnderTriage {
+				fmt.Printf("Warning: shadow model %s under-triaged %s as %s\n", record.ShadowModel, record.PrimaryCode, record.ShadowCode)
+			}
+		}
+
+		if m.config.Log != nil {
+			m.config.Log.Add(record)
+		}
+	}()
+
+	return response, nil
+}
+
+// sampled decides whether this request is also sent to the shadow model
+func (m *ShadowModel) sampled() bool {
+	return m.config.Model != nil && m.config.SampleRate > 0 && rand.Float64() < m.config.SampleRate
+}
+
+// shadowOutput renders a response, including any function calls, for the log
+func shadowOutput(response *ModelResponse) string {
+	if response == nil {
+		return ""
+	}
+	if len(response.FunctionCalls) == 0 {
+		return response.Content
+	}
+
+	calls, err := json.Marshal(response.FunctionCalls)
+	if err != nil {
+		return response.Content
+	}
+	if response.Content == "" {
+		return string(calls)
+	}
+	return response.Content + "\n" + string(calls)
+}
+
+// extractTriageCode reads the triage code from a structured JSON response, if it has one
+func extractTriageCode(response *ModelResponse) models.TriageCode {
+	if response == nil || response.Format != FormatJSON {
+		return ""
+	}
+
+	var structured struct {
+		TriageCode string `json:"triage_code"`
+	}
+	if err := json.Unmarshal([]byte(response.Content), &structured); err != nil {
+		return ""
+	}
+
+	switch code := models.TriageCode(structured.TriageCode); code {
+	case models.CodeRed, models.CodeYellow, models.CodeGreen:
+		return code
+	default:
+		return ""
+	}
+}
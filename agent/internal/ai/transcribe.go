@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"context"
+
+	"agent/internal/models"
+)
+
+// Transcriber is implemented by models that can return a timed transcript of a recording.
+// Segment times are relative to the start of the input audio and segment indexes are left to the caller.
+type Transcriber interface {
+	Transcribe(ctx context.Context, input *AudioInput) (*models.Transcript, error)
+}
+
+// unwrapper is implemented by models that wrap another model
+type unwrapper interface {
+	Unwrap() Model
+}
+
+// AsTranscriber returns the first model in a chain of wrappers that can transcribe. Wrappers that record
+// metrics implement Transcriber themselves and return ErrUnsupportedRequestType when nothing they wrap can.
+func AsTranscriber(model Model) (Transcriber, bool) {
+	for model != nil {
+		if transcriber, ok := model.(Transcriber); ok {
+			return transcriber, true
+		}
+		wrapper, ok := model.(unwrapper)
+		if !ok {
+			return nil, false
+		}
+		model = wrapper.Unwrap()
+	}
+	return nil, false
+}
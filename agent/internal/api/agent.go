@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"agent/internal/ai"
+	"agent/internal/models"
+	"agent/internal/safety"
+	"agent/internal/tools"
+)
+
+// AgentConfig enables model-driven tool selection in the coordinator
+type AgentConfig struct {
+	// Enabled switches the coordinator from fixed rules to letting the model choose tools
+	Enabled bool
+
+	// Model is the function-calling model that selects tools
+	Model ai.Model
+
+	// MaxSteps bounds the number of model round trips per emergency
+	MaxSteps int
+}
+
+// AgentStep is one audited decision in the tool-selection loop
+type AgentStep struct {
+	Step      int                    `json:"step"`
+	Source    string                 `json:"source"` // "model" or "guard_rail"
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Success   bool                   `json:"success"`
+	Message   string                 `json:"message,omitempty"`
+	Timestamp string                 `json:"timestamp"`
+}
+
+// Sources of agent steps
+const (
+	StepSourceModel     = "model"
+	StepSourceGuardRail = "guard_rail"
+)
+
+const agentSystemPrompt = `You are the dispatch coordinator of an emergency medical triage service.
+You receive a triaged emergency as JSON and must decide which of the available tools to call.
+` + safety.UntrustedDataInstruction + `
+Rules:
+- Call only the tools that help this patient. Call each tool at most once.
+- RED emergencies are life-threatening: dispatch an ambulance at HIGH priority and alert the hospital.
+- YELLOW emergencies need the hospital alerted. GREEN emergencies should get an appointment booked.
+- Fill tool arguments with concise, factual information taken from the emergency.
+When no further tools are needed, reply with one sentence explaining your decisions.`
+
+// processWithAgent lets the model choose tools through native function calling. The loop is bounded
+// by MaxSteps and every call is recorded in the returned audit trace.
+func (c *EmergencyCoordinator) processWithAgent(ctx context.Context, situation *models.EmergencySituation, toolResponses *[]*tools.ToolResponse) ([]AgentStep, error) {
+	// Only offer tools that are applicable, so the model cannot pick one the rules would forbid
+	available := make(map[string]tools.EmergencyTool)
+	var functions []ai.FunctionDeclaration
+	for _, tool := range c.toolRegistry.GetApplicable(situation) {
+		schema := tool.Schema()
+		available[schema.Name] = tool
+		functions = append(functions, ai.FunctionDeclaration{
+			Name:        schema.Name,
+			Description: schema.Description,
+			Parameters:  schema.Parameters,
+		})
+	}
+
+	if len(functions) == 0 {
+		return nil, nil
+	}
+
+	// Descriptions and symptoms come from the caller, so the whole emergency is delimited as untrusted data
+	situationJSON, err := json.Marshal(situation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal situation for agent: %w", err)
+	}
+
+	messages := []ai.Message{
+		{Role: ai.RoleSystem, Content: agentSystemPrompt},
+		{Role: ai.RoleUser, Content: "Emergency:\n" + safety.DataBlock("caller_input", string(situationJSON))},
+	}
+
+	var trace []AgentStep
+	executed := make(map[string]bool)
+
+	for step := 1; step <= c.agent.MaxSteps; step++ {
+		response, err := c.agent.Model.ProcessWithFunctions(ctx, messages, functions)
+		if err != nil {
+			return trace, fmt.Errorf("agent step %d failed: %w", step, err)
+		}
+
+		if len(response.FunctionCalls) == 0 {
+			if response.Content != "" {
+				situation.Metadata["agent_rationale"] = response.Content
+			}
+			return trace, nil
+		}
+
+		messages = append(messages, ai.Message{
+			Role:          ai.RoleAssistant,
+			Content:       response.Content,
+			FunctionCalls: response.FunctionCalls,
+		})
+
+		for _, call := range response.FunctionCalls {
+			auditStep := AgentStep{
+				Step:      step,
+				Source:    StepSourceModel,
+				Tool:      call.Name,
+				Arguments: call.Arguments,
+				Timestamp: time.Now().Format(time.RFC3339),
+			}
+
+			var result string
+			tool, ok := available[call.Name]
+			switch {
+			case !ok:
+				result = fmt.Sprintf("error: tool %q is not available for this emergency", call.Name)
+			case executed[call.Name]:
+				result = fmt.Sprintf("error: tool %q has already been called", call.Name)
+			default:
+				executed[call.Name] = true
+				toolResponse, err := executeTool(tools.WithArguments(ctx, call.Arguments), tool, situation)
+				if err != nil {
+					result = fmt.Sprintf("error: %v", err)
+				} else {
+					*toolResponses = append(*toolResponses, toolResponse)
+					reportToolResult(ctx, tool, toolResponse)
+					auditStep.Success = toolResponse.Success
+					resultJSON, _ := json.Marshal(toolResponse)
+					result = string(resultJSON)
+				}
+			}
+
+			if !auditStep.Success {
+				auditStep.Message = result
+			}
+			log.Printf("Agent step %d for %s: %s success=%t", step, situation.ID, call.Name, auditStep.Success)
+			trace = append(trace, auditStep)
+
+			messages = append(messages, ai.Message{
+				Role:           ai.RoleFunction,
+				Content:        result,
+				FunctionCallID: call.ID,
+				FunctionName:   call.Name,
+			})
+		}
+	}
+
+	log.Printf("Agent for %s stopped after the maximum of %d steps", situation.ID, c.agent.MaxSteps)
+	return trace, nil
+}
+
+// applyGuardRails enforces deterministic safety rules after tool selection, regardless of what the
+// model or the rules chose. A RED emergency always gets an ambulance dispatched at HIGH priority.
+func (c *EmergencyCoordinator) applyGuardRails(ctx context.Context, situation *models.EmergencySituation, toolResponses *[]*tools.ToolResponse) []AgentStep {
+	if situation.Code != models.CodeRed {
+		return nil
+	}
+
+	for _, response := range *toolResponses {
+		if response.Success && response.ToolName == c.ambulanceToolName() && response.Data["priority"] == "HIGH" {
+			return nil
+		}
+	}
+
+	for _, tool := range c.toolRegistry.GetAll() {
+		if !isAmbulanceTool(tool) {
+			continue
+		}
+
+		step := AgentStep{
+			Source:    StepSourceGuardRail,
+			Tool:      tool.Schema().Name,
+			Message:   "RED emergency without a successful HIGH priority ambulance dispatch",
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+
+		toolResponse, err := executeTool(ctx, tool, situation)
+		if err != nil {
+			step.Message += fmt.Sprintf("; dispatch failed: %v", err)
+			log.Printf("Guard rail failed to dispatch ambulance for %s: %v", situation.ID, err)
+		} else {
+			*toolResponses = append(*toolResponses, toolResponse)
+			reportToolResult(ctx, tool, toolResponse)
+			step.Success = toolResponse.Success
+		}
+
+		return []AgentStep{step}
+	}
+
+	log.Printf("Guard rail could not dispatch an ambulance for %s: no ambulance tool registered", situation.ID)
+	return nil
+}
+
+// ambulanceToolName returns the display name of the registered ambulance tool, if any
+func (c *EmergencyCoordinator) ambulanceToolName() string {
+	for _, tool := range c.toolRegistry.GetAll() {
+		if isAmbulanceTool(tool) {
+			return tool.Name()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent/internal/models"
+	"agent/internal/triage"
+)
+
+// AssessmentRequest is the body of a structured symptom assessment
+type AssessmentRequest struct {
+	models.SymptomAssessment
+	Location *models.Location `json:"location,omitempty"`
+}
+
+// HandleAssessment triages a structured symptom assessment. The deterministic score is a floor under the
+// model's reading of the symptoms, so the result is never less urgent than the rules allow.
+func (h *EmergencyHandler) HandleAssessment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be application/json")
+		return
+	}
+
+	var request AssessmentRequest
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxAudioSize))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+		return
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+		return
+	}
+	if err := validateAssessment(&request.SymptomAssessment); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	log.Printf("Received symptom assessment (%d symptoms)", len(request.Symptoms))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	response, err := h.processAssessment(ctx, request)
+	if err != nil {
+		writeServiceError(w, r, "Failed to process assessment", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// validateAssessment checks that an assessment describes some symptom and that its values are in range
+func validateAssessment(a *models.SymptomAssessment) error {
+	if len(a.Symptoms) == 0 && strings.TrimSpace(a.Description) == "" {
+		return fmt.Errorf("symptoms or description is required")
+	}
+	if a.PainScore != nil && (*a.PainScore < 0 || *a.PainScore > 10) {
+		return fmt.Errorf("pain_score must be between 0 and 10")
+	}
+	if a.Age < 0 || a.Age > 130 {
+		return fmt.Errorf("age must be between 0 and 130")
+	}
+	switch a.Duration {
+	case "", models.DurationUnderADay, models.DurationOneToThree, models.DurationThreeToWeek, models.DurationOverAWeek:
+	default:
+		return fmt.Errorf("duration must be one of %s, %s, %s or %s",
+			models.DurationUnderADay, models.DurationOneToThree, models.DurationThreeToWeek, models.DurationOverAWeek)
+	}
+	if a.Onset != nil && a.Onset.After(time.Now().Add(time.Hour)) {
+		return fmt.Errorf("onset must not be in the future")
+	}
+	switch strings.ToLower(a.Sex) {
+	case "", "female", "male", "other":
+	default:
+		return fmt.Errorf("sex must be female, male or other")
+	}
+	if a.Vitals != nil {
+		return validateVitals(a.Vitals)
+	}
+	return nil
+}
+
+// processAssessment combines the model's assessment of the symptoms with the deterministic score and
+// coordinates the response
+func (h *EmergencyHandler) processAssessment(ctx context.Context, request AssessmentRequest) (*EmergencyResponse, error) {
+	assessment := &request.SymptomAssessment
+
+	situation, err := h.textProcessor.ProcessEmergencyText(ctx, assessmentReport(assessment))
+	if err != nil {
+		return nil, &stageError{operation: "Failed to process text", err: err}
+	}
+
+	situation.PatientInfo = &models.PatientInfo{
+		Age:         assessment.Age,
+		Gender:      strings.ToLower(assessment.Sex),
+		Pregnant:    assessment.Pregnant,
+		Conditions:  assessment.Conditions,
+		Medications: assessment.Medications,
+	}
+	situation.Vitals = assessment.Vitals
+	if request.Location != nil {
+		situation.Location = request.Location
+	}
+
+	score := triage.ScoreAssessment(assessment, time.Now())
+	situation.Metadata["assessment_code"] = string(score.Code)
+	situation.Metadata["assessment_points"] = strconv.Itoa(score.Points)
+	if len(score.Findings) > 0 {
+		situation.Metadata["assessment_findings"] = strings.Join(score.Findings, "; ")
+	}
+	if score.Code.Severity() > situation.Code.Severity() {
+		situation.SetTriageCode(score.Code, score.Confidence)
+		situation.Metadata["triage_escalated_by"] = "assessment"
+	}
+
+	return h.coordinator.ProcessEmergency(ctx, situation)
+}
+
+// assessmentReport writes a structured assessment out as text for the model
+func assessmentReport(a *models.SymptomAssessment) string {
+	var report strings.Builder
+
+	if len(a.Symptoms) > 0 {
+		fmt.Fprintf(&report, "Symptoms: %s\n", strings.Join(a.Symptoms, ", "))
+	}
+	if a.Description != "" {
+		fmt.Fprintf(&report, "In the patient's words: %s\n", a.Description)
+	}
+	switch {
+	case a.Onset != nil:
+		fmt.Fprintf(&report, "Started: %s (%s ago)\n", a.Onset.Format(time.RFC3339), time.Since(*a.Onset).Round(time.Minute))
+	case a.Duration != "":
+		fmt.Fprintf(&report, "Duration: %s\n", strings.ReplaceAll(a.Duration, "-", " "))
+	}
+	if a.PainScore != nil {
+		fmt.Fprintf(&report, "Pain: %d/10\n", *a.PainScore)
+	}
+	if a.Age > 0 {
+		fmt.Fprintf(&report, "Age: %d\n", a.Age)
+	}
+	if a.Sex != "" {
+		fmt.Fprintf(&report, "Sex: %s\n", a.Sex)
+	}
+	if a.Pregnant != nil && *a.Pregnant {
+		report.WriteString("Pregnant: yes\n")
+	}
+	if len(a.Conditions) > 0 {
+		fmt.Fprintf(&report, "Conditions: %s\n", strings.Join(a.Conditions, ", "))
+	}
+	if len(a.Medications) > 0 {
+		fmt.Fprintf(&report, "Medications: %s\n", strings.Join(a.Medications, ", "))
+	}
+	if a.Vitals != nil {
+		fmt.Fprintf(&report, "Vitals: %s\n", a.Vitals)
+	}
+	return report.String()
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"agent/internal/auth"
+	"agent/internal/models"
+)
+
+// APIKeyHeader carries an API key for clients that do not send it as a bearer token
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// reporters may submit emergencies and follow their jobs
+	reporters = []auth.Scope{auth.ScopeCaller, auth.ScopeDispatcher, auth.ScopeClinician}
+
+	// responders may review and update stored emergencies; reporters only the ones they submitted
+	responders = []auth.Scope{auth.ScopeDispatcher, auth.ScopeClinician}
+
+	// profileUsers may use patient profiles; callers only their own
+	profileUsers = []auth.Scope{auth.ScopeCaller, auth.ScopeClinician}
+
+	// operators may use operational endpoints
+	operators = []auth.Scope{auth.ScopeAdmin}
+)
+
+// routeScopes lists the scopes allowed on each route; the first match applies. A path ending in "/" is a
+// prefix. Admins may use every route, and routes not listed need any authenticated client.
+var routeScopes = []struct {
+	path   string
+	public bool
+	scopes []auth.Scope
+}{
+	{path: "/api/v1/health", public: true},
+	{path: "/api/v1/health/", public: true},
+	{path: openAPIPath, public: true},
+	{path: "/api/v1/emergency", scopes: reporters},
+	{path: "/api/v1/emergency/text", scopes: reporters},
+	{path: "/api/v1/emergency/stream", scopes: reporters},
+	{path: "/api/v1/emergency/live", scopes: reporters},
+	{path: "/api/v1/emergency/chat", scopes: reporters},
+	{path: "/api/v1/assessment", scopes: reporters},
+	{path: jobsPath, scopes: reporters},
+	{path: incidentPath, scopes: reporters},
+	{path: "/api/v1/emergencies", scopes: responders},
+	{path: profilesPath, scopes: profileUsers},
+	{path: profilesPath + "/", scopes: profileUsers},
+	{path: "/api/v1/shadow/", scopes: operators},
+	{path: "/api/v1/metrics", scopes: operators},
+	{path: prometheusPath, scopes: operators},
+}
+
+// AuthMiddleware requires a valid API key or JWT on every non-public route and checks the client's scopes.
+// The client is attached to the request context for handlers and recorded on the emergencies it reports.
+func AuthMiddleware(authenticator *auth.Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		public, scopes := routeAccess(r.URL.Path)
+		if public {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, err := authenticator.Authenticate(credential(r))
+		if err != nil {
+			if !errors.Is(err, auth.ErrNoCredentials) {
+				log.Printf("Rejected credentials from %s: %v", r.RemoteAddr, err)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rapidtriage"`)
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "A valid API key or token is required")
+			return
+		}
+
+		if len(scopes) > 0 && !client.HasAny(scopes...) {
+			log.Printf("Client %s lacks a scope for %s %s", client.Principal(), r.Method, r.URL.Path)
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "This client is not allowed to use this endpoint")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithClient(r.Context(), client)))
+	})
+}
+
+// routeAccess returns whether a path is public and, if not, the scopes that may use it
+func routeAccess(path string) (bool, []auth.Scope) {
+	for _, route := range routeScopes {
+		if path == route.path || strings.HasSuffix(route.path, "/") && strings.HasPrefix(path, route.path) {
+			return route.public, route.scopes
+		}
+	}
+	return false, nil
+}
+
+// credential returns the API key or token a request presents. Browser WebSockets cannot set headers, so
+// an upgrade request may pass it as the access_token query parameter instead.
+func credential(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// recordClient notes which client reported an emergency
+func recordClient(ctx context.Context, situation *models.EmergencySituation) {
+	if client := auth.ClientFromContext(ctx); client != nil && situation.SubmittedBy == "" {
+		situation.SubmittedBy = client.Principal()
+	}
+}
+
+// clientPrincipal returns the principal of the request's client, or "" when authentication is disabled
+func clientPrincipal(ctx context.Context) string {
+	if client := auth.ClientFromContext(ctx); client != nil {
+		return client.Principal()
+	}
+	return ""
+}
+
+// isOwner reports whether the request's client created a resource. Admins own everything, and without
+// authentication every resource is accessible.
+func isOwner(ctx context.Context, owner string) bool {
+	client := auth.ClientFromContext(ctx)
+	if client == nil || client.HasAny(auth.ScopeAdmin) {
+		return true
+	}
+	return owner != "" && owner == client.Principal()
+}
+
+// canAccessIncident reports whether the request's client may read or update a stored emergency: responders
+// may use any, reporters only those they submitted
+func canAccessIncident(ctx context.Context, incident *Incident) bool {
+	client := auth.ClientFromContext(ctx)
+	if client == nil || client.HasAny(responders...) {
+		return true
+	}
+	return incident.Situation != nil && isOwner(ctx, incident.Situation.SubmittedBy)
+}
+
+// canAccessProfile reports whether the request's client may use a patient profile: clinicians may use
+// any, callers only those they created. Without authentication every profile is accessible.
+func canAccessProfile(ctx context.Context, profile *models.PatientProfile) bool {
+	client := auth.ClientFromContext(ctx)
+	if client == nil || client.HasAny(auth.ScopeClinician) {
+		return true
+	}
+	return profile.Owner != "" && profile.Owner == client.Principal()
+}
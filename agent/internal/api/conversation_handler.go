@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"agent/internal/models"
+)
+
+// ConversationHandler handles multi-turn chat requests with the triage agent
+type ConversationHandler struct {
+	textProcessor *TextProcessor
+	coordinator   *EmergencyCoordinator
+	sessions      *SessionStore
+}
+
+// NewConversationHandler creates a new conversation API handler
+func NewConversationHandler(textProcessor *TextProcessor, coordinator *EmergencyCoordinator, sessions *SessionStore) *ConversationHandler {
+	return &ConversationHandler{
+		textProcessor: textProcessor,
+		coordinator:   coordinator,
+		sessions:      sessions,
+	}
+}
+
+// ChatRequest is one caller message in a conversation
+type ChatRequest struct {
+	SessionID string           `json:"session_id,omitempty"` // Omitted to start a new conversation
+	Text      string           `json:"text"`
+	Location  *models.Location `json:"location,omitempty"`
+}
+
+// ConversationResponse is returned for every chat turn
+type ConversationResponse struct {
+	SessionID string             `json:"session_id"`
+	Reply     string             `json:"reply"`
+	Turn      int                `json:"turn"`
+	Emergency *EmergencyResponse `json:"emergency,omitempty"`
+}
+
+// RegisterRoutes registers the conversation API routes
+func (h *ConversationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/emergency/chat", h.HandleChat)
+}
+
+// HandleChat processes one caller message in a conversation. Omitting session_id starts a new session;
+// follow-up messages refine the same emergency situation.
+func (h *ConversationHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	// Check content type
+	if r.Header.Get("Content-Type") != "application/json" {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be application/json")
+		return
+	}
+
+	var requestBody ChatRequest
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024)) // 1MB limit
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+		return
+	}
+
+	if requestBody.Text == "" {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Text field is required")
+		return
+	}
+
+	// Resume the existing session or start a new one
+	var session *ConversationSession
+	if requestBody.SessionID != "" {
+		var ok bool
+		session, ok = h.sessions.Get(r.Context(), requestBody.SessionID)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, CodeNotFound, "Conversation session not found or expired")
+			return
+		}
+	} else {
+		session = h.sessions.Create(r.Context())
+	}
+	defer h.sessions.Release(session)
+
+	session.Lock()
+	defer session.Unlock()
+
+	log.Printf("Received chat message for session %s (length: %d characters)", session.ID, len(requestBody.Text))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	reply, err := h.textProcessor.ContinueConversation(ctx, session, requestBody.Text)
+	if err != nil {
+		writeServiceError(w, r, "Failed to process message", err)
+		return
+	}
+
+	situation := session.Situation
+	if requestBody.Location != nil {
+		situation.Location = requestBody.Location
+	}
+
+	// Without the model nothing has re-triaged the conversation yet, and a new code decides whether to coordinate
+	if _, ruleBased := ruleBasedTriage(ctx); ruleBased {
+		h.coordinator.applyRuleBasedTriage(ctx, situation)
+	}
+
+	// Only coordinate again when the triage code has changed, so repeated messages don't re-dispatch. A change
+	// is coordinated as an update: tools that already succeeded, such as the hospital alert, are not repeated.
+	switch {
+	case session.LastResponse == nil:
+		response, err := h.coordinator.ProcessEmergency(ctx, situation)
+		if err != nil {
+			writeServiceError(w, r, "Failed to process emergency", err)
+			return
+		}
+		session.LastResponse = response
+		session.DispatchedCode = situation.Code
+	case situation.Code != session.DispatchedCode:
+		reason := fmt.Sprintf("chat turn %d", len(session.Messages)/2)
+		response, _, err := h.coordinator.ProcessUpdate(ctx, situation, session.LastResponse, reason)
+		if err != nil {
+			writeServiceError(w, r, "Failed to process emergency", err)
+			return
+		}
+		h.coordinator.saveIncident(situation, response)
+		session.LastResponse = response
+		session.DispatchedCode = situation.Code
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := ConversationResponse{
+		SessionID: session.ID,
+		Reply:     reply,
+		Turn:      len(session.Messages) / 2,
+		Emergency: session.LastResponse,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
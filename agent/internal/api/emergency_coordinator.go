@@ -3,11 +3,14 @@ package api
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"agent/internal/models"
+	"agent/internal/safety"
 	"agent/internal/tools"
 	"agent/internal/tools/location"
+	"agent/internal/triage"
 )
 
 // EmergencyCoordinator manages the emergency response process
@@ -17,6 +20,8 @@ type EmergencyCoordinator struct {
 	locationTool       *location.LocationTool
 	summaryGenerator   SummaryGenerator
 	notificationConfig NotificationConfig
+	agent              AgentConfig
+	store              IncidentStore
 }
 
 // Classifier defines the interface for emergency classification
@@ -43,6 +48,10 @@ type CoordinatorConfig struct {
 	MaxConcurrentTools int
 	Notifications      NotificationConfig
 	DefaultTimeout     time.Duration
+	Agent              AgentConfig
+
+	// Store, if set, persists every coordinated emergency
+	Store IncidentStore
 }
 
 // NewEmergencyCoordinator creates a new emergency coordinator
@@ -61,21 +70,32 @@ func NewEmergencyCoordinator(
 		config.DefaultTimeout = 30 * time.Second
 	}
 
+	if config.Agent.MaxSteps == 0 {
+		config.Agent.MaxSteps = 4
+	}
+
 	return &EmergencyCoordinator{
 		classifier:         classifier,
 		toolRegistry:       toolRegistry,
 		locationTool:       locationTool,
 		summaryGenerator:   summaryGenerator,
 		notificationConfig: config.Notifications,
+		agent:              config.Agent,
+		store:              config.Store,
 	}
 }
 
 // ProcessEmergency processes an emergency situation
 func (c *EmergencyCoordinator) ProcessEmergency(ctx context.Context, situation *models.EmergencySituation) (*EmergencyResponse, error) {
+	defer observeStage(StageCoordination, time.Now())
+
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	attachPatient(ctx, situation)
+	recordClient(ctx, situation)
+
 	// Classify the emergency if not already classified
 	if situation.Code == models.CodeUnknown {
 		code, confidence, err := c.classifier.Classify(ctx, situation)
@@ -85,40 +105,59 @@ func (c *EmergencyCoordinator) ProcessEmergency(ctx context.Context, situation *
 		situation.SetTriageCode(code, confidence)
 	}
 
+	// A flagged input may have steered the model, so the rule-based reading of the caller's own words is a floor.
+	// Without the model it is the only reading.
+	_, ruleBased := ruleBasedTriage(ctx)
+	if ruleBased {
+		c.applyRuleBasedTriage(ctx, situation)
+	} else if safety.IsFlagged(situation) {
+		c.applyRuleBasedFloor(ctx, situation)
+	}
+	applyVitalsFloor(situation)
+
+	reason := "initial triage"
+	if len(situation.CodeHistory) > 0 {
+		reason = "reassessment"
+	}
+	situation.RecordCode(reason)
+	reportProgress(ctx, EventTriageDecided, newTriageProgress(situation))
+	countTriage(ctx, situation.Code)
+
 	// Initialize response variables
+	toolsStart := time.Now()
 	var toolResponses []*tools.ToolResponse
+	var agentTrace []AgentStep
 
-	// Process emergency based on triage code
-	switch situation.Code {
-	case models.CodeRed:
-		// For critical cases, call both hospital and ambulance tools
-		responseErr := c.processRedEmergency(ctx, situation, &toolResponses)
-		if responseErr != nil {
-			fmt.Printf("Warning: error in processing RED emergency: %v\n", responseErr)
-		}
-	case models.CodeYellow:
-		// For urgent cases, call hospital tool only
-		responseErr := c.processYellowEmergency(ctx, situation, &toolResponses)
-		if responseErr != nil {
-			fmt.Printf("Warning: error in processing YELLOW emergency: %v\n", responseErr)
-		}
-	case models.CodeGreen:
-		// For non-urgent cases, call booking tool
-		responseErr := c.processGreenEmergency(ctx, situation, &toolResponses)
-		if responseErr != nil {
-			fmt.Printf("Warning: error in processing GREEN emergency: %v\n", responseErr)
+	// In agent mode the model selects tools; fall back to the deterministic rules if it fails
+	agentHandled := false
+	if c.agent.Enabled && c.agent.Model != nil && !ruleBased {
+		trace, err := c.processWithAgent(ctx, situation, &toolResponses)
+		agentTrace = trace
+		if err != nil {
+			fmt.Printf("Warning: agent tool selection failed: %v\n", err)
 		}
-	default:
-		fmt.Printf("Warning: unknown emergency code: %s\n", situation.Code)
+		// Once the agent has executed anything, don't replay tools through the deterministic path
+		agentHandled = err == nil || len(toolResponses) > 0
+	}
+
+	if !agentHandled {
+		c.processDeterministic(ctx, situation, &toolResponses)
 	}
 
+	// Guard rails apply whichever way the tools were chosen
+	agentTrace = append(agentTrace, c.applyGuardRails(ctx, situation, &toolResponses)...)
+	observeStage(StageTools, toolsStart)
+
 	// Generate a summary for responders
+	summaryStart := time.Now()
 	summary, err := c.summaryGenerator.GenerateSummary(ctx, situation, toolResponses)
+	observeStage(StageSummary, summaryStart)
 	if err != nil {
 		// Use a simplified summary if generator fails
 		summary = fmt.Sprintf("Emergency: %s (Code %s). Confidence: %.2f",
 			situation.Description, situation.Code, situation.Confidence)
 	}
+	reportProgress(ctx, EventSummaryReady, map[string]string{"summary": summary})
 
 	// Create emergency response
 	response := &EmergencyResponse{
@@ -127,11 +166,215 @@ func (c *EmergencyCoordinator) ProcessEmergency(ctx context.Context, situation *
 		Summary:       summary,
 		Timestamp:     time.Now().Format(time.RFC3339),
 		ToolResponses: toolResponses,
+		AgentTrace:    agentTrace,
+		Transcript:    situation.Transcript,
 	}
 
+	c.saveIncident(situation, response)
+
 	return response, nil
 }
 
+// saveIncident persists a coordinated emergency. A storage failure is logged rather than failing the response.
+func (c *EmergencyCoordinator) saveIncident(situation *models.EmergencySituation, response *EmergencyResponse) {
+	if c.store == nil {
+		return
+	}
+
+	// Re-coordinating a known situation, as a conversation does each turn, keeps its update history
+	incident, err := c.store.Get(situation.ID)
+	if err != nil {
+		incident = &Incident{ID: situation.ID, Version: 1, CreatedAt: situation.Timestamp}
+	}
+	incident.Code = situation.Code
+	incident.UpdatedAt = time.Now()
+	incident.Situation = situation
+	incident.Response = response
+	if situation.SourceText != "" {
+		incident.SourceText = situation.SourceText
+	}
+
+	if err := c.store.Save(incident); err != nil {
+		fmt.Printf("Warning: failed to store incident %s: %v\n", situation.ID, err)
+	}
+}
+
+// ProcessUpdate re-coordinates an incident after new information has been triaged into situation. Only the
+// tools the new code calls for that have not already succeeded are run; earlier tool responses are kept.
+func (c *EmergencyCoordinator) ProcessUpdate(ctx context.Context, situation *models.EmergencySituation, previous *EmergencyResponse, reason string) (*EmergencyResponse, []*tools.ToolResponse, error) {
+	defer observeStage(StageCoordination, time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	attachPatient(ctx, situation)
+
+	if situation.Code == models.CodeUnknown {
+		code, confidence, err := c.classifier.Classify(ctx, situation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to classify emergency: %w", err)
+		}
+		situation.SetTriageCode(code, confidence)
+	}
+
+	if _, ruleBased := ruleBasedTriage(ctx); ruleBased {
+		c.applyRuleBasedTriage(ctx, situation)
+	} else if safety.IsFlagged(situation) {
+		c.applyRuleBasedFloor(ctx, situation)
+	}
+	applyVitalsFloor(situation)
+
+	situation.RecordCode(reason)
+	reportProgress(ctx, EventTriageDecided, newTriageProgress(situation))
+	countTriage(ctx, situation.Code)
+
+	// Tools that already succeeded for this incident are not repeated
+	var toolResponses []*tools.ToolResponse
+	succeeded := make(map[string]bool)
+	if previous != nil {
+		toolResponses = append(toolResponses, previous.ToolResponses...)
+		for _, response := range previous.ToolResponses {
+			if response.Success {
+				succeeded[response.ToolName] = true
+			}
+		}
+	}
+
+	toolsStart := time.Now()
+	var added []*tools.ToolResponse
+	required := requiredTools(situation.Code)
+	applicable := c.toolRegistry.GetApplicable(situation)
+	for _, tool := range applicable {
+		if succeeded[tool.Name()] {
+			required[tool.Schema().Name] = false
+		}
+	}
+	for _, tool := range applicable {
+		name := tool.Schema().Name
+		if !required[name] {
+			continue
+		}
+		// One tool per function is enough, as for a first response
+		required[name] = false
+
+		toolResponse, err := executeTool(ctx, tool, situation)
+		if err != nil {
+			fmt.Printf("Warning: tool %s failed: %v\n", tool.Name(), err)
+			continue
+		}
+		added = append(added, toolResponse)
+		reportToolResult(ctx, tool, toolResponse)
+	}
+	toolResponses = append(toolResponses, added...)
+	observeStage(StageTools, toolsStart)
+
+	summaryStart := time.Now()
+	summary, err := c.summaryGenerator.GenerateSummary(ctx, situation, toolResponses)
+	observeStage(StageSummary, summaryStart)
+	if err != nil {
+		summary = fmt.Sprintf("Emergency: %s (Code %s). Confidence: %.2f",
+			situation.Description, situation.Code, situation.Confidence)
+	}
+	reportProgress(ctx, EventSummaryReady, map[string]string{"summary": summary})
+
+	response := &EmergencyResponse{
+		EmergencyID:   situation.ID,
+		Code:          situation.Code,
+		Summary:       summary,
+		Timestamp:     time.Now().Format(time.RFC3339),
+		ToolResponses: toolResponses,
+		Transcript:    situation.Transcript,
+	}
+	if previous != nil {
+		response.NearestHospitals = previous.NearestHospitals
+		response.NearestAmbulances = previous.NearestAmbulances
+	}
+	return response, added, nil
+}
+
+// requiredTools returns the tool functions the deterministic path runs for a triage code
+func requiredTools(code models.TriageCode) map[string]bool {
+	switch code {
+	case models.CodeRed:
+		return map[string]bool{tools.FunctionNotifyHospital: true, tools.FunctionDispatchAmbulance: true}
+	case models.CodeYellow:
+		return map[string]bool{tools.FunctionNotifyHospital: true}
+	case models.CodeGreen:
+		return map[string]bool{tools.FunctionBookAppointment: true}
+	}
+	return map[string]bool{}
+}
+
+// applyVitalsFloor raises the triage code to what the patient's vitals require
+func applyVitalsFloor(situation *models.EmergencySituation) {
+	code, findings := triage.VitalsFloor(situation.Vitals)
+	if code.Severity() > situation.Code.Severity() {
+		situation.Metadata["vitals_floor_applied"] = fmt.Sprintf("%s->%s: %s", situation.Code, code, strings.Join(findings, ", "))
+		situation.SetTriageCode(code, situation.Confidence)
+	}
+}
+
+// applyRuleBasedFloor raises the triage code to the rule-based classifier's result if the model's code is lower
+func (c *EmergencyCoordinator) applyRuleBasedFloor(ctx context.Context, situation *models.EmergencySituation) {
+	probe := *situation
+	if situation.SourceText != "" {
+		probe.Description = situation.SourceText
+	}
+
+	code, confidence, err := c.classifier.Classify(ctx, &probe)
+	if err != nil {
+		fmt.Printf("Warning: rule-based floor classification failed: %v\n", err)
+		return
+	}
+
+	if code.Severity() > situation.Code.Severity() {
+		situation.Metadata["triage_floor_applied"] = fmt.Sprintf("%s->%s", situation.Code, code)
+		situation.SetTriageCode(code, confidence)
+	}
+}
+
+// applyRuleBasedTriage triages a situation the model has not seen. The classifier wants several keywords
+// before it is confident, but without the model a single sign of a life-threatening emergency dispatches.
+func (c *EmergencyCoordinator) applyRuleBasedTriage(ctx context.Context, situation *models.EmergencySituation) {
+	c.applyRuleBasedFloor(ctx, situation)
+
+	text := situation.SourceText
+	if text == "" {
+		text = situation.Description
+	}
+	if situation.Code != models.CodeRed && triage.SuspectsCritical(text) {
+		situation.Metadata["triage_floor_applied"] = fmt.Sprintf("%s->%s: critical keyword", situation.Code, models.CodeRed)
+		situation.SetTriageCode(models.CodeRed, 0.5)
+	}
+}
+
+// processDeterministic selects tools with fixed rules based on the triage code
+func (c *EmergencyCoordinator) processDeterministic(ctx context.Context, situation *models.EmergencySituation, toolResponses *[]*tools.ToolResponse) {
+	// Process emergency based on triage code
+	switch situation.Code {
+	case models.CodeRed:
+		// For critical cases, call both hospital and ambulance tools
+		responseErr := c.processRedEmergency(ctx, situation, toolResponses)
+		if responseErr != nil {
+			fmt.Printf("Warning: error in processing RED emergency: %v\n", responseErr)
+		}
+	case models.CodeYellow:
+		// For urgent cases, call hospital tool only
+		responseErr := c.processYellowEmergency(ctx, situation, toolResponses)
+		if responseErr != nil {
+			fmt.Printf("Warning: error in processing YELLOW emergency: %v\n", responseErr)
+		}
+	case models.CodeGreen:
+		// For non-urgent cases, call booking tool
+		responseErr := c.processGreenEmergency(ctx, situation, toolResponses)
+		if responseErr != nil {
+			fmt.Printf("Warning: error in processing GREEN emergency: %v\n", responseErr)
+		}
+	default:
+		fmt.Printf("Warning: unknown emergency code: %s\n", situation.Code)
+	}
+}
+
 // processRedEmergency handles critical emergencies (Code Red)
 func (c *EmergencyCoordinator) processRedEmergency(ctx context.Context, situation *models.EmergencySituation, toolResponses *[]*tools.ToolResponse) error {
 	// Get all tools that are applicable for this situation
@@ -140,14 +383,15 @@ func (c *EmergencyCoordinator) processRedEmergency(ctx context.Context, situatio
 	// Find and execute hospital and ambulance tools
 	for _, tool := range applicableTools {
 		toolName := tool.Name()
-		if isHospitalOrAmbulanceTool(toolName) {
-			toolResponse, err := tool.Execute(ctx, situation)
+		if isHospitalOrAmbulanceTool(tool) {
+			toolResponse, err := executeTool(ctx, tool, situation)
 			if err != nil {
 				// Log error but continue with other tools
 				fmt.Printf("Warning: tool %s failed: %v\n", toolName, err)
 				continue
 			}
 			*toolResponses = append(*toolResponses, toolResponse)
+			reportToolResult(ctx, tool, toolResponse)
 		}
 	}
 
@@ -161,14 +405,14 @@ func (c *EmergencyCoordinator) processYellowEmergency(ctx context.Context, situa
 
 	// Execute only hospital tool
 	for _, tool := range applicableTools {
-		toolName := tool.Name()
-		if isHospitalTool(toolName) {
-			toolResponse, err := tool.Execute(ctx, situation)
+		if isHospitalTool(tool) {
+			toolResponse, err := executeTool(ctx, tool, situation)
 			if err != nil {
 				fmt.Printf("Warning: hospital tool failed: %v\n", err)
 				return err
 			}
 			*toolResponses = append(*toolResponses, toolResponse)
+			reportToolResult(ctx, tool, toolResponse)
 			break // Only need one hospital tool
 		}
 	}
@@ -183,14 +427,14 @@ func (c *EmergencyCoordinator) processGreenEmergency(ctx context.Context, situat
 
 	// Execute only booking tool
 	for _, tool := range applicableTools {
-		toolName := tool.Name()
-		if isBookingTool(toolName) {
-			toolResponse, err := tool.Execute(ctx, situation)
+		if isBookingTool(tool) {
+			toolResponse, err := executeTool(ctx, tool, situation)
 			if err != nil {
 				fmt.Printf("Warning: booking tool failed: %v\n", err)
 				return err
 			}
 			*toolResponses = append(*toolResponses, toolResponse)
+			reportToolResult(ctx, tool, toolResponse)
 			break // Only need one booking tool
 		}
 	}
@@ -199,20 +443,20 @@ func (c *EmergencyCoordinator) processGreenEmergency(ctx context.Context, situat
 }
 
 // Helper functions to identify tool types
-func isHospitalTool(toolName string) bool {
-	return toolName == "Hospital Communication Tool"
+func isHospitalTool(tool tools.EmergencyTool) bool {
+	return tool.Schema().Name == tools.FunctionNotifyHospital
 }
 
-func isAmbulanceTool(toolName string) bool {
-	return toolName == "Ambulance Dispatch Tool"
+func isAmbulanceTool(tool tools.EmergencyTool) bool {
+	return tool.Schema().Name == tools.FunctionDispatchAmbulance
 }
 
-func isBookingTool(toolName string) bool {
-	return toolName == "Hospital Booking Tool"
+func isBookingTool(tool tools.EmergencyTool) bool {
+	return tool.Schema().Name == tools.FunctionBookAppointment
 }
 
-func isHospitalOrAmbulanceTool(toolName string) bool {
-	return isHospitalTool(toolName) || isAmbulanceTool(toolName)
+func isHospitalOrAmbulanceTool(tool tools.EmergencyTool) bool {
+	return isHospitalTool(tool) || isAmbulanceTool(tool)
 }
 
 // EmergencyResponse represents the coordinated emergency response
@@ -224,6 +468,8 @@ type EmergencyResponse struct {
 	NearestHospitals  []location.Facility   `json:"nearest_hospitals,omitempty"`
 	NearestAmbulances []location.Facility   `json:"nearest_ambulances,omitempty"`
 	ToolResponses     []*tools.ToolResponse `json:"tool_responses,omitempty"`
+	AgentTrace        []AgentStep           `json:"agent_trace,omitempty"`
+	Transcript        *models.Transcript    `json:"transcript,omitempty"`
 }
 
 // DefaultSummaryGenerator implements a basic summary generator
@@ -252,6 +498,15 @@ func (g *DefaultSummaryGenerator) GenerateSummary(ctx context.Context, situation
 		if len(situation.PatientInfo.Allergies) > 0 {
 			summary += fmt.Sprintf("Allergies: %v\n", situation.PatientInfo.Allergies)
 		}
+		if situation.PatientInfo.Pregnant != nil && *situation.PatientInfo.Pregnant {
+			summary += "Pregnant\n"
+		}
+		if len(situation.PatientInfo.Conditions) > 0 {
+			summary += fmt.Sprintf("Conditions: %v\n", situation.PatientInfo.Conditions)
+		}
+		if len(situation.PatientInfo.Medications) > 0 {
+			summary += fmt.Sprintf("Medications: %v\n", situation.PatientInfo.Medications)
+		}
 	}
 
 	if situation.Location != nil {
@@ -262,6 +517,17 @@ func (g *DefaultSummaryGenerator) GenerateSummary(ctx context.Context, situation
 		}
 	}
 
+	if situation.Vitals != nil {
+		summary += fmt.Sprintf("\nVITALS: %s\n", situation.Vitals)
+	}
+
+	if len(situation.CodeHistory) > 1 {
+		summary += "\nCODE HISTORY:\n"
+		for _, change := range situation.CodeHistory {
+			summary += fmt.Sprintf("v%d %s %s (%s)\n", change.Version, change.ChangedAt.Format(time.RFC3339), change.Code, change.Reason)
+		}
+	}
+
 	// Add timestamps
 	summary += fmt.Sprintf("\nEmergency reported at: %s\n", situation.Timestamp.Format(time.RFC3339))
 	summary += fmt.Sprintf("Alert generated at: %s\n", time.Now().Format(time.RFC3339))
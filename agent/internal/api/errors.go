@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"agent/internal/ai"
+	"agent/internal/audio"
+	"agent/internal/openapi"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error
+type ErrorCode string
+
+const (
+	// CodeInvalidRequest means the request was malformed or missing a required field
+	CodeInvalidRequest ErrorCode = "INVALID_REQUEST"
+
+	// CodeMethodNotAllowed means the endpoint does not accept the HTTP method
+	CodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+
+	// CodeInvalidAudio means the recording is unsupported, corrupt, empty or too long
+	CodeInvalidAudio ErrorCode = "INVALID_AUDIO"
+
+	// CodeFeatureDisabled means the request needs a capability this server has not enabled
+	CodeFeatureDisabled ErrorCode = "FEATURE_DISABLED"
+
+	// CodeUnauthorized means the request carried no valid API key or token
+	CodeUnauthorized ErrorCode = "UNAUTHORIZED"
+
+	// CodeForbidden means the client is authenticated but its scopes do not allow the request
+	CodeForbidden ErrorCode = "FORBIDDEN"
+
+	// CodeNotFound means the referenced resource does not exist or has expired
+	CodeNotFound ErrorCode = "NOT_FOUND"
+
+	// CodeIdempotencyConflict means an Idempotency-Key was reused for a different request
+	CodeIdempotencyConflict ErrorCode = "IDEMPOTENCY_CONFLICT"
+
+	// CodeModelUnavailable means the AI provider failed or is temporarily unavailable
+	CodeModelUnavailable ErrorCode = "MODEL_UNAVAILABLE"
+
+	// CodeRateLimited means too many requests were made; retry after the advertised delay
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+
+	// CodeTimeout means processing did not finish in time
+	CodeTimeout ErrorCode = "TIMEOUT"
+
+	// CodeInternal means an unexpected server error
+	CodeInternal ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the JSON envelope returned for every API error
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error
+type ErrorDetail struct {
+	Code              ErrorCode `json:"code"`
+	Message           string    `json:"message"`
+	RequestID         string    `json:"request_id,omitempty"`
+	Retryable         bool      `json:"retryable"`
+	RetryAfterSeconds int       `json:"retry_after_seconds,omitempty"`
+
+	// Fields lists each invalid field of a request body that failed validation
+	Fields []openapi.FieldError `json:"fields,omitempty"`
+}
+
+// apiError is an error classified for a response
+type apiError struct {
+	status     int
+	code       ErrorCode
+	message    string
+	retryAfter int // seconds; zero means the request should not be retried as is
+	fields     []openapi.FieldError
+}
+
+// writeError writes a client error whose message is safe to show the caller
+func writeError(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string) {
+	writeAPIError(w, r, apiError{status: status, code: code, message: message})
+}
+
+// writeServiceError logs err in full and writes a classified error that does not expose its details
+func writeServiceError(w http.ResponseWriter, r *http.Request, operation string, err error) {
+	writeAPIError(w, r, serviceError(operation, err, RequestIDFromContext(r.Context())))
+}
+
+// serviceError logs err in full and classifies it for a response. A stageError names the operation instead.
+func serviceError(operation string, err error, requestID string) apiError {
+	logged := err
+	var stage *stageError
+	if errors.As(err, &stage) {
+		operation = stage.operation
+		if err == error(stage) {
+			// Don't repeat the operation the stage error already names
+			logged = stage.err
+		}
+	}
+	log.Printf("Request %s: %s: %v", requestID, operation, logged)
+
+	classified := classifyError(err)
+	classified.message = operation + ": " + classified.message
+	return classified
+}
+
+// stageError records which processing stage failed so the response can say so
+type stageError struct {
+	operation string
+	err       error
+}
+
+func (e *stageError) Error() string {
+	return e.operation + ": " + e.err.Error()
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// writeAPIError writes the JSON error envelope with any retry hint
+func writeAPIError(w http.ResponseWriter, r *http.Request, e apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	if e.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(e.retryAfter))
+	}
+	w.WriteHeader(e.status)
+
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: e.detail(RequestIDFromContext(r.Context()))}); err != nil {
+		log.Printf("Failed to encode error response: %v", err)
+	}
+}
+
+// detail returns the error as it appears in a response
+func (e apiError) detail(requestID string) ErrorDetail {
+	return ErrorDetail{
+		Code:              e.code,
+		Message:           e.message,
+		RequestID:         requestID,
+		Retryable:         e.retryAfter > 0,
+		RetryAfterSeconds: e.retryAfter,
+		Fields:            e.fields,
+	}
+}
+
+// classifyError maps audio and ai sentinel errors to a status, code and caller-safe message
+func classifyError(err error) apiError {
+	switch {
+	case errors.Is(err, audio.ErrUnsupportedFormat):
+		return apiError{status: http.StatusUnsupportedMediaType, code: CodeInvalidAudio, message: "audio format is not supported"}
+	case errors.Is(err, audio.ErrTooLong):
+		return apiError{status: http.StatusRequestEntityTooLarge, code: CodeInvalidAudio, message: "recording is longer than the maximum allowed"}
+	case errors.Is(err, audio.ErrCorrupt):
+		return apiError{status: http.StatusBadRequest, code: CodeInvalidAudio, message: "audio file is corrupt"}
+	case errors.Is(err, audio.ErrEmpty):
+		return apiError{status: http.StatusBadRequest, code: CodeInvalidAudio, message: "recording contains no audio"}
+	case errors.Is(err, ai.ErrInvalidAudioFormat):
+		return apiError{status: http.StatusUnsupportedMediaType, code: CodeInvalidAudio, message: "audio format is not supported by the model"}
+
+	case errors.Is(err, ErrQueueFull):
+		return apiError{status: http.StatusServiceUnavailable, code: CodeRateLimited, message: "too many emergencies are being processed", retryAfter: 5}
+	case errors.Is(err, ai.ErrRateLimitExceeded):
+		return apiError{status: http.StatusTooManyRequests, code: CodeRateLimited, message: "the AI model is rate limited", retryAfter: 10}
+	case errors.Is(err, ai.ErrModelUnavailable):
+		return apiError{status: http.StatusServiceUnavailable, code: CodeModelUnavailable, message: "the AI model is temporarily unavailable", retryAfter: 5}
+	case errors.Is(err, ai.ErrAPICallFailed):
+		return apiError{status: http.StatusBadGateway, code: CodeModelUnavailable, message: "the AI model request failed", retryAfter: 5}
+	case errors.Is(err, ai.ErrContextDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return apiError{status: http.StatusGatewayTimeout, code: CodeTimeout, message: "processing timed out", retryAfter: 1}
+	case errors.Is(err, context.Canceled):
+		return apiError{status: http.StatusServiceUnavailable, code: CodeTimeout, message: "request was cancelled", retryAfter: 1}
+	}
+
+	return apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "an internal error occurred"}
+}
+
+// methodNotAllowed writes the standard error for an unsupported HTTP method
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"agent/internal/ai"
 	"agent/internal/models"
 )
 
@@ -16,12 +20,14 @@ import (
 type EmergencyHandler struct {
 	audioProcessor *AudioProcessor
 	textProcessor  *TextProcessor
+	imageProcessor *ImageProcessor
 	coordinator    *EmergencyCoordinator
+	jobs           *JobQueue
 	maxAudioSize   int64
 }
 
 // NewEmergencyHandler creates a new emergency API handler
-func NewEmergencyHandler(audioProcessor *AudioProcessor, textProcessor *TextProcessor, coordinator *EmergencyCoordinator, maxAudioSize int64) *EmergencyHandler {
+func NewEmergencyHandler(audioProcessor *AudioProcessor, textProcessor *TextProcessor, imageProcessor *ImageProcessor, coordinator *EmergencyCoordinator, jobs *JobQueue, maxAudioSize int64) *EmergencyHandler {
 	if maxAudioSize == 0 {
 		maxAudioSize = 10 * 1024 * 1024 // Default to 10MB
 	}
@@ -29,7 +35,9 @@ func NewEmergencyHandler(audioProcessor *AudioProcessor, textProcessor *TextProc
 	return &EmergencyHandler{
 		audioProcessor: audioProcessor,
 		textProcessor:  textProcessor,
+		imageProcessor: imageProcessor,
 		coordinator:    coordinator,
+		jobs:           jobs,
 		maxAudioSize:   maxAudioSize,
 	}
 }
@@ -38,73 +46,244 @@ func NewEmergencyHandler(audioProcessor *AudioProcessor, textProcessor *TextProc
 func (h *EmergencyHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/emergency", h.HandleEmergency)
 	mux.HandleFunc("/api/v1/emergency/text", h.HandleTextEmergency)
+	mux.HandleFunc("/api/v1/emergency/stream", h.HandleEmergencyStream)
+	mux.HandleFunc("/api/v1/emergency/live", h.HandleLiveEmergency)
+	mux.HandleFunc("/api/v1/assessment", h.HandleAssessment)
 	mux.HandleFunc("/api/v1/health", h.HandleHealthCheck)
+	mux.HandleFunc("/api/v1/health/live", h.HandleHealthCheck)
 }
 
 // HandleEmergency processes an incoming emergency request
 func (h *EmergencyHandler) HandleEmergency(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST method
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r)
 		return
 	}
 
+	upload, ok := h.parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer upload.close()
+
+	if wantsAsync(r) {
+		h.submitUpload(w, r, upload)
+		return
+	}
+
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	response, err := h.processUpload(ctx, upload, nil)
+	if err != nil {
+		writeServiceError(w, r, "Failed to process emergency", err)
+		return
+	}
+
+	// Return response as JSON
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// parseUpload reads the audio, photo and location of a multipart emergency request. On failure it writes
+// the error response and returns false.
+func (h *EmergencyHandler) parseUpload(w http.ResponseWriter, r *http.Request) (emergencyUpload, bool) {
+	var upload emergencyUpload
+
 	// Check content type
 	contentType := r.Header.Get("Content-Type")
 	if contentType == "" || len(contentType) < 19 || contentType[:19] != "multipart/form-data" {
-		http.Error(w, "Content-Type must be multipart/form-data", http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be multipart/form-data")
+		return upload, false
 	}
 
 	// Parse multipart form with max size limit - letting Go parse the Content-Type header directly
 	err := r.ParseMultipartForm(h.maxAudioSize)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to parse form; the upload may exceed the size limit")
+		return upload, false
 	}
 
 	// Get location data
-	var location *models.Location
 	locationData := r.FormValue("location")
 	if locationData != "" {
-		if err := json.Unmarshal([]byte(locationData), &location); err != nil {
-			http.Error(w, fmt.Sprintf("Invalid location data: %v", err), http.StatusBadRequest)
-			return
+		if err := json.Unmarshal([]byte(locationData), &upload.location); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Location must be a JSON object with latitude and longitude")
+			return upload, false
+		}
+	}
+
+	// Get the optional scene or wound photo
+	imageFile, imageHeader, err := r.FormFile("image")
+	if err != nil && err != http.ErrMissingFile {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read the image upload")
+		return upload, false
+	}
+	if imageFile != nil {
+		upload.closers = append(upload.closers, imageFile)
+		if h.imageProcessor == nil {
+			upload.close()
+			writeError(w, r, http.StatusBadRequest, CodeFeatureDisabled, "Image analysis is not enabled on this server")
+			return upload, false
 		}
+
+		log.Printf("Received emergency image: %s (size: %d bytes)", imageHeader.Filename, imageHeader.Size)
+		upload.image = imageFile
+		upload.imageMIMEType = imageHeader.Header.Get("Content-Type")
 	}
 
-	// Get audio file
+	// Get audio file; it may only be omitted when a photo is provided
 	file, header, err := r.FormFile("audio")
+	if err != nil && (err != http.ErrMissingFile || imageFile == nil) {
+		upload.close()
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "An audio file or image is required")
+		return upload, false
+	}
+	if file != nil {
+		upload.closers = append(upload.closers, file)
+
+		// Log incoming request
+		log.Printf("Received emergency request with audio file: %s (size: %d bytes)",
+			header.Filename, header.Size)
+		upload.audio = file
+	}
+
+	return upload, true
+}
+
+// emergencyUpload is the parsed content of a multipart emergency request
+type emergencyUpload struct {
+	audio         io.Reader
+	image         io.Reader
+	imageMIMEType string
+	location      *models.Location
+
+	closers []io.Closer
+}
+
+// close releases the uploaded files
+func (u *emergencyUpload) close() {
+	for _, closer := range u.closers {
+		closer.Close()
+	}
+}
+
+// wantsAsync reports whether the caller asked for a job instead of waiting for the result
+func wantsAsync(r *http.Request) bool {
+	return r.FormValue("async") == "true" || strings.Contains(r.Header.Get("Prefer"), "respond-async")
+}
+
+// submitUpload queues an upload as a job and responds 202 with the job's status URL
+func (h *EmergencyHandler) submitUpload(w http.ResponseWriter, r *http.Request, upload emergencyUpload) {
+	if h.jobs == nil {
+		writeError(w, r, http.StatusBadRequest, CodeFeatureDisabled, "Asynchronous processing is not enabled on this server")
+		return
+	}
+
+	// Multipart files are deleted when the handler returns, so the job keeps its own copy
+	var err error
+	if upload.audio, err = bufferUpload(upload.audio); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read the audio upload")
+		return
+	}
+	if upload.image, err = bufferUpload(upload.image); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read the image upload")
+		return
+	}
+
+	job, err := h.jobs.Submit(r.Context(), func(ctx context.Context, partial func(*models.EmergencySituation)) (*EmergencyResponse, error) {
+		return h.processUpload(ctx, upload, partial)
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get audio file: %v", err), http.StatusBadRequest)
+		writeServiceError(w, r, "Failed to queue emergency", err)
 		return
 	}
-	defer file.Close()
 
-	// Log incoming request
-	log.Printf("Received emergency request with audio file: %s (size: %d bytes)",
-		header.Filename, header.Size)
+	log.Printf("Queued emergency job %s", job.JobID)
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", jobsPath+job.JobID)
+	w.WriteHeader(http.StatusAccepted)
 
-	// Process audio to extract emergency information
-	situation, err := h.audioProcessor.ProcessEmergencyAudio(ctx, file)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// bufferUpload reads an uploaded file into memory; a nil reader stays nil
+func bufferUpload(r io.Reader) (io.Reader, error) {
+	if r == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process audio: %v", err), http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// processUpload triages the audio and photo of an upload and coordinates the response.
+// partial, if set, receives a preliminary triage while a long recording is still being processed.
+func (h *EmergencyHandler) processUpload(ctx context.Context, upload emergencyUpload, partial func(*models.EmergencySituation)) (*EmergencyResponse, error) {
+	var situation *models.EmergencySituation
+	if upload.audio != nil {
+		// Process audio to extract emergency information
+		var err error
+		situation, err = h.audioProcessor.ProcessEmergencyAudioWithProgress(ctx, upload.audio, partial)
+		if err != nil {
+			return nil, &stageError{operation: "Failed to process audio", err: err}
+		}
+	}
+
+	if upload.image != nil {
+		imageSituation, err := h.imageProcessor.ProcessEmergencyImage(ctx, upload.image, upload.imageMIMEType)
+		switch {
+		case err != nil && situation == nil:
+			return nil, &stageError{operation: "Failed to process image", err: err}
+		case err != nil:
+			skipImage(situation, err)
+		case situation == nil:
+			situation = imageSituation
+		default:
+			mergeImageAssessment(situation, imageSituation)
+		}
 	}
 
 	// Add location information if available
-	if location != nil {
-		situation.Location = location
+	if upload.location != nil {
+		situation.Location = upload.location
 	}
 
 	// Process the emergency with the coordinator
-	response, err := h.coordinator.ProcessEmergency(ctx, situation)
+	return h.coordinator.ProcessEmergency(ctx, situation)
+}
+
+// HandleTextEmergency processes an incoming emergency request with text input
+func (h *EmergencyHandler) HandleTextEmergency(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	request, ok := h.parseTextRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	response, err := h.processTextRequest(ctx, request)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process emergency: %v", err), http.StatusInternalServerError)
+		writeServiceError(w, r, "Failed to process emergency", err)
 		return
 	}
 
@@ -117,83 +296,119 @@ func (h *EmergencyHandler) HandleEmergency(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// HandleTextEmergency processes an incoming emergency request with text input
-func (h *EmergencyHandler) HandleTextEmergency(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST method
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// TextEmergencyRequest is the body of a text emergency
+type TextEmergencyRequest struct {
+	Text          string           `json:"text"`
+	Location      *models.Location `json:"location,omitempty"`
+	Image         string           `json:"image,omitempty"` // Base64-encoded photo
+	ImageMIMEType string           `json:"image_mime_type,omitempty"`
+}
+
+// textEmergencyRequest is the parsed body of a text emergency request
+type textEmergencyRequest struct {
+	text          string
+	location      *models.Location
+	imageData     []byte
+	imageMIMEType string
+}
+
+// parseTextRequest reads and validates a JSON text emergency request. On failure it writes the error
+// response and returns false.
+func (h *EmergencyHandler) parseTextRequest(w http.ResponseWriter, r *http.Request) (textEmergencyRequest, bool) {
+	var request textEmergencyRequest
 
 	// Check content type
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" {
-		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be application/json")
+		return request, false
 	}
 
 	// Parse request body
-	var requestBody struct {
-		Text     string           `json:"text"`
-		Location *models.Location `json:"location,omitempty"`
-	}
+	var requestBody TextEmergencyRequest
 
-	// Limit the request body size
-	body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024)) // 1MB limit
+	// Limit the request body size; photos are embedded as base64 so allow the media limit
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxAudioSize))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
-		return
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, CodeInvalidRequest, "Request body is too large")
+			return request, false
+		}
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+		return request, false
 	}
 	defer r.Body.Close()
 
 	// Parse JSON
 	if err := json.Unmarshal(body, &requestBody); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+		return request, false
 	}
 
 	// Validate that text is provided
 	if requestBody.Text == "" {
-		http.Error(w, "Text field is required", http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Text field is required")
+		return request, false
+	}
+
+	// Decode the optional photo
+	if requestBody.Image != "" {
+		if h.imageProcessor == nil {
+			writeError(w, r, http.StatusBadRequest, CodeFeatureDisabled, "Image analysis is not enabled on this server")
+			return request, false
+		}
+		request.imageData, err = base64.StdEncoding.DecodeString(requestBody.Image)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Image field must be base64 encoded")
+			return request, false
+		}
 	}
 
 	// Log incoming request
 	log.Printf("Received emergency text request (length: %d characters)", len(requestBody.Text))
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancel()
+	request.text = requestBody.Text
+	request.location = requestBody.Location
+	request.imageMIMEType = requestBody.ImageMIMEType
+	return request, true
+}
 
+// processTextRequest triages the text and photo of a request and coordinates the response
+func (h *EmergencyHandler) processTextRequest(ctx context.Context, request textEmergencyRequest) (*EmergencyResponse, error) {
 	// Process text to extract emergency information
-	situation, err := h.textProcessor.ProcessEmergencyText(ctx, requestBody.Text)
+	situation, err := h.textProcessor.ProcessEmergencyText(ctx, request.text)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process text: %v", err), http.StatusInternalServerError)
-		return
+		return nil, &stageError{operation: "Failed to process text", err: err}
 	}
 
-	// Add location information if available
-	if requestBody.Location != nil {
-		situation.Location = requestBody.Location
+	// Combine the photo assessment with the text triage
+	if request.imageData != nil {
+		imageSituation, err := h.imageProcessor.ProcessEmergencyImage(ctx, bytes.NewReader(request.imageData), request.imageMIMEType)
+		if err != nil {
+			skipImage(situation, err)
+		} else {
+			mergeImageAssessment(situation, imageSituation)
+		}
 	}
 
-	// Process the emergency with the coordinator
-	response, err := h.coordinator.ProcessEmergency(ctx, situation)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process emergency: %v", err), http.StatusInternalServerError)
-		return
+	// Add location information if available
+	if request.location != nil {
+		situation.Location = request.location
 	}
 
-	// Return response as JSON
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	// Process the emergency with the coordinator
+	return h.coordinator.ProcessEmergency(ctx, situation)
+}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode response: %v", err)
-	}
+// skipImage records a photo that could not be assessed. The emergency is still triaged and coordinated from
+// the caller's audio or text, so a bad photo never holds up a dispatch.
+func skipImage(situation *models.EmergencySituation, err error) {
+	log.Printf("Failed to process image for %s; continuing without it: %v", situation.ID, err)
+	situation.Metadata["image_error"] = ai.ErrorType(err)
 }
 
-// HandleHealthCheck provides a basic health check endpoint
+// HandleHealthCheck is the liveness probe: it reports the process is serving, not that its dependencies work
 func (h *EmergencyHandler) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
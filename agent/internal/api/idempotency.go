@@ -0,0 +1,345 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agent/internal/auth"
+	"agent/internal/metrics"
+)
+
+const (
+	// IdempotencyKeyHeader names a client-chosen key that makes retries of a request return its first response
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on responses replayed for a retried request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKey bounds the length of an Idempotency-Key
+const maxIdempotencyKey = 255
+
+// IdempotencyConfig contains the settings for IdempotencyMiddleware
+type IdempotencyConfig struct {
+	// Paths lists the routes that honour Idempotency-Key; a path ending in "/" is a prefix
+	Paths []string
+
+	// MaxBodySize bounds the request bodies that are fingerprinted; larger requests are rejected
+	MaxBodySize int64
+
+	// Metrics receives idempotency counters; defaults to metrics.Default
+	Metrics *metrics.Registry
+}
+
+// DefaultIdempotentPaths are the routes whose requests can dispatch responders. The incident prefix also
+// covers the text, stream and chat endpoints.
+var DefaultIdempotentPaths = []string{"/api/v1/emergency", "/api/v1/assessment", incidentPath}
+
+// idempotentRequest is the first request made with a key, and once it has finished, its response
+type idempotentRequest struct {
+	fingerprint [sha256.Size]byte
+	done        chan struct{} // Closed when the response has been recorded or abandoned
+
+	// Set before done is closed
+	status    int
+	header    http.Header
+	body      []byte
+	stored    bool // False when the response was not worth replaying and the key was released
+	expiresAt time.Time
+}
+
+// IdempotencyStore remembers the responses of requests made with an Idempotency-Key and expires them
+type IdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]*idempotentRequest
+	ttl      time.Duration
+}
+
+// NewIdempotencyStore creates a new idempotency store; responses are kept for ttl after they finish
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	return &IdempotencyStore{
+		requests: make(map[string]*idempotentRequest),
+		ttl:      ttl,
+	}
+}
+
+// begin returns the request already made with key, or registers a new one and returns it with true when
+// the caller should run it
+func (s *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) (*idempotentRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+
+	if existing, ok := s.requests[key]; ok {
+		return existing, false
+	}
+	request := &idempotentRequest{fingerprint: fingerprint, done: make(chan struct{})}
+	s.requests[key] = request
+	return request, true
+}
+
+// finish records the response to a request begun with key. A response that should not be replayed
+// releases the key so a retry runs the request again.
+func (s *IdempotencyStore) finish(key string, request *idempotentRequest, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request.status = status
+	request.header = header
+	request.body = body
+	request.stored = replayable(status, header, body)
+	request.expiresAt = time.Now().Add(s.ttl)
+	if !request.stored && s.requests[key] == request {
+		delete(s.requests, key)
+	}
+	close(request.done)
+}
+
+// pruneLocked removes expired responses; the caller must hold s.mu
+func (s *IdempotencyStore) pruneLocked() {
+	now := time.Now()
+	for key, request := range s.requests {
+		select {
+		case <-request.done:
+			if now.After(request.expiresAt) {
+				delete(s.requests, key)
+			}
+		default:
+			// Still in flight
+		}
+	}
+}
+
+// replayable reports whether a response is final. Server errors and throttling are worth retrying for real,
+// as are emergencies triaged without the model because of a rate limit and streams that did not complete.
+func replayable(status int, header http.Header, body []byte) bool {
+	if status <= 0 || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		return false
+	}
+	if header.Get(RuleBasedTriageHeader) != "" {
+		return false
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		return streamCompleted(body)
+	}
+	return true
+}
+
+// streamCompleted reports whether a server-sent event stream ended with its completed event. Event data is
+// JSON on one line, so only frame boundaries start a line with "event: ".
+func streamCompleted(body []byte) bool {
+	body = append([]byte("\n"), body...)
+	last := bytes.LastIndex(body, []byte("\nevent: "))
+	return last >= 0 && bytes.HasPrefix(body[last+1:], []byte("event: "+string(EventCompleted)+"\n"))
+}
+
+// IdempotencyMiddleware makes POST requests carrying an Idempotency-Key safe to retry. The first request with
+// a key runs; a retry made while it is in flight waits for it, and a later one gets the stored response,
+// so a network error on the client never dispatches responders twice. Keys are scoped to the client.
+func IdempotencyMiddleware(store *IdempotencyStore, config IdempotencyConfig, next http.Handler) http.Handler {
+	if config.Paths == nil {
+		config.Paths = DefaultIdempotentPaths
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 32 * 1024 * 1024 // Room for the largest audio upload
+	}
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+
+	replays := registry.Counter("idempotency_replays_total", "Retried requests answered with the stored response")
+	waits := registry.Counter("idempotency_waits_total", "Retried requests that waited for the original to finish")
+	conflicts := registry.Counter("idempotency_conflicts_total", "Idempotency keys reused for a different request")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost || !matchesPath(config.Paths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		// The body is fingerprinted so a key reused for a different emergency is caught, then replayed to the handler
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, http.StatusRequestEntityTooLarge, CodeInvalidRequest, "Request body is too large")
+				return
+			}
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		principal := "anonymous"
+		if client := auth.ClientFromContext(r.Context()); client != nil {
+			principal = client.Principal()
+		}
+		scopedKey := principal + " " + r.URL.Path + " " + key
+		fingerprint := requestFingerprint(r.Header.Get("Content-Type"), body)
+
+		for {
+			request, first := store.begin(scopedKey, fingerprint)
+			if first {
+				recorder := &responseRecorder{ResponseWriter: w}
+				defer func() {
+					// A panicking handler still releases the key and any retries waiting on it
+					store.finish(scopedKey, request, recorder.status, recorder.header, recorder.body.Bytes())
+				}()
+				next.ServeHTTP(recorder, r)
+				return
+			}
+
+			if request.fingerprint != fingerprint {
+				conflicts.Inc()
+				writeError(w, r, http.StatusUnprocessableEntity, CodeIdempotencyConflict, "Idempotency-Key was already used for a different request")
+				return
+			}
+
+			select {
+			case <-request.done:
+			default:
+				waits.Inc()
+				log.Printf("Request with Idempotency-Key from %s is still in flight; waiting for it", principal)
+				select {
+				case <-request.done:
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if !request.stored {
+				// The original failed in a way worth retrying, so this request runs in its place
+				continue
+			}
+
+			replays.Inc()
+			for name, values := range request.header {
+				if name != RequestIDHeader {
+					w.Header()[name] = values
+				}
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(request.status)
+			w.Write(request.body)
+			return
+		}
+	})
+}
+
+// requestFingerprint hashes what a request asks for. A multipart body is hashed by its parsed fields, since
+// its boundary is chosen afresh for every attempt and would make a real retry look like a different request.
+func requestFingerprint(contentType string, body []byte) [sha256.Size]byte {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return sha256.Sum256(append([]byte(contentType+"\n"), body...))
+	}
+
+	var fields []string
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A malformed body fails in the handler; its raw bytes are fingerprint enough
+			return sha256.Sum256(append([]byte(contentType+"\n"), body...))
+		}
+		content := sha256.New()
+		io.Copy(content, part)
+		if part.FileName() != "" {
+			fields = append(fields, fmt.Sprintf("file %q %x", part.FormName(), content.Sum(nil)))
+		} else {
+			fields = append(fields, fmt.Sprintf("value %q %x", part.FormName(), content.Sum(nil)))
+		}
+		part.Close()
+	}
+	sort.Strings(fields)
+	return sha256.Sum256([]byte(mediaType + "\n" + strings.Join(fields, "\n")))
+}
+
+// matchesPath reports whether path is one of paths or under one of those ending in "/"
+func matchesPath(paths []string, path string) bool {
+	for _, candidate := range paths {
+		if path == candidate || strings.HasSuffix(candidate, "/") && strings.HasPrefix(path, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseRecorder passes a response through while keeping a copy. It supports flushing so streamed
+// responses still reach the original caller as they are produced.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status and a snapshot of the headers
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = status
+	r.header = r.ResponseWriter.Header().Clone()
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records and forwards part of the body
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original writer, so http.ResponseController can reach its deadlines
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush forwards buffered data to the client
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection to the handler; a hijacked response cannot be replayed
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
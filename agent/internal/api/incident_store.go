@@ -0,0 +1,291 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"agent/internal/models"
+	"agent/internal/tools/location"
+)
+
+// ErrIncidentNotFound is returned when no incident has the requested ID
+var ErrIncidentNotFound = errors.New("incident not found")
+
+// Incident is a stored emergency together with the response coordinated for it
+type Incident struct {
+	ID        string                     `json:"id"`
+	Version   int                        `json:"version"` // 1 when first coordinated, then one more per update
+	Code      models.TriageCode          `json:"code"`
+	CreatedAt time.Time                  `json:"created_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	Situation *models.EmergencySituation `json:"situation"`
+	Response  *EmergencyResponse         `json:"response,omitempty"`
+	Updates   []IncidentUpdate           `json:"updates,omitempty"`
+
+	// SourceText is everything the caller said or typed, kept for re-triage
+	SourceText string `json:"source_text,omitempty"`
+}
+
+// IncidentUpdate is information added to an incident after it was first coordinated
+type IncidentUpdate struct {
+	Version      int                `json:"version"`
+	ReceivedAt   time.Time          `json:"received_at"`
+	Text         string             `json:"text,omitempty"`
+	Transcript   *models.Transcript `json:"transcript,omitempty"`
+	Vitals       *models.Vitals     `json:"vitals,omitempty"`
+	PreviousCode models.TriageCode  `json:"previous_code"`
+	Code         models.TriageCode  `json:"code"`
+	ToolsRun     []string           `json:"tools_run,omitempty"`
+	SubmittedBy  string             `json:"submitted_by,omitempty"`
+}
+
+// IncidentSummary is the listing view of an incident
+type IncidentSummary struct {
+	ID          string            `json:"id"`
+	Code        models.TriageCode `json:"code"`
+	Description string            `json:"description"`
+	Location    *models.Location  `json:"location,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// IncidentFilter selects incidents to list; zero fields match everything
+type IncidentFilter struct {
+	Codes    []models.TriageCode
+	Since    time.Time
+	Until    time.Time
+	Near     *models.Location
+	RadiusKm float64 // Distance from Near; ignored without Near
+	Limit    int
+	Offset   int
+}
+
+// IncidentStore persists incidents
+type IncidentStore interface {
+	// Save creates or replaces an incident
+	Save(incident *Incident) error
+
+	// Get returns an incident by ID, or ErrIncidentNotFound
+	Get(id string) (*Incident, error)
+
+	// List returns a page of matching incidents, newest first, and the total number that match
+	List(filter IncidentFilter) ([]IncidentSummary, int, error)
+
+	// Ping checks the store can accept writes
+	Ping() error
+}
+
+// FileIncidentStore keeps each incident as a JSON file in a directory, with an index in memory for
+// lookups and filtering. With no directory it keeps incidents in memory only.
+type FileIncidentStore struct {
+	dir string
+
+	mu        sync.RWMutex
+	incidents map[string]*storedIncident
+}
+
+// storedIncident is an encoded incident with the fields the index filters on
+type storedIncident struct {
+	data    []byte
+	summary IncidentSummary
+}
+
+// NewFileIncidentStore opens the store in dir, creating it if needed and loading existing incidents
+func NewFileIncidentStore(dir string) (*FileIncidentStore, error) {
+	store := &FileIncidentStore{
+		dir:       dir,
+		incidents: make(map[string]*storedIncident),
+	}
+	if dir == "" {
+		return store, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create incident store directory: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read incident %s: %w", path, err)
+		}
+		var incident Incident
+		if err := json.Unmarshal(data, &incident); err != nil || incident.ID == "" || incident.Situation == nil {
+			fmt.Printf("Warning: skipping unreadable incident file %s: %v\n", path, err)
+			continue
+		}
+		store.incidents[incident.ID] = &storedIncident{data: data, summary: summarize(&incident)}
+	}
+	return store, nil
+}
+
+// Save creates or replaces an incident, writing it to disk before it becomes visible
+func (s *FileIncidentStore) Save(incident *Incident) error {
+	if !validIncidentID(incident.ID) {
+		return fmt.Errorf("invalid incident ID %q", incident.ID)
+	}
+
+	data, err := json.Marshal(incident)
+	if err != nil {
+		return fmt.Errorf("failed to encode incident: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir != "" {
+		if err := writeFileAtomic(filepath.Join(s.dir, incident.ID+".json"), data); err != nil {
+			return fmt.Errorf("failed to write incident: %w", err)
+		}
+	}
+	s.incidents[incident.ID] = &storedIncident{data: data, summary: summarize(incident)}
+	return nil
+}
+
+// Ping checks the incident directory is writable
+func (s *FileIncidentStore) Ping() error {
+	return checkWritable(s.dir)
+}
+
+// Get returns a copy of an incident by ID
+func (s *FileIncidentStore) Get(id string) (*Incident, error) {
+	s.mu.RLock()
+	stored, ok := s.incidents[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIncidentNotFound, id)
+	}
+
+	var incident Incident
+	if err := json.Unmarshal(stored.data, &incident); err != nil {
+		return nil, fmt.Errorf("failed to decode incident %s: %w", id, err)
+	}
+	return &incident, nil
+}
+
+// List returns a page of incidents matching filter, newest first
+func (s *FileIncidentStore) List(filter IncidentFilter) ([]IncidentSummary, int, error) {
+	s.mu.RLock()
+	var matches []IncidentSummary
+	for _, stored := range s.incidents {
+		if filter.matches(&stored.summary) {
+			matches = append(matches, stored.summary)
+		}
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(matches, func(a, b IncidentSummary) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	total := len(matches)
+	start := min(filter.Offset, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+	return matches[start:end], total, nil
+}
+
+// matches reports whether an incident passes every filter criterion
+func (f *IncidentFilter) matches(summary *IncidentSummary) bool {
+	if len(f.Codes) > 0 && !slices.Contains(f.Codes, summary.Code) {
+		return false
+	}
+	if !f.Since.IsZero() && summary.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && summary.CreatedAt.After(f.Until) {
+		return false
+	}
+	if f.Near != nil {
+		if summary.Location == nil || location.CalculateDistance(f.Near.Latitude, f.Near.Longitude, summary.Location.Latitude, summary.Location.Longitude) > f.RadiusKm {
+			return false
+		}
+	}
+	return true
+}
+
+// summarize builds the listing view of an incident
+func summarize(incident *Incident) IncidentSummary {
+	return IncidentSummary{
+		ID:          incident.ID,
+		Code:        incident.Code,
+		Description: incident.Situation.Description,
+		Location:    incident.Situation.Location,
+		CreatedAt:   incident.CreatedAt,
+		UpdatedAt:   incident.UpdatedAt,
+	}
+}
+
+// validIncidentID reports whether id is safe to use as a file name
+func validIncidentID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return !strings.HasPrefix(id, ".")
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// checkWritable writes and removes a probe file in dir; an empty dir means memory only, which is always writable
+func checkWritable(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	file, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString("ok"); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"agent/internal/auth"
+	"agent/internal/models"
+)
+
+// IncidentUpdateRequest is the JSON body of an incident update
+type IncidentUpdateRequest struct {
+	Text     string           `json:"text,omitempty"`
+	Vitals   *models.Vitals   `json:"vitals,omitempty"`
+	Location *models.Location `json:"location,omitempty"`
+}
+
+// incidentUpdate is the parsed content of an update request
+type incidentUpdate struct {
+	text     string
+	audio    io.Reader
+	vitals   *models.Vitals
+	location *models.Location
+
+	closers []io.Closer
+}
+
+// close releases the uploaded files
+func (u *incidentUpdate) close() {
+	for _, closer := range u.closers {
+		closer.Close()
+	}
+}
+
+// HandleIncidentUpdate adds text, audio or vitals to a stored emergency, re-triages it and runs any tools
+// the new code calls for that have not already run
+func (h *IncidentsHandler) HandleIncidentUpdate(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+	if h.textProcessor == nil || h.coordinator == nil {
+		writeError(w, r, http.StatusBadRequest, CodeFeatureDisabled, "Incident updates are not enabled on this server")
+		return
+	}
+
+	update, ok := h.parseUpdate(w, r)
+	if !ok {
+		return
+	}
+	defer update.close()
+
+	// Updates to one incident are applied in order so none is lost
+	unlock := h.locks.lock(id)
+	defer unlock()
+
+	incident, err := h.store.Get(id)
+	if err == nil && !canAccessIncident(r.Context(), incident) {
+		err = ErrIncidentNotFound
+	}
+	if errors.Is(err, ErrIncidentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Emergency not found")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, "Failed to load emergency", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	if err := h.applyUpdate(ctx, incident, update); err != nil {
+		writeServiceError(w, r, "Failed to update emergency", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(incident); err != nil {
+		log.Printf("Failed to encode incident: %v", err)
+	}
+}
+
+// parseUpdate reads an update as JSON ({"text", "vitals", "location"}) or as a multipart form with the same
+// fields plus an audio file. On failure it writes the error response and returns false.
+func (h *IncidentsHandler) parseUpdate(w http.ResponseWriter, r *http.Request) (incidentUpdate, bool) {
+	var update incidentUpdate
+
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		var requestBody IncidentUpdateRequest
+		body, err := io.ReadAll(io.LimitReader(r.Body, h.maxAudioSize))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+			return update, false
+		}
+		if err := json.Unmarshal(body, &requestBody); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+			return update, false
+		}
+		update.text = strings.TrimSpace(requestBody.Text)
+		update.vitals = requestBody.Vitals
+		update.location = requestBody.Location
+
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(h.maxAudioSize); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to parse form; the upload may exceed the size limit")
+			return update, false
+		}
+		update.text = strings.TrimSpace(r.FormValue("text"))
+		if vitals := r.FormValue("vitals"); vitals != "" {
+			if err := json.Unmarshal([]byte(vitals), &update.vitals); err != nil {
+				writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Vitals must be a JSON object")
+				return update, false
+			}
+		}
+		if location := r.FormValue("location"); location != "" {
+			if err := json.Unmarshal([]byte(location), &update.location); err != nil {
+				writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Location must be a JSON object with latitude and longitude")
+				return update, false
+			}
+		}
+
+		file, header, err := r.FormFile("audio")
+		if err != nil && err != http.ErrMissingFile {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read the audio upload")
+			return update, false
+		}
+		if file != nil {
+			update.closers = append(update.closers, file)
+			log.Printf("Received emergency update with audio file: %s (size: %d bytes)", header.Filename, header.Size)
+			update.audio = file
+		}
+
+	default:
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be application/json or multipart/form-data")
+		return update, false
+	}
+
+	if update.text == "" && update.audio == nil && update.vitals == nil {
+		update.close()
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "An update needs text, audio or vitals")
+		return update, false
+	}
+	if update.vitals != nil {
+		if err := validateVitals(update.vitals); err != nil {
+			update.close()
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return update, false
+		}
+	}
+	return update, true
+}
+
+// validateVitals rejects readings that cannot be real measurements
+func validateVitals(v *models.Vitals) error {
+	switch {
+	case v.HeartRate < 0 || v.HeartRate > 350:
+		return fmt.Errorf("heart_rate must be between 0 and 350")
+	case v.RespiratoryRate < 0 || v.RespiratoryRate > 100:
+		return fmt.Errorf("respiratory_rate must be between 0 and 100")
+	case v.SystolicBP < 0 || v.SystolicBP > 350 || v.DiastolicBP < 0 || v.DiastolicBP > 250:
+		return fmt.Errorf("blood pressure is out of range")
+	case v.OxygenSaturation < 0 || v.OxygenSaturation > 100:
+		return fmt.Errorf("oxygen_saturation must be a percentage")
+	case v.Temperature != 0 && (v.Temperature < 20 || v.Temperature > 45):
+		return fmt.Errorf("temperature must be in degrees Celsius")
+	case v.GlasgowComaScale != 0 && (v.GlasgowComaScale < 3 || v.GlasgowComaScale > 15):
+		return fmt.Errorf("glasgow_coma_scale must be between 3 and 15")
+	}
+	return nil
+}
+
+// applyUpdate re-triages an incident with the update and saves the new version
+func (h *IncidentsHandler) applyUpdate(ctx context.Context, incident *Incident, update incidentUpdate) error {
+	entry := IncidentUpdate{
+		Version:      incident.Version + 1,
+		ReceivedAt:   time.Now(),
+		Text:         update.text,
+		Vitals:       update.vitals,
+		PreviousCode: incident.Code,
+	}
+	if client := auth.ClientFromContext(ctx); client != nil {
+		entry.SubmittedBy = client.Principal()
+	}
+
+	if update.audio != nil {
+		heard, err := h.audioProcessor.ProcessEmergencyAudio(ctx, update.audio)
+		if err != nil {
+			return &stageError{operation: "Failed to process audio", err: err}
+		}
+		entry.Transcript = heard.Transcript
+		text := heard.SourceText
+		if text == "" {
+			text = heard.Description
+		}
+		entry.Text = strings.TrimSpace(entry.Text + "\n" + text)
+	}
+
+	situation := incident.Situation
+	if update.vitals != nil {
+		if situation.Vitals == nil {
+			situation.Vitals = &models.Vitals{}
+		}
+		situation.Vitals.Merge(update.vitals)
+	}
+	if update.location != nil {
+		situation.Location = update.location
+	}
+
+	if reason, ok := ruleBasedTriage(ctx); ok {
+		// Keyword rules see only fragments of an incident, so without the model they may raise the code but
+		// never replace it
+		markRuleBased(situation, reason)
+	} else {
+		// The whole incident is reassessed so the update is weighed against what was already known
+		assessed, err := h.textProcessor.ProcessEmergencyText(ctx, updateReport(incident, &entry, situation.Vitals))
+		if err != nil {
+			return &stageError{operation: "Failed to process text", err: err}
+		}
+		mergeReassessment(situation, assessed)
+	}
+	situation.SourceText = strings.TrimSpace(incident.SourceText + "\n" + entry.Text)
+
+	reason := fmt.Sprintf("update %d", entry.Version)
+	response, added, err := h.coordinator.ProcessUpdate(ctx, situation, incident.Response, reason)
+	if err != nil {
+		return err
+	}
+
+	entry.Code = situation.Code
+	for _, toolResponse := range added {
+		entry.ToolsRun = append(entry.ToolsRun, toolResponse.ToolName)
+	}
+	log.Printf("Emergency %s updated to version %d: %s -> %s", incident.ID, entry.Version, entry.PreviousCode, entry.Code)
+
+	incident.Version = entry.Version
+	incident.Code = situation.Code
+	incident.UpdatedAt = entry.ReceivedAt
+	incident.Response = response
+	incident.SourceText = situation.SourceText
+	incident.Updates = append(incident.Updates, entry)
+	if err := h.store.Save(incident); err != nil {
+		return fmt.Errorf("failed to save emergency: %w", err)
+	}
+	return nil
+}
+
+// updateReport describes an incident and its updates in order, newest last, for re-triage
+func updateReport(incident *Incident, latest *IncidentUpdate, vitals *models.Vitals) string {
+	var report strings.Builder
+
+	initial := incident.SourceText
+	if initial == "" {
+		initial = incident.Situation.Description
+	}
+	fmt.Fprintf(&report, "Initial report (%s): %s\n", incident.CreatedAt.Format(time.RFC3339), initial)
+
+	for _, update := range append(incident.Updates, *latest) {
+		if update.Text == "" && update.Vitals == nil {
+			continue
+		}
+		fmt.Fprintf(&report, "\nUpdate %d (%s):", update.Version, update.ReceivedAt.Format(time.RFC3339))
+		if update.Text != "" {
+			fmt.Fprintf(&report, " %s", update.Text)
+		}
+		if update.Vitals != nil {
+			fmt.Fprintf(&report, " [vitals: %s]", update.Vitals)
+		}
+		report.WriteString("\n")
+	}
+
+	if vitals != nil {
+		fmt.Fprintf(&report, "\nLatest vitals: %s\n", vitals)
+	}
+	return report.String()
+}
+
+// mergeReassessment takes the new triage from a reassessment while keeping the incident's identity,
+// patient, transcript and code history
+func mergeReassessment(situation *models.EmergencySituation, assessed *models.EmergencySituation) {
+	situation.SetTriageCode(assessed.Code, assessed.Confidence)
+	situation.Description = assessed.Description
+	if assessed.EmotionalMarkers != nil {
+		situation.EmotionalMarkers = assessed.EmotionalMarkers
+	}
+
+	seen := make(map[string]bool, len(situation.Keywords))
+	for _, keyword := range situation.Keywords {
+		seen[keyword] = true
+	}
+	for _, keyword := range assessed.Keywords {
+		if !seen[keyword] {
+			situation.Keywords = append(situation.Keywords, keyword)
+			seen[keyword] = true
+		}
+	}
+
+	// A floor applied to an earlier version no longer holds once the incident is reassessed
+	if situation.Metadata == nil {
+		situation.Metadata = make(map[string]string)
+	}
+	delete(situation.Metadata, "vitals_floor_applied")
+	for key, value := range assessed.Metadata {
+		situation.Metadata[key] = value
+	}
+}
+
+// incidentLocks serializes work on the same incident
+type incidentLocks struct {
+	mu    sync.Mutex
+	locks map[string]*incidentLock
+}
+
+// incidentLock is a lock on one incident and the number of callers holding or waiting for it
+type incidentLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock blocks until the incident is free and returns the function that releases it
+func (l *incidentLocks) lock(id string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*incidentLock)
+	}
+	entry, ok := l.locks[id]
+	if !ok {
+		entry = &incidentLock{}
+		l.locks[id] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent/internal/models"
+)
+
+// incidentPath is the URL prefix of individual incidents
+const incidentPath = "/api/v1/emergency/"
+
+const (
+	// defaultIncidentLimit is the page size when a listing does not set one
+	defaultIncidentLimit = 50
+
+	// maxIncidentLimit is the largest page a listing returns
+	maxIncidentLimit = 200
+
+	// defaultRadiusKm is the search radius around lat and lon when radius_km is not set
+	defaultRadiusKm = 10.0
+)
+
+// IncidentsHandler exposes stored emergencies and accepts updates to them
+type IncidentsHandler struct {
+	store          IncidentStore
+	textProcessor  *TextProcessor
+	audioProcessor *AudioProcessor
+	coordinator    *EmergencyCoordinator
+	maxAudioSize   int64
+	locks          incidentLocks
+}
+
+// NewIncidentsHandler creates a new incidents API handler
+func NewIncidentsHandler(store IncidentStore, textProcessor *TextProcessor, audioProcessor *AudioProcessor, coordinator *EmergencyCoordinator, maxAudioSize int64) *IncidentsHandler {
+	if maxAudioSize == 0 {
+		maxAudioSize = 10 * 1024 * 1024 // Default to 10MB
+	}
+
+	return &IncidentsHandler{
+		store:          store,
+		textProcessor:  textProcessor,
+		audioProcessor: audioProcessor,
+		coordinator:    coordinator,
+		maxAudioSize:   maxAudioSize,
+	}
+}
+
+// RegisterRoutes registers the incidents API routes
+func (h *IncidentsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(incidentPath, h.HandleIncident)
+	mux.HandleFunc("/api/v1/emergencies", h.HandleIncidents)
+}
+
+// IncidentListResponse is a page of incidents
+type IncidentListResponse struct {
+	Incidents []IncidentSummary `json:"incidents"`
+	Total     int               `json:"total"`
+	Limit     int               `json:"limit"`
+	Offset    int               `json:"offset"`
+}
+
+// HandleIncident returns a stored emergency with its situation, transcript, tool responses and summary.
+// Updates are posted to {id}/updates.
+func (h *IncidentsHandler) HandleIncident(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, incidentPath)
+	if before, ok := strings.CutSuffix(id, "/updates"); ok && before != "" && !strings.Contains(before, "/") {
+		h.HandleIncidentUpdate(w, r, before)
+		return
+	}
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Emergency not found")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	incident, err := h.store.Get(id)
+	if err == nil && !canAccessIncident(r.Context(), incident) {
+		err = ErrIncidentNotFound
+	}
+	if errors.Is(err, ErrIncidentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Emergency not found")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, "Failed to load emergency", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(incident); err != nil {
+		log.Printf("Failed to encode incident: %v", err)
+	}
+}
+
+// HandleIncidents lists stored emergencies, newest first, filtered by code, time range and distance from a point
+func (h *IncidentsHandler) HandleIncidents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	filter, err := parseIncidentFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	incidents, total, err := h.store.List(filter)
+	if err != nil {
+		writeServiceError(w, r, "Failed to list emergencies", err)
+		return
+	}
+	if incidents == nil {
+		incidents = []IncidentSummary{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(IncidentListResponse{
+		Incidents: incidents,
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	}); err != nil {
+		log.Printf("Failed to encode incidents: %v", err)
+	}
+}
+
+// parseIncidentFilter reads a listing's filters: code (comma-separated), since and until (RFC 3339),
+// lat, lon and radius_km, limit and offset
+func parseIncidentFilter(query url.Values) (IncidentFilter, error) {
+	filter := IncidentFilter{Limit: defaultIncidentLimit}
+
+	for _, value := range query["code"] {
+		for _, code := range strings.Split(value, ",") {
+			switch triageCode := models.TriageCode(strings.ToUpper(strings.TrimSpace(code))); triageCode {
+			case models.CodeRed, models.CodeYellow, models.CodeGreen, models.CodeUnknown:
+				filter.Codes = append(filter.Codes, triageCode)
+			default:
+				return filter, fmt.Errorf("code must be RED, YELLOW, GREEN or UNKNOWN")
+			}
+		}
+	}
+
+	var err error
+	if filter.Since, err = queryTime(query, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = queryTime(query, "until"); err != nil {
+		return filter, err
+	}
+
+	lat, lon := query.Get("lat"), query.Get("lon")
+	if lat != "" || lon != "" {
+		near := &models.Location{}
+		if near.Latitude, err = strconv.ParseFloat(lat, 64); err != nil || near.Latitude < -90 || near.Latitude > 90 {
+			return filter, fmt.Errorf("lat and lon must both be set; lat must be between -90 and 90")
+		}
+		if near.Longitude, err = strconv.ParseFloat(lon, 64); err != nil || near.Longitude < -180 || near.Longitude > 180 {
+			return filter, fmt.Errorf("lat and lon must both be set; lon must be between -180 and 180")
+		}
+		filter.Near = near
+
+		filter.RadiusKm = defaultRadiusKm
+		if radius := query.Get("radius_km"); radius != "" {
+			if filter.RadiusKm, err = strconv.ParseFloat(radius, 64); err != nil || filter.RadiusKm <= 0 {
+				return filter, fmt.Errorf("radius_km must be a positive number")
+			}
+		}
+	}
+
+	if filter.Limit, err = queryInt(query, "limit", defaultIncidentLimit); err != nil {
+		return filter, err
+	}
+	if filter.Limit < 1 || filter.Limit > maxIncidentLimit {
+		return filter, fmt.Errorf("limit must be between 1 and %d", maxIncidentLimit)
+	}
+	if filter.Offset, err = queryInt(query, "offset", 0); err != nil {
+		return filter, err
+	}
+	if filter.Offset < 0 {
+		return filter, fmt.Errorf("offset must not be negative")
+	}
+
+	return filter, nil
+}
+
+// queryTime parses an RFC 3339 query parameter, returning the zero time when it is absent
+func queryTime(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time such as 2024-01-02T15:04:05Z", name)
+	}
+	return t, nil
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"agent/internal/models"
+)
+
+// ErrQueueFull is returned when a job cannot be queued because every slot is taken
+var ErrQueueFull = errors.New("job queue is full")
+
+// JobStatus is the lifecycle state of an asynchronous job
+type JobStatus string
+
+const (
+	// JobQueued means the job is waiting for a worker
+	JobQueued JobStatus = "queued"
+
+	// JobRunning means a worker is processing the job
+	JobRunning JobStatus = "running"
+
+	// JobSucceeded means the job finished and its result is available
+	JobSucceeded JobStatus = "succeeded"
+
+	// JobFailed means the job finished with an error
+	JobFailed JobStatus = "failed"
+
+	// JobCancelled means the job was cancelled before it finished
+	JobCancelled JobStatus = "cancelled"
+)
+
+// finished reports whether the job has stopped and will not change again
+func (s JobStatus) finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// JobFunc performs the work of a job; it may report a preliminary triage through partial
+type JobFunc func(ctx context.Context, partial func(*models.EmergencySituation)) (*EmergencyResponse, error)
+
+// Job is an asynchronous emergency triage request
+type Job struct {
+	ID         string
+	Status     JobStatus
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Partial    *models.EmergencySituation
+	Result     *EmergencyResponse
+	Error      *ErrorDetail
+
+	requestID string
+	owner     string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	run       JobFunc
+}
+
+// JobView is the JSON representation of a job
+type JobView struct {
+	JobID      string                     `json:"job_id"`
+	Status     JobStatus                  `json:"status"`
+	CreatedAt  string                     `json:"created_at"`
+	StartedAt  string                     `json:"started_at,omitempty"`
+	FinishedAt string                     `json:"finished_at,omitempty"`
+	Partial    *models.EmergencySituation `json:"partial,omitempty"`
+	Result     *EmergencyResponse         `json:"result,omitempty"`
+	Error      *ErrorDetail               `json:"error,omitempty"`
+}
+
+// JobQueueConfig contains configuration for the job queue
+type JobQueueConfig struct {
+	Workers   int           // Jobs processed at once
+	QueueSize int           // Jobs that may wait for a worker
+	Timeout   time.Duration // Maximum run time of one job
+	ResultTTL time.Duration // How long finished jobs can be fetched
+}
+
+// JobQueue runs jobs on a bounded pool of workers and keeps their results until they expire
+type JobQueue struct {
+	config  JobQueueConfig
+	pending chan *Job
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobQueue creates a job queue and starts its workers
+func NewJobQueue(config JobQueueConfig) *JobQueue {
+	if config.Workers == 0 {
+		config.Workers = 4
+	}
+
+	if config.QueueSize == 0 {
+		config.QueueSize = 32
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Minute
+	}
+
+	if config.ResultTTL == 0 {
+		config.ResultTTL = 15 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &JobQueue{
+		config:  config,
+		pending: make(chan *Job, config.QueueSize),
+		jobs:    make(map[string]*Job),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues a job. ctx supplies request-scoped values such as the request ID; its cancellation is ignored
+// so the job outlives the request that created it.
+func (q *JobQueue) Submit(ctx context.Context, run JobFunc) (JobView, error) {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	// Closing the queue cancels the job too
+	stop := context.AfterFunc(q.ctx, cancel)
+
+	job := &Job{
+		ID:        newRandomID("job"),
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+		requestID: RequestIDFromContext(ctx),
+		owner:     clientPrincipal(ctx),
+		ctx:       jobCtx,
+		cancel:    func() { stop(); cancel() },
+		run:       run,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pruneLocked()
+
+	select {
+	case q.pending <- job:
+	default:
+		job.cancel()
+		return JobView{}, ErrQueueFull
+	}
+	q.jobs[job.ID] = job
+	return job.viewLocked(), nil
+}
+
+// Get returns a job by ID if it exists, belongs to the request's client and its result has not expired
+func (q *JobQueue) Get(ctx context.Context, id string) (JobView, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pruneLocked()
+
+	job, ok := q.jobs[id]
+	if !ok || !isOwner(ctx, job.owner) {
+		return JobView{}, false
+	}
+	return job.viewLocked(), true
+}
+
+// Cancel stops a queued or running job belonging to the request's client. Finished jobs are left unchanged.
+func (q *JobQueue) Cancel(ctx context.Context, id string) (JobView, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok || !isOwner(ctx, job.owner) {
+		return JobView{}, false
+	}
+
+	if !job.Status.finished() {
+		job.cancel()
+		job.Status = JobCancelled
+		job.FinishedAt = time.Now()
+	}
+	return job.viewLocked(), true
+}
+
+// Close cancels every unfinished job and waits for the workers to stop
+func (q *JobQueue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// work runs queued jobs until the queue is closed
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case job := <-q.pending:
+			q.runJob(job)
+		}
+	}
+}
+
+// runJob runs one job and records its outcome
+func (q *JobQueue) runJob(job *Job) {
+	q.mu.Lock()
+	if job.Status != JobQueued {
+		// Cancelled while waiting for a worker
+		q.mu.Unlock()
+		return
+	}
+	job.Status = JobRunning
+	job.StartedAt = time.Now()
+	q.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(job.ctx, q.config.Timeout)
+	defer cancel()
+	defer job.cancel()
+
+	result, err := job.run(ctx, func(partial *models.EmergencySituation) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if job.Status == JobRunning {
+			job.Partial = partial
+		}
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job.Status != JobRunning {
+		// Cancelled while running; keep the cancelled status
+		return
+	}
+
+	job.FinishedAt = time.Now()
+	if err != nil {
+		detail := serviceError("Failed to process emergency", err, job.requestID).detail(job.requestID)
+		job.Status = JobFailed
+		job.Error = &detail
+		return
+	}
+
+	job.Status = JobSucceeded
+	job.Result = result
+}
+
+// pruneLocked removes finished jobs older than the result TTL; the caller must hold q.mu
+func (q *JobQueue) pruneLocked() {
+	for id, job := range q.jobs {
+		if job.Status.finished() && time.Since(job.FinishedAt) > q.config.ResultTTL {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// viewLocked returns a snapshot of the job; the caller must hold the queue's lock
+func (j *Job) viewLocked() JobView {
+	view := JobView{
+		JobID:     j.ID,
+		Status:    j.Status,
+		CreatedAt: j.CreatedAt.Format(time.RFC3339),
+		Partial:   j.Partial,
+		Result:    j.Result,
+		Error:     j.Error,
+	}
+	if !j.StartedAt.IsZero() {
+		view.StartedAt = j.StartedAt.Format(time.RFC3339)
+	}
+	if !j.FinishedAt.IsZero() {
+		view.FinishedAt = j.FinishedAt.Format(time.RFC3339)
+	}
+	if j.Status.finished() {
+		// The preliminary triage is superseded once the job ends
+		view.Partial = nil
+	}
+	return view
+}
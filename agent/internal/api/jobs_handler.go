@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// jobsPath is the URL prefix of individual jobs
+const jobsPath = "/api/v1/jobs/"
+
+// JobsHandler exposes the status and results of asynchronous emergency jobs
+type JobsHandler struct {
+	jobs *JobQueue
+}
+
+// NewJobsHandler creates a new jobs API handler
+func NewJobsHandler(jobs *JobQueue) *JobsHandler {
+	return &JobsHandler{jobs: jobs}
+}
+
+// RegisterRoutes registers the jobs API routes
+func (h *JobsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(jobsPath, h.HandleJob)
+}
+
+// HandleJob returns a job's status and result on GET and cancels it on DELETE
+func (h *JobsHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, jobsPath)
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Job not found")
+		return
+	}
+
+	var job JobView
+	var ok bool
+	switch r.Method {
+	case http.MethodGet:
+		job, ok = h.jobs.Get(r.Context(), id)
+	case http.MethodDelete:
+		job, ok = h.jobs.Cancel(r.Context(), id)
+	default:
+		methodNotAllowed(w, r)
+		return
+	}
+	if !ok {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Job not found or expired")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode job: %v", err)
+	}
+}
@@ -0,0 +1,542 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"agent/internal/ai"
+	"agent/internal/audio"
+	"agent/internal/models"
+	"agent/internal/websocket"
+)
+
+const (
+	// liveIdleTimeout is how long a live call may go without a message before it is treated as ended
+	liveIdleTimeout = 30 * time.Second
+
+	// liveTick is how often a live call checks for audio ready to transcribe
+	liveTick = time.Second
+
+	// liveMessageLimit is the largest WebSocket message a live call accepts
+	liveMessageLimit = 1 << 20
+)
+
+// errLiveLimit ends a live call that reaches the maximum recording length or size
+var errLiveLimit = errors.New("live call reached the recording limit")
+
+// HandleLiveEmergency accepts a call's audio over a WebSocket while it is still being recorded. The audio is
+// transcribed in segments, the growing transcript is re-triaged, and progress events including triage code
+// changes are pushed back on the socket. A RED call is coordinated straight away rather than at hang-up.
+func (h *EmergencyHandler) HandleLiveEmergency(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method, as required for a WebSocket upgrade
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	live, err := newLiveAudio(query, h.audioProcessor.config.LiveSegmentLength)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidAudio, err.Error())
+		return
+	}
+
+	var location *models.Location
+	if locationData := query.Get("location"); locationData != "" {
+		if err := json.Unmarshal([]byte(locationData), &location); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Location must be a JSON object with latitude and longitude")
+			return
+		}
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		if errors.Is(err, websocket.ErrNotWebSocket) {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Expected a WebSocket upgrade request")
+			return
+		}
+		writeServiceError(w, r, "Failed to start live call", err)
+		return
+	}
+	conn.SetReadLimit(liveMessageLimit)
+
+	log.Printf("Live emergency call %s started from %s", RequestIDFromContext(r.Context()), conn.RemoteAddr())
+
+	session := &liveSession{
+		handler:   h,
+		conn:      conn,
+		requestID: RequestIDFromContext(r.Context()),
+		audio:     live,
+		location:  location,
+	}
+	session.run(r.Context())
+}
+
+// liveControl is a JSON control message from the client of a live call
+type liveControl struct {
+	Type     string           `json:"type"` // "location" or "end"
+	Location *models.Location `json:"location,omitempty"`
+}
+
+// liveTriageChange is the data of a triage_changed event
+type liveTriageChange struct {
+	Previous models.TriageCode `json:"previous_code"`
+	triageProgress
+}
+
+// liveSegment is audio from a live call that is ready to transcribe
+type liveSegment struct {
+	audio   io.Reader
+	info    *audio.Info
+	start   time.Duration
+	end     time.Duration
+	replace bool // the segment holds the whole call so far, so its transcript replaces the previous one
+}
+
+// liveAudio buffers the audio of a live call and yields segments ready for transcription
+type liveAudio interface {
+	write(data []byte) error
+	duration() time.Duration
+	next(final bool) (liveSegment, bool)
+}
+
+// newLiveAudio creates the buffer for the format named in a live call's query: raw PCM, described by
+// sample_rate, channels and bits, or a compressed stream such as Opus in Ogg or WebM
+func newLiveAudio(query url.Values, segmentLength time.Duration) (liveAudio, error) {
+	switch format := query.Get("format"); format {
+	case "", "pcm":
+		sampleRate, err := queryInt(query, "sample_rate", 16000)
+		if err != nil {
+			return nil, err
+		}
+		channels, err := queryInt(query, "channels", 1)
+		if err != nil {
+			return nil, err
+		}
+		bits, err := queryInt(query, "bits", 16)
+		if err != nil {
+			return nil, err
+		}
+		pcm, err := audio.NewLivePCM(sampleRate, channels, bits)
+		if err != nil {
+			return nil, err
+		}
+		return &livePCMAudio{pcm: pcm, length: segmentLength}, nil
+
+	case "opus", "ogg", "webm":
+		return &liveContainerAudio{interval: segmentLength}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported live audio format %q; use pcm, opus, ogg or webm", format)
+	}
+}
+
+// queryInt parses an integer query parameter, returning fallback when it is absent
+func queryInt(query url.Values, name string, fallback int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// livePCMAudio cuts raw PCM into segments at pauses so each part of the call is transcribed once
+type livePCMAudio struct {
+	pcm    *audio.LivePCM
+	length time.Duration
+}
+
+func (a *livePCMAudio) write(data []byte) error {
+	_, err := a.pcm.Write(data)
+	return err
+}
+
+func (a *livePCMAudio) duration() time.Duration {
+	return a.pcm.Duration()
+}
+
+func (a *livePCMAudio) next(final bool) (liveSegment, bool) {
+	chunk, ok := a.pcm.Next(a.length, final)
+	if !ok {
+		return liveSegment{}, false
+	}
+	return liveSegment{audio: chunk.Reader(), info: a.pcm.Info(), start: chunk.Start, end: chunk.End}, true
+}
+
+// liveContainerAudio collects a compressed stream. The container cannot be cut without decoding, so the
+// whole call so far is transcribed again once per interval.
+type liveContainerAudio struct {
+	data        []byte
+	format      audio.Format
+	interval    time.Duration
+	started     time.Time
+	transcribed int
+	lastPass    time.Time
+}
+
+func (a *liveContainerAudio) write(data []byte) error {
+	if len(a.data) == 0 {
+		a.started = time.Now()
+		a.lastPass = a.started
+	}
+	a.data = append(a.data, data...)
+
+	// Identify the container once enough of its header has arrived
+	if a.format.Container == "" {
+		format, err := audio.Detect(a.data[:min(len(a.data), 64)])
+		if err == nil {
+			a.format = format
+		} else if len(a.data) >= 64 {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *liveContainerAudio) duration() time.Duration {
+	if a.started.IsZero() {
+		return 0
+	}
+	return time.Since(a.started)
+}
+
+func (a *liveContainerAudio) next(final bool) (liveSegment, bool) {
+	if a.format.Container == "" || len(a.data) == a.transcribed {
+		return liveSegment{}, false
+	}
+	if !final && time.Since(a.lastPass) < a.interval {
+		return liveSegment{}, false
+	}
+
+	// Later writes only append, so the slice stays valid while it is transcribed
+	data := a.data[:len(a.data)]
+	a.transcribed = len(data)
+	a.lastPass = time.Now()
+	return liveSegment{
+		audio:   bytes.NewReader(data),
+		info:    &audio.Info{Format: a.format},
+		end:     a.duration(),
+		replace: true,
+	}, true
+}
+
+// liveSession is one live call
+type liveSession struct {
+	handler   *EmergencyHandler
+	conn      *websocket.Conn
+	requestID string
+
+	// mu guards the audio and location, which the read loop updates while segments are processed
+	mu       sync.Mutex
+	audio    liveAudio
+	location *models.Location
+	received int64
+
+	// The remaining fields belong to the goroutine running the session
+	transcript *models.Transcript
+	situation  *models.EmergencySituation
+	lastErr    error
+	started    bool // whether audio_received has been reported
+
+	dispatched  chan struct{} // closed when an early dispatch finishes; nil until one starts
+	response    *EmergencyResponse
+	dispatchErr error
+
+	// dispatchedSituation is the coordinator's copy of the situation dispatched mid-call; read it only once
+	// dispatched is closed
+	dispatchedSituation *models.EmergencySituation
+}
+
+// run processes the call until the caller ends it, then coordinates the final triage
+func (s *liveSession) run(ctx context.Context) {
+	processor := s.handler.audioProcessor
+	maxLength := time.Duration(processor.config.MaxAudioLength) * time.Second
+
+	ctx, cancel := context.WithTimeout(ctx, maxLength+processor.config.Timeout)
+	defer cancel()
+	ctx = WithProgress(ctx, s.send)
+
+	ended := make(chan error, 1)
+	go func() {
+		ended <- s.read(maxLength)
+	}()
+
+	ticker := time.NewTicker(liveTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.advance(ctx, false)
+
+		case err := <-ended:
+			if errors.Is(err, audio.ErrUnsupportedFormat) {
+				s.fail(ctx, fmt.Errorf("invalid audio: %w", err))
+				s.conn.Close(websocket.CloseUnsupportedData, "unsupported audio format")
+				return
+			}
+			// A caller who hangs up or drops out is still triaged and coordinated
+			if err != nil {
+				log.Printf("Live emergency call %s ended: %v", s.requestID, err)
+			}
+			s.advance(ctx, true)
+			s.finish(ctx)
+			return
+
+		case <-ctx.Done():
+			s.fail(ctx, ctx.Err())
+			s.conn.Close(websocket.CloseInternalError, "processing timed out")
+			return
+		}
+	}
+}
+
+// read receives audio and control messages until the call ends. A nil error means the caller sent an end message.
+func (s *liveSession) read(maxLength time.Duration) error {
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(liveIdleTimeout))
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if messageType == websocket.BinaryMessage {
+			s.mu.Lock()
+			s.received += int64(len(data))
+			err := s.audio.write(data)
+			full := s.received > s.handler.maxAudioSize || s.audio.duration() > maxLength
+			s.mu.Unlock()
+
+			if err != nil {
+				return err
+			}
+			if full {
+				return errLiveLimit
+			}
+			continue
+		}
+
+		var control liveControl
+		if err := json.Unmarshal(data, &control); err != nil {
+			fmt.Printf("Warning: ignoring malformed live call control message: %v\n", err)
+			continue
+		}
+		switch control.Type {
+		case "end":
+			return nil
+		case "location":
+			s.mu.Lock()
+			s.location = control.Location
+			s.mu.Unlock()
+		default:
+			fmt.Printf("Warning: ignoring unknown live call control message %q\n", control.Type)
+		}
+	}
+}
+
+// advance transcribes the audio that is ready and, if the transcript grew, triages the call again
+func (s *liveSession) advance(ctx context.Context, final bool) {
+	processor := s.handler.audioProcessor
+
+	grew := false
+	for {
+		s.mu.Lock()
+		segment, ok := s.audio.next(final)
+		s.mu.Unlock()
+		if !ok {
+			break
+		}
+		if !s.started {
+			s.started = true
+			reportProgress(ctx, EventAudioReceived, map[string]interface{}{
+				"format":           segment.info.Format.Container,
+				"duration_seconds": segment.end.Seconds(),
+			})
+		}
+
+		// Latency matters more than depth while the caller is talking
+		model := processor.modelProvider.Route(ctx, ai.AudioRequest, ai.TierFast)
+		transcript, err := processor.transcribe(ctx, model, segment.audio, segment.info, segment.start, segment.end)
+		if err != nil {
+			fmt.Printf("Warning: transcription of live audio failed: %v\n", err)
+			s.lastErr = err
+			if segment.replace {
+				// The next pass covers this audio again
+				continue
+			}
+			transcript = &models.Transcript{
+				Diarized: true,
+				Segments: []models.TranscriptSegment{{
+					StartSeconds: segment.start.Seconds(),
+					EndSeconds:   segment.end.Seconds(),
+					Speaker:      models.SpeakerUnknown,
+					Text:         "[transcription unavailable]",
+				}},
+			}
+		}
+		s.addTranscript(transcript, segment.replace)
+		grew = true
+	}
+	if !grew {
+		return
+	}
+	reportProgress(ctx, EventTranscriptReady, s.transcript)
+
+	// The situation keeps its own copy because the live transcript goes on growing
+	transcript := &models.Transcript{Segments: slices.Clone(s.transcript.Segments), Diarized: s.transcript.Diarized}
+	situation, err := processor.triageTranscript(ctx, transcript)
+	if err != nil {
+		fmt.Printf("Warning: triage of live call failed: %v\n", err)
+		s.lastErr = err
+		return
+	}
+	situation.Transcript.LinkKeywords(situation.Keywords)
+	situation.Metadata["live"] = "true"
+	situation.Metadata["audio_duration_seconds"] = fmt.Sprintf("%.1f", transcript.Segments[len(transcript.Segments)-1].EndSeconds)
+
+	// Every triage of the call describes the same incident
+	previous := models.CodeUnknown
+	if s.situation != nil {
+		previous = s.situation.Code
+		situation.ID = s.situation.ID
+		situation.Timestamp = s.situation.Timestamp
+	}
+	s.situation = situation
+	if situation.Code != previous {
+		reportProgress(ctx, EventTriageChanged, liveTriageChange{Previous: previous, triageProgress: newTriageProgress(situation)})
+	}
+
+	if situation.Code == models.CodeRed && s.dispatched == nil {
+		s.dispatch(ctx, situation)
+	}
+}
+
+// addTranscript appends a segment's transcript to the call's, or replaces it for whole-call passes
+func (s *liveSession) addTranscript(transcript *models.Transcript, replace bool) {
+	if replace || s.transcript == nil {
+		s.transcript = &models.Transcript{Diarized: transcript.Diarized}
+	} else {
+		s.transcript.Diarized = s.transcript.Diarized && transcript.Diarized
+	}
+	s.transcript.Segments = append(s.transcript.Segments, transcript.Segments...)
+	for i := range s.transcript.Segments {
+		s.transcript.Segments[i].Index = i
+	}
+}
+
+// dispatch coordinates a RED call while the caller is still talking
+func (s *liveSession) dispatch(ctx context.Context, situation *models.EmergencySituation) {
+	s.mu.Lock()
+	situation.Location = s.location
+	s.mu.Unlock()
+	situation.Metadata["dispatched_mid_call"] = "true"
+
+	log.Printf("Live emergency call %s triaged RED; coordinating before the call ends", s.requestID)
+
+	// The coordinator works on its own copy while the call goes on being triaged
+	dispatched := *situation
+	dispatched.Metadata = maps.Clone(situation.Metadata)
+	s.dispatchedSituation = &dispatched
+
+	done := make(chan struct{})
+	s.dispatched = done
+	go func() {
+		defer close(done)
+		s.response, s.dispatchErr = s.handler.coordinator.ProcessEmergency(ctx, &dispatched)
+	}()
+}
+
+// finish coordinates the call's final triage, reports the result and closes the socket
+func (s *liveSession) finish(ctx context.Context) {
+	var response *EmergencyResponse
+	var err error
+
+	switch {
+	case s.dispatched != nil:
+		<-s.dispatched
+		response, err = s.response, s.dispatchErr
+		if err == nil {
+			response, err = s.updateDispatched(ctx)
+		}
+	case s.situation != nil:
+		s.mu.Lock()
+		s.situation.Location = s.location
+		s.mu.Unlock()
+		response, err = s.handler.coordinator.ProcessEmergency(ctx, s.situation)
+	case s.lastErr != nil:
+		err = s.lastErr
+	default:
+		err = fmt.Errorf("invalid audio: %w: no audio received on live call", audio.ErrEmpty)
+	}
+
+	if err != nil {
+		s.fail(ctx, err)
+		s.conn.Close(websocket.CloseInternalError, "processing failed")
+		return
+	}
+
+	reportProgress(ctx, EventCompleted, response)
+	s.conn.Close(websocket.CloseNormal, "")
+	log.Printf("Live emergency call %s completed with code %s", s.requestID, response.Code)
+}
+
+// updateDispatched coordinates the call's final triage as an update to the response dispatched mid-call, so
+// tools that already ran are not repeated. The incident keeps its reporter, patient and code history, and an
+// ambulance already on its way is not recalled by a lower triage later in the call.
+func (s *liveSession) updateDispatched(ctx context.Context) (*EmergencyResponse, error) {
+	dispatched, final := s.dispatchedSituation, s.situation
+
+	s.mu.Lock()
+	final.Location = s.location
+	s.mu.Unlock()
+
+	final.SubmittedBy = dispatched.SubmittedBy
+	final.CodeHistory = slices.Clone(dispatched.CodeHistory)
+	if final.PatientInfo == nil {
+		final.PatientInfo = dispatched.PatientInfo
+	}
+	final.Metadata["dispatched_mid_call"] = "true"
+	if final.Code != models.CodeRed {
+		final.Metadata["reassessed_code"] = string(final.Code)
+		final.SetTriageCode(dispatched.Code, dispatched.Confidence)
+	}
+
+	response, _, err := s.handler.coordinator.ProcessUpdate(ctx, final, s.response, "live call ended")
+	if err != nil {
+		return nil, err
+	}
+	s.handler.coordinator.saveIncident(final, response)
+	return response, nil
+}
+
+// fail reports err to the caller as the final event of the call
+func (s *liveSession) fail(ctx context.Context, err error) {
+	reportProgress(ctx, EventError, serviceError("Failed to process live call", err, s.requestID).detail(s.requestID))
+}
+
+// send writes a progress event to the socket. Write errors are ignored because a caller who hung up
+// is still coordinated.
+func (s *liveSession) send(event ProgressEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode live call event: %v", err)
+		return
+	}
+	s.conn.WriteMessage(websocket.TextMessage, data)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"agent/internal/metrics"
+	"agent/internal/models"
+	"agent/internal/tools"
+)
+
+// prometheusPath is where Prometheus scrapes metrics
+const prometheusPath = "/metrics"
+
+// Stages of handling an emergency whose latency is recorded
+const (
+	StageAudio        = "audio"
+	StageText         = "text"
+	StageImage        = "image"
+	StageConversation = "conversation"
+	StageCoordination = "coordination"
+	StageTools        = "tools"
+	StageSummary      = "summary"
+)
+
+var (
+	stageDuration = metrics.Default.HistogramVec("emergency_stage_duration_seconds",
+		"Time spent in each stage of handling an emergency", nil, "stage")
+	triageCodes = metrics.Default.CounterVec("emergency_triage_codes_total",
+		"Coordinated emergency responses by triage code and whether the model or the rules decided it", "code", "mode")
+	toolExecutions = metrics.Default.CounterVec("tool_executions_total",
+		"Tool executions by tool and outcome: success, failure or error", "tool", "outcome")
+	toolDuration = metrics.Default.HistogramVec("tool_execution_duration_seconds",
+		"Latency of tool executions by tool", nil, "tool")
+)
+
+// observeStage records how long a stage took since start
+func observeStage(stage string, start time.Time) {
+	stageDuration.With(stage).ObserveSince(start)
+}
+
+// countTriage records the code an emergency was triaged to
+func countTriage(ctx context.Context, code models.TriageCode) {
+	mode := "model"
+	if _, ok := ruleBasedTriage(ctx); ok {
+		mode = "rule_based"
+	}
+	triageCodes.With(string(code), mode).Inc()
+}
+
+// executeTool runs a tool and records its latency and outcome
+func executeTool(ctx context.Context, tool tools.EmergencyTool, situation *models.EmergencySituation) (*tools.ToolResponse, error) {
+	name := tool.Schema().Name
+	start := time.Now()
+	response, err := tool.Execute(ctx, situation)
+	toolDuration.With(name).ObserveSince(start)
+
+	switch {
+	case err != nil:
+		toolExecutions.With(name, "error").Inc()
+	case response == nil || !response.Success:
+		toolExecutions.With(name, "failure").Inc()
+	default:
+		toolExecutions.With(name, "success").Inc()
+	}
+	return response, err
+}
+
+// MetricsHandler exposes operational metrics such as hedge rates and wins
+type MetricsHandler struct {
+	registry *metrics.Registry
+}
+
+// NewMetricsHandler creates a new metrics API handler
+func NewMetricsHandler(registry *metrics.Registry) *MetricsHandler {
+	return &MetricsHandler{registry: registry}
+}
+
+// RegisterRoutes registers the metrics API routes
+func (h *MetricsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/metrics", h.HandleMetrics)
+	mux.HandleFunc(prometheusPath, h.HandlePrometheus)
+}
+
+// HandleMetrics returns a JSON snapshot of every registered metric
+func (h *MetricsHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"metrics": h.registry.Snapshot(),
+	}); err != nil {
+		log.Printf("Failed to encode metrics: %v", err)
+	}
+}
+
+// HandlePrometheus returns every registered metric in the Prometheus text format
+func (h *MetricsHandler) HandlePrometheus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	if err := h.registry.WritePrometheus(w); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
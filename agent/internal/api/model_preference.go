@@ -0,0 +1,38 @@
+package api
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+
+	"agent/internal/ai"
+)
+
+const (
+	// ModelPreferenceHeader names the model, or model type, a trusted caller wants the request routed to
+	ModelPreferenceHeader = "X-Model-Preference"
+
+	// ModelOverrideTokenHeader carries the shared secret that marks a caller as trusted to choose a model
+	ModelOverrideTokenHeader = "X-Model-Override-Token"
+)
+
+// ModelPreferenceMiddleware honours the model preference header for callers presenting the override token.
+// Untrusted preferences are ignored rather than rejected so an emergency request is never refused over it.
+func ModelPreferenceMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		preference := r.Header.Get(ModelPreferenceHeader)
+		if preference == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presented := r.Header.Get(ModelOverrideTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			log.Printf("Ignoring %s from untrusted caller %s", ModelPreferenceHeader, r.RemoteAddr)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ai.WithModelPreference(r.Context(), preference)))
+	})
+}
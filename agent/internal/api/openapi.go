@@ -0,0 +1,438 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"agent/internal/ai"
+	"agent/internal/auth"
+	"agent/internal/metrics"
+	"agent/internal/models"
+	"agent/internal/openapi"
+)
+
+// openAPIPath serves the API's OpenAPI document
+const openAPIPath = "/api/v1/openapi.json"
+
+// maxValidatedBody bounds the JSON bodies RequestValidationMiddleware reads; larger ones are left to the handlers
+const maxValidatedBody = 32 * 1024 * 1024
+
+// BuildOpenAPI describes every route of the API. Schemas are generated from the request and response types,
+// and each operation's access comes from the same table AuthMiddleware enforces.
+func BuildOpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "RapidTriage API",
+		Version:     "1.0.0",
+		Description: "Triages medical emergencies reported by voice, text, photo or structured assessment and coordinates the response.",
+	})
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer", Description: "An API key or an HS256 JWT",
+	}
+	doc.Components.SecuritySchemes["apiKey"] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "header", Name: APIKeyHeader,
+	}
+	doc.Security = []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}}
+
+	// Generated schemas
+	emergencyResponse := doc.SchemaOf(EmergencyResponse{})
+	job := doc.SchemaOf(JobView{})
+	incident := doc.SchemaOf(Incident{})
+	profile := doc.SchemaOf(models.PatientProfile{})
+	textRequest := doc.SchemaOf(TextEmergencyRequest{})
+	chatRequest := doc.SchemaOf(ChatRequest{})
+	assessmentRequest := doc.SchemaOf(AssessmentRequest{})
+	updateRequest := doc.SchemaOf(IncidentUpdateRequest{})
+	doc.SchemaOf(ErrorResponse{})
+
+	// Constraints the handlers enforce, which types alone cannot express
+	location := doc.Component("Location")
+	location.Property("latitude").Range(-90, 90)
+	location.Property("longitude").Range(-180, 180)
+
+	vitals := doc.Component("Vitals")
+	vitals.Property("heart_rate").Range(0, 350).Describe("Beats per minute")
+	vitals.Property("respiratory_rate").Range(0, 100).Describe("Breaths per minute")
+	vitals.Property("systolic_bp").Range(0, 350).Describe("mmHg")
+	vitals.Property("diastolic_bp").Range(0, 250).Describe("mmHg")
+	vitals.Property("oxygen_saturation").Range(0, 100).Describe("SpO2 percentage")
+	vitals.Property("temperature").Range(0, 45).Describe("Degrees Celsius, between 20 and 45; 0 when not measured")
+	vitals.Property("glasgow_coma_scale").Range(0, 15).Describe("3 to 15; 0 when not assessed")
+
+	doc.Component("TextEmergencyRequest").Require("text").Property("text").NonEmpty()
+	doc.Component("TextEmergencyRequest").Property("image").Describe("Base64-encoded scene or wound photo")
+	doc.Component("ChatRequest").Require("text").Property("text").NonEmpty()
+
+	assessment := doc.Component("AssessmentRequest")
+	assessment.Description = "Needs symptoms or a description"
+	assessment.Property("pain_score").Range(0, 10)
+	assessment.Property("age").Range(0, 130)
+	assessment.Property("duration").OneOf(models.DurationUnderADay, models.DurationOneToThree, models.DurationThreeToWeek, models.DurationOverAWeek)
+	assessment.Property("sex").Describe("female, male or other")
+
+	patient := doc.Component("PatientProfile")
+	patient.Require("name").Property("name").NonEmpty()
+	patient.Property("date_of_birth").Format = "date"
+	for _, field := range []string{"id", "owner", "created_at", "updated_at"} {
+		patient.Property(field).Describe("Set by the server")
+	}
+	contact := doc.Component("EmergencyContact")
+	contact.Require("name", "phone")
+	contact.Property("name").NonEmpty()
+	contact.Property("phone").NonEmpty()
+
+	// Parameters shared by the endpoints that report emergencies
+	reportHeaders := []*openapi.Parameter{
+		{Name: IdempotencyKeyHeader, In: "header", Description: "Makes retries return the first response instead of reporting the emergency again", Schema: &openapi.Schema{Type: "string"}},
+		{Name: PatientIDHeader, In: "header", Description: "Patient profile to attach to the emergency", Schema: &openapi.Schema{Type: "string"}},
+	}
+	idParameter := func(description string) *openapi.Parameter {
+		return &openapi.Parameter{Name: "id", In: "path", Required: true, Description: description, Schema: &openapi.Schema{Type: "string"}}
+	}
+	uploadForm := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"audio":    {Type: "string", Format: "binary", Description: "Recording of the call; may be omitted when an image is sent"},
+		"image":    {Type: "string", Format: "binary", Description: "Scene or wound photo"},
+		"location": {Type: "string", Description: "JSON-encoded Location"},
+		"async":    {Type: "string", Description: "true to queue the emergency as a job and respond 202"},
+	}}
+
+	liveness := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"status":    {Type: "string"},
+		"timestamp": {Type: "string", Format: "date-time"},
+	}}
+	for _, path := range []string{"/api/v1/health", "/api/v1/health/live"} {
+		doc.Add(http.MethodGet, path, &openapi.Operation{
+			Summary:   "Report that the server is running",
+			Tags:      []string{"operations"},
+			Responses: map[string]*openapi.Response{"200": jsonResponse("The server is running", liveness)},
+		})
+	}
+	doc.Add(http.MethodGet, readinessPath, &openapi.Operation{
+		Summary:     "Report whether the models, tools and stores the server depends on are working",
+		Description: "Results are cached briefly. The status is degraded when only non-critical checks fail.",
+		Tags:        []string{"operations"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("The server is ready or degraded", doc.SchemaOf(ReadinessReport{})),
+			"503": jsonResponse("A critical dependency is failing", doc.SchemaOf(ReadinessReport{})),
+		},
+	})
+	doc.Add(http.MethodGet, openAPIPath, &openapi.Operation{
+		Summary:   "Get this document",
+		Tags:      []string{"operations"},
+		Responses: map[string]*openapi.Response{"200": jsonResponse("The OpenAPI document", &openapi.Schema{Type: "object"})},
+	})
+
+	doc.Add(http.MethodPost, "/api/v1/emergency", &openapi.Operation{
+		Summary:     "Report an emergency by voice recording and/or photo",
+		Description: "Send Prefer: respond-async or async=true to queue long recordings as a job.",
+		Tags:        []string{"emergencies"},
+		Parameters:  reportHeaders,
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{"multipart/form-data": {Schema: uploadForm}}},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("The coordinated response", emergencyResponse),
+			"202": jsonResponse("The queued job; poll its Location", job),
+		}, "400", "413", "415", "422", "503"),
+	})
+	doc.Add(http.MethodPost, "/api/v1/emergency/text", &openapi.Operation{
+		Summary:     "Report an emergency in writing, optionally with a photo",
+		Tags:        []string{"emergencies"},
+		Parameters:  reportHeaders,
+		RequestBody: jsonBody(textRequest),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("The coordinated response", emergencyResponse),
+		}, "400", "413", "422", "503"),
+	})
+	doc.Add(http.MethodPost, "/api/v1/emergency/stream", &openapi.Operation{
+		Summary:     "Report an emergency by recording and follow its progress as server-sent events",
+		Tags:        []string{"emergencies"},
+		Parameters:  reportHeaders,
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{"multipart/form-data": {Schema: uploadForm}}},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {Description: "A stream of progress events", Content: map[string]*openapi.MediaType{
+				"text/event-stream": {Schema: doc.SchemaOf(ProgressEvent{})},
+			}},
+		}, "400"),
+	})
+	doc.Add(http.MethodGet, "/api/v1/emergency/live", &openapi.Operation{
+		Summary:     "Stream live call audio over a WebSocket",
+		Description: "Binary frames carry audio; the server answers with progress events as JSON text frames. Browsers may pass the credential as the access_token query parameter.",
+		Tags:        []string{"emergencies"},
+		Parameters: []*openapi.Parameter{
+			{Name: "format", In: "query", Schema: (&openapi.Schema{Type: "string"}).OneOf("pcm", "opus", "ogg", "webm")},
+			{Name: "sample_rate", In: "query", Description: "Sample rate of pcm audio; defaults to 16000", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "channels", In: "query", Description: "Channels of pcm audio; defaults to 1", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "bits", In: "query", Description: "Bits per pcm sample; defaults to 16", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "location", In: "query", Description: "JSON-encoded Location", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "access_token", In: "query", Description: "API key or JWT for clients that cannot set headers", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: withErrors(map[string]*openapi.Response{"101": {Description: "Switching to the WebSocket protocol"}}, "400"),
+	})
+	doc.Add(http.MethodPost, "/api/v1/emergency/chat", &openapi.Operation{
+		Summary:     "Send one message in a conversation with the triage agent",
+		Tags:        []string{"emergencies"},
+		Parameters:  reportHeaders,
+		RequestBody: jsonBody(chatRequest),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("The agent's reply and the current response", doc.SchemaOf(ConversationResponse{})),
+		}, "400", "404", "503"),
+	})
+	doc.Add(http.MethodPost, "/api/v1/assessment", &openapi.Operation{
+		Summary:     "Triage a structured symptom assessment",
+		Description: "A deterministic score is a floor under the model's reading of the symptoms.",
+		Tags:        []string{"emergencies"},
+		Parameters:  reportHeaders,
+		RequestBody: jsonBody(assessmentRequest),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("The coordinated response", emergencyResponse),
+		}, "400", "503"),
+	})
+
+	doc.Add(http.MethodGet, jobsPath+"{id}", &openapi.Operation{
+		Summary:    "Get a queued emergency job",
+		Tags:       []string{"emergencies"},
+		Parameters: []*openapi.Parameter{idParameter("Job ID")},
+		Responses:  withErrors(map[string]*openapi.Response{"200": jsonResponse("The job", job)}, "404"),
+	})
+	doc.Add(http.MethodGet, incidentPath+"{id}", &openapi.Operation{
+		Summary:    "Get a stored emergency",
+		Tags:       []string{"incidents"},
+		Parameters: []*openapi.Parameter{idParameter("Emergency ID")},
+		Responses:  withErrors(map[string]*openapi.Response{"200": jsonResponse("The emergency", incident)}, "404"),
+	})
+	doc.Add(http.MethodPost, incidentPath+"{id}/updates", &openapi.Operation{
+		Summary:    "Add text, audio or vitals to an emergency and re-triage it",
+		Tags:       []string{"incidents"},
+		Parameters: []*openapi.Parameter{idParameter("Emergency ID"), reportHeaders[0]},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+			"application/json": {Schema: updateRequest},
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"text":     {Type: "string"},
+				"audio":    {Type: "string", Format: "binary"},
+				"vitals":   {Type: "string", Description: "JSON-encoded Vitals"},
+				"location": {Type: "string", Description: "JSON-encoded Location"},
+			}}},
+		}},
+		Responses: withErrors(map[string]*openapi.Response{"200": jsonResponse("The updated emergency", incident)}, "400", "404", "503"),
+	})
+	doc.Add(http.MethodGet, "/api/v1/emergencies", &openapi.Operation{
+		Summary: "List stored emergencies, newest first",
+		Tags:    []string{"incidents"},
+		Parameters: []*openapi.Parameter{
+			{Name: "code", In: "query", Description: "Comma-separated triage codes", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "since", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+			{Name: "until", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+			{Name: "lat", In: "query", Schema: (&openapi.Schema{Type: "number"}).Range(-90, 90)},
+			{Name: "lon", In: "query", Schema: (&openapi.Schema{Type: "number"}).Range(-180, 180)},
+			{Name: "radius_km", In: "query", Schema: &openapi.Schema{Type: "number"}},
+			{Name: "limit", In: "query", Schema: (&openapi.Schema{Type: "integer"}).Range(1, maxIncidentLimit)},
+			{Name: "offset", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("A page of emergencies", doc.SchemaOf(IncidentListResponse{})),
+		}, "400"),
+	})
+
+	doc.Add(http.MethodPost, profilesPath, &openapi.Operation{
+		Summary:     "Create a patient profile",
+		Tags:        []string{"patients"},
+		RequestBody: jsonBody(profile),
+		Responses:   withErrors(map[string]*openapi.Response{"201": jsonResponse("The created profile", profile)}, "400"),
+	})
+	doc.Add(http.MethodGet, profilesPath+"/{id}", &openapi.Operation{
+		Summary:    "Get a patient profile",
+		Tags:       []string{"patients"},
+		Parameters: []*openapi.Parameter{idParameter("Patient profile ID")},
+		Responses:  withErrors(map[string]*openapi.Response{"200": jsonResponse("The profile", profile)}, "404"),
+	})
+	doc.Add(http.MethodPut, profilesPath+"/{id}", &openapi.Operation{
+		Summary:     "Replace a patient profile",
+		Tags:        []string{"patients"},
+		Parameters:  []*openapi.Parameter{idParameter("Patient profile ID")},
+		RequestBody: jsonBody(profile),
+		Responses:   withErrors(map[string]*openapi.Response{"200": jsonResponse("The updated profile", profile)}, "400", "404"),
+	})
+	doc.Add(http.MethodDelete, profilesPath+"/{id}", &openapi.Operation{
+		Summary:    "Delete a patient profile",
+		Tags:       []string{"patients"},
+		Parameters: []*openapi.Parameter{idParameter("Patient profile ID")},
+		Responses:  withErrors(map[string]*openapi.Response{"204": {Description: "The profile was deleted"}}, "404"),
+	})
+
+	doc.Add(http.MethodGet, "/api/v1/shadow/summary", &openapi.Operation{
+		Summary:   "Summarise how the shadow model compares with the primary",
+		Tags:      []string{"operations"},
+		Responses: withErrors(map[string]*openapi.Response{"200": jsonResponse("The comparison", doc.SchemaOf(ai.ShadowSummary{}))}),
+	})
+	doc.Add(http.MethodGet, "/api/v1/shadow/records", &openapi.Operation{
+		Summary: "List paired primary and shadow outputs, newest first",
+		Tags:    []string{"operations"},
+		Parameters: []*openapi.Parameter{
+			{Name: "disagreements", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "under_triage", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("The records", &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"records": {Type: "array", Items: doc.SchemaOf(ai.ShadowRecord{})},
+				"count":   {Type: "integer"},
+			}}),
+		}, "400"),
+	})
+	doc.Add(http.MethodGet, "/api/v1/metrics", &openapi.Operation{
+		Summary: "Get a snapshot of the operational metrics",
+		Tags:    []string{"operations"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": jsonResponse("Every registered metric", &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"metrics": {Type: "array", Items: doc.SchemaOf(metrics.Metric{})},
+			}}),
+		}),
+	})
+
+	doc.Add(http.MethodGet, prometheusPath, &openapi.Operation{
+		Summary: "Get the operational metrics in the Prometheus text format",
+		Tags:    []string{"operations"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {Description: "Every registered metric", Content: map[string]*openapi.MediaType{
+				"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+		}),
+	})
+
+	documentAccess(doc)
+	return doc
+}
+
+// documentAccess marks public operations and names the scopes each of the others needs, as AuthMiddleware
+// decides them
+func documentAccess(doc *openapi.Document) {
+	for path, item := range doc.Paths {
+		concrete := strings.ReplaceAll(strings.ReplaceAll(path, "{id}", "id"), "{", "")
+		public, scopes := routeAccess(concrete)
+		for _, operation := range *item {
+			if public {
+				operation.Public = true
+				continue
+			}
+			operation.Responses["401"] = errorResponse("401")
+			if len(scopes) == 0 {
+				continue
+			}
+			// Admins may use every route
+			names := []string{string(auth.ScopeAdmin)}
+			for _, scope := range scopes {
+				if scope != auth.ScopeAdmin {
+					names = append(names, string(scope))
+				}
+			}
+			sort.Strings(names)
+			operation.Description = strings.TrimSpace(operation.Description + " Scopes: " + strings.Join(names, ", ") + ".")
+			operation.Responses["403"] = errorResponse("403")
+		}
+	}
+}
+
+// jsonBody documents a required JSON request body
+func jsonBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{"application/json": {Schema: schema}}}
+}
+
+// jsonResponse documents a JSON response
+func jsonResponse(description string, schema *openapi.Schema) *openapi.Response {
+	return &openapi.Response{Description: description, Content: map[string]*openapi.MediaType{"application/json": {Schema: schema}}}
+}
+
+// errorResponse documents an error status, which always carries an ErrorResponse
+func errorResponse(status string) *openapi.Response {
+	code, _ := strconv.Atoi(status)
+	return jsonResponse(http.StatusText(code), openapi.Ref("ErrorResponse"))
+}
+
+// withErrors adds error statuses, and the 500 every operation can return, to a response map
+func withErrors(responses map[string]*openapi.Response, statuses ...string) map[string]*openapi.Response {
+	for _, status := range append(statuses, "500") {
+		responses[status] = errorResponse(status)
+	}
+	return responses
+}
+
+// OpenAPIHandler serves the API's OpenAPI document
+type OpenAPIHandler struct {
+	document []byte
+}
+
+// NewOpenAPIHandler creates a handler serving doc
+func NewOpenAPIHandler(doc *openapi.Document) (*OpenAPIHandler, error) {
+	document, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{document: document}, nil
+}
+
+// RegisterRoutes registers the OpenAPI document route
+func (h *OpenAPIHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(openAPIPath, h.HandleOpenAPI)
+}
+
+// HandleOpenAPI returns the OpenAPI document
+func (h *OpenAPIHandler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(h.document); err != nil {
+		log.Printf("Failed to write OpenAPI document: %v", err)
+	}
+}
+
+// RequestValidationMiddleware checks JSON request bodies against the operation's schema in doc and rejects
+// those that do not match with every failing field. Other bodies are left to the handlers.
+func RequestValidationMiddleware(doc *openapi.Document, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		operation := doc.Find(r.Method, r.URL.Path)
+		if operation == nil || operation.RequestBody == nil || operation.RequestBody.Content["application/json"] == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+			return
+		}
+		if len(body) > maxValidatedBody {
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fields := doc.Validate(operation.RequestBody.Content["application/json"].Schema, body)
+		if len(fields) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		messages := make([]string, len(fields))
+		for i, field := range fields {
+			messages[i] = field.Error()
+		}
+		writeAPIError(w, r, apiError{
+			status:  http.StatusBadRequest,
+			code:    CodeInvalidRequest,
+			message: "Request body is invalid: " + strings.Join(messages, "; "),
+			fields:  fields,
+		})
+	})
+}
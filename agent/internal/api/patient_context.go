@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"agent/internal/models"
+	"agent/internal/redact"
+)
+
+const (
+	// PatientIDHeader references the patient profile an emergency request is about
+	PatientIDHeader = "X-Patient-ID"
+
+	// patientIDParam is the query parameter alternative for clients that cannot set headers, such as
+	// browser WebSockets
+	patientIDParam = "patient_id"
+)
+
+type patientProfileKey struct{}
+
+// PatientProfileMiddleware loads the profile a request references, so that triage, prompts and tool calls
+// include the patient's record. Unknown profiles are logged and ignored rather than rejected so an
+// emergency request is never refused over it.
+func PatientProfileMiddleware(store ProfileStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(PatientIDHeader)
+		if id == "" {
+			id = r.URL.Query().Get(patientIDParam)
+		}
+		if id == "" || strings.HasPrefix(r.URL.Path, profilesPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		profile, err := store.Get(id)
+		if err == nil && !canAccessProfile(r.Context(), profile) {
+			err = ErrProfileNotFound
+		}
+		if err != nil {
+			if !errors.Is(err, ErrProfileNotFound) {
+				log.Printf("Failed to load patient profile %s: %v", id, err)
+			} else {
+				log.Printf("Ignoring unknown patient profile %s from %s", id, r.RemoteAddr)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPatientProfile(r.Context(), profile)))
+	})
+}
+
+// WithPatientProfile returns a context carrying the patient's profile. The identifying values in it are
+// also registered with the redactor so they never reach a model.
+func WithPatientProfile(ctx context.Context, profile *models.PatientProfile) context.Context {
+	ctx = redact.WithKnownEntities(ctx, profileEntities(profile)...)
+	return context.WithValue(ctx, patientProfileKey{}, profile)
+}
+
+// PatientProfileFromContext returns the profile attached by WithPatientProfile, or nil
+func PatientProfileFromContext(ctx context.Context) *models.PatientProfile {
+	profile, _ := ctx.Value(patientProfileKey{}).(*models.PatientProfile)
+	return profile
+}
+
+// attachPatient fills the situation's patient details from the request's profile, keeping anything the
+// caller reported for this emergency
+func attachPatient(ctx context.Context, situation *models.EmergencySituation) {
+	profile := PatientProfileFromContext(ctx)
+	if profile == nil {
+		return
+	}
+	if situation.PatientInfo == nil {
+		situation.PatientInfo = profile.PatientInfo(time.Now())
+		return
+	}
+	situation.PatientInfo.Merge(profile.PatientInfo(time.Now()))
+}
+
+// patientPrompt describes the request's patient for a model, or returns "" without a profile
+func patientPrompt(ctx context.Context) string {
+	profile := PatientProfileFromContext(ctx)
+	if profile == nil {
+		return ""
+	}
+	details := profile.PatientInfo(time.Now()).Describe()
+	if details == "" {
+		return ""
+	}
+	return "The patient's medical record on file, supplied by the server rather than the caller:\n" + details
+}
+
+// withPatientPrompt appends the request's patient record to a model prompt
+func withPatientPrompt(ctx context.Context, prompt string) string {
+	if patient := patientPrompt(ctx); patient != "" {
+		return strings.TrimRight(prompt, "\n") + "\n\n" + patient + "\n"
+	}
+	return prompt
+}
+
+// profileEntities lists the identifying values in a profile: names, phone numbers and date of birth
+func profileEntities(profile *models.PatientProfile) []redact.Entity {
+	var entities []redact.Entity
+	addName := func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		entities = append(entities, redact.Entity{Type: redact.EntityName, Value: name})
+		// Callers often use just a first or last name
+		if parts := strings.Fields(name); len(parts) > 1 {
+			for _, part := range parts {
+				if len(part) > 2 {
+					entities = append(entities, redact.Entity{Type: redact.EntityName, Value: part})
+				}
+			}
+		}
+	}
+
+	addName(profile.Name)
+	if profile.DateOfBirth != "" {
+		entities = append(entities, redact.Entity{Type: redact.EntityDateOfBirth, Value: profile.DateOfBirth})
+	}
+	for _, contact := range profile.EmergencyContacts {
+		addName(contact.Name)
+		if contact.Phone != "" {
+			entities = append(entities, redact.Entity{Type: redact.EntityPhone, Value: contact.Phone})
+		}
+	}
+	if profile.AdvanceDirectives != nil {
+		addName(profile.AdvanceDirectives.HealthcareProxy)
+	}
+	return entities
+}
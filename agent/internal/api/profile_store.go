@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"agent/internal/models"
+)
+
+// ErrProfileNotFound is returned when no patient profile has the requested ID
+var ErrProfileNotFound = errors.New("patient profile not found")
+
+// ProfileStore persists patient profiles
+type ProfileStore interface {
+	// Save creates or replaces a profile
+	Save(profile *models.PatientProfile) error
+
+	// Get returns a profile by ID, or ErrProfileNotFound
+	Get(id string) (*models.PatientProfile, error)
+
+	// Delete removes a profile, or returns ErrProfileNotFound
+	Delete(id string) error
+
+	// Ping checks the store can accept writes
+	Ping() error
+}
+
+// FileProfileStore keeps each profile as a JSON file in a directory, cached in memory.
+// With no directory it keeps profiles in memory only.
+type FileProfileStore struct {
+	dir string
+
+	mu       sync.RWMutex
+	profiles map[string][]byte
+}
+
+// NewFileProfileStore opens the store in dir, creating it if needed and loading existing profiles
+func NewFileProfileStore(dir string) (*FileProfileStore, error) {
+	store := &FileProfileStore{
+		dir:      dir,
+		profiles: make(map[string][]byte),
+	}
+	if dir == "" {
+		return store, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create profile store directory: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile %s: %w", path, err)
+		}
+		var profile models.PatientProfile
+		if err := json.Unmarshal(data, &profile); err != nil || profile.ID == "" {
+			fmt.Printf("Warning: skipping unreadable profile file %s: %v\n", path, err)
+			continue
+		}
+		store.profiles[profile.ID] = data
+	}
+	return store, nil
+}
+
+// Ping checks the profile directory is writable
+func (s *FileProfileStore) Ping() error {
+	return checkWritable(s.dir)
+}
+
+// Save creates or replaces a profile, writing it to disk before it becomes visible
+func (s *FileProfileStore) Save(profile *models.PatientProfile) error {
+	if !validIncidentID(profile.ID) {
+		return fmt.Errorf("invalid profile ID %q", profile.ID)
+	}
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir != "" {
+		if err := writeFileAtomic(filepath.Join(s.dir, profile.ID+".json"), data); err != nil {
+			return fmt.Errorf("failed to write profile: %w", err)
+		}
+	}
+	s.profiles[profile.ID] = data
+	return nil
+}
+
+// Get returns a copy of a profile by ID
+func (s *FileProfileStore) Get(id string) (*models.PatientProfile, error) {
+	s.mu.RLock()
+	data, ok := s.profiles[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, id)
+	}
+
+	var profile models.PatientProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode profile %s: %w", id, err)
+	}
+	return &profile, nil
+}
+
+// Delete removes a profile from disk and memory
+func (s *FileProfileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, id)
+	}
+	if s.dir != "" {
+		if err := os.Remove(filepath.Join(s.dir, id+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete profile: %w", err)
+		}
+	}
+	delete(s.profiles, id)
+	return nil
+}
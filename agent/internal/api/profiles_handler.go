@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"agent/internal/auth"
+	"agent/internal/models"
+)
+
+// profilesPath is the URL of the profile collection; individual profiles live below it
+const profilesPath = "/api/v1/patients"
+
+// maxProfileSize bounds a profile request body
+const maxProfileSize = 64 * 1024
+
+// ProfilesHandler manages patient profiles
+type ProfilesHandler struct {
+	store ProfileStore
+}
+
+// NewProfilesHandler creates a new patient profiles API handler
+func NewProfilesHandler(store ProfileStore) *ProfilesHandler {
+	return &ProfilesHandler{store: store}
+}
+
+// RegisterRoutes registers the patient profile API routes
+func (h *ProfilesHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(profilesPath, h.HandleProfiles)
+	mux.HandleFunc(profilesPath+"/", h.HandleProfile)
+}
+
+// HandleProfiles creates a profile
+func (h *ProfilesHandler) HandleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	profile, ok := parseProfile(w, r)
+	if !ok {
+		return
+	}
+	profile.ID = models.NewPatientProfileID()
+	if client := auth.ClientFromContext(r.Context()); client != nil {
+		profile.Owner = client.Principal()
+	}
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = profile.CreatedAt
+
+	if err := h.store.Save(profile); err != nil {
+		writeServiceError(w, r, "Failed to save patient profile", err)
+		return
+	}
+	log.Printf("Created patient profile %s", profile.ID)
+
+	w.Header().Set("Location", profilesPath+"/"+profile.ID)
+	writeProfile(w, http.StatusCreated, profile)
+}
+
+// HandleProfile returns, replaces or deletes a profile
+func (h *ProfilesHandler) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, profilesPath+"/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Patient profile not found")
+		return
+	}
+
+	// Profiles the client may not use are reported as missing rather than revealed
+	existing, err := h.store.Get(id)
+	if err == nil && !canAccessProfile(r.Context(), existing) {
+		err = ErrProfileNotFound
+	}
+	if err != nil {
+		h.writeStoreError(w, r, "Failed to load patient profile", err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeProfile(w, http.StatusOK, existing)
+
+	case http.MethodPut:
+		profile, ok := parseProfile(w, r)
+		if !ok {
+			return
+		}
+		profile.ID = existing.ID
+		profile.Owner = existing.Owner
+		profile.CreatedAt = existing.CreatedAt
+		profile.UpdatedAt = time.Now()
+		if err := h.store.Save(profile); err != nil {
+			writeServiceError(w, r, "Failed to save patient profile", err)
+			return
+		}
+		writeProfile(w, http.StatusOK, profile)
+
+	case http.MethodDelete:
+		if err := h.store.Delete(id); err != nil {
+			h.writeStoreError(w, r, "Failed to delete patient profile", err)
+			return
+		}
+		log.Printf("Deleted patient profile %s", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// writeStoreError reports a missing profile as 404 and anything else as a service error
+func (h *ProfilesHandler) writeStoreError(w http.ResponseWriter, r *http.Request, operation string, err error) {
+	if errors.Is(err, ErrProfileNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Patient profile not found")
+		return
+	}
+	writeServiceError(w, r, operation, err)
+}
+
+// parseProfile reads and validates a profile from a JSON body. On failure it writes the error response
+// and returns false.
+func parseProfile(w http.ResponseWriter, r *http.Request) (*models.PatientProfile, bool) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Content-Type must be application/json")
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProfileSize))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body")
+		return nil, false
+	}
+
+	var profile models.PatientProfile
+	if err := json.Unmarshal(body, &profile); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+		return nil, false
+	}
+	// Server-managed fields are never taken from the body
+	profile.ID, profile.Owner = "", ""
+	profile.CreatedAt, profile.UpdatedAt = time.Time{}, time.Time{}
+
+	if err := validateProfile(&profile); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return nil, false
+	}
+	return &profile, true
+}
+
+// validateProfile checks the fields a profile's owner supplies
+func validateProfile(profile *models.PatientProfile) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if profile.DateOfBirth != "" {
+		birth, err := profile.Birth()
+		if err != nil {
+			return fmt.Errorf("date_of_birth must be a date such as 1980-04-23")
+		}
+		if birth.After(time.Now()) {
+			return fmt.Errorf("date_of_birth must not be in the future")
+		}
+	}
+	for _, contact := range profile.EmergencyContacts {
+		if strings.TrimSpace(contact.Name) == "" || strings.TrimSpace(contact.Phone) == "" {
+			return fmt.Errorf("every emergency contact needs a name and phone")
+		}
+	}
+	return nil
+}
+
+// writeProfile sends a profile as JSON
+func writeProfile(w http.ResponseWriter, status int, profile *models.PatientProfile) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		log.Printf("Failed to encode patient profile: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"agent/internal/models"
+	"agent/internal/tools"
+)
+
+// ProgressEventType identifies a stage of the emergency pipeline
+type ProgressEventType string
+
+// Pipeline stages reported as progress events
+const (
+	EventAudioReceived       ProgressEventType = "audio_received"
+	EventTranscriptReady     ProgressEventType = "transcript_ready"
+	EventPreliminaryTriage   ProgressEventType = "preliminary_triage"
+	EventTriageDecided       ProgressEventType = "triage_decided"
+	EventTriageChanged       ProgressEventType = "triage_changed"
+	EventFacilitiesFound     ProgressEventType = "facilities_found"
+	EventHospitalNotified    ProgressEventType = "hospital_notified"
+	EventAmbulanceDispatched ProgressEventType = "ambulance_dispatched"
+	EventAppointmentBooked   ProgressEventType = "appointment_booked"
+	EventSummaryReady        ProgressEventType = "summary_ready"
+	EventCompleted           ProgressEventType = "completed"
+	EventError               ProgressEventType = "error"
+)
+
+// ProgressEvent is one step of the pipeline reported to a caller as it happens
+type ProgressEvent struct {
+	Type      ProgressEventType `json:"type"`
+	Timestamp string            `json:"timestamp"`
+	Data      json.RawMessage   `json:"data,omitempty"`
+}
+
+// ProgressReporter receives progress events; it may be called from several goroutines at once
+type ProgressReporter func(ProgressEvent)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose pipeline stages are reported to reporter
+func WithProgress(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// reportProgress sends an event to the context's reporter, if any. The data is encoded straight away
+// because the pipeline may go on to modify it.
+func reportProgress(ctx context.Context, eventType ProgressEventType, data interface{}) {
+	reporter, ok := ctx.Value(progressKey{}).(ProgressReporter)
+	if !ok {
+		return
+	}
+
+	event := ProgressEvent{Type: eventType, Timestamp: time.Now().Format(time.RFC3339Nano)}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Failed to encode %s progress event: %v", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+	reporter(event)
+}
+
+// triageProgress is the data of triage events, including pre-arrival instructions the caller can act on
+type triageProgress struct {
+	Code               models.TriageCode `json:"code"`
+	Confidence         float64           `json:"confidence"`
+	Description        string            `json:"description,omitempty"`
+	RecommendedActions []string          `json:"recommended_actions,omitempty"`
+}
+
+// newTriageProgress summarizes a situation's triage for a progress event
+func newTriageProgress(situation *models.EmergencySituation) triageProgress {
+	progress := triageProgress{
+		Code:        situation.Code,
+		Confidence:  situation.Confidence,
+		Description: situation.Description,
+	}
+	if actions, ok := situation.Metadata["recommended_actions"]; ok {
+		json.Unmarshal([]byte(actions), &progress.RecommendedActions)
+	}
+	return progress
+}
+
+// reportToolResult reports a completed tool call as the matching pipeline stage
+func reportToolResult(ctx context.Context, tool tools.EmergencyTool, response *tools.ToolResponse) {
+	var eventType ProgressEventType
+	switch tool.Schema().Name {
+	case tools.FunctionNotifyHospital:
+		eventType = EventHospitalNotified
+	case tools.FunctionDispatchAmbulance:
+		eventType = EventAmbulanceDispatched
+	case tools.FunctionBookAppointment:
+		eventType = EventAppointmentBooked
+	case tools.FunctionFindFacilities:
+		eventType = EventFacilitiesFound
+	default:
+		return
+	}
+
+	reportProgress(ctx, eventType, map[string]interface{}{
+		"tool":    response.ToolName,
+		"success": response.Success,
+		"message": response.Message,
+	})
+}
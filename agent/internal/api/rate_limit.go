@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"agent/internal/auth"
+	"agent/internal/metrics"
+	"agent/internal/models"
+	"agent/internal/ratelimit"
+)
+
+// RuleBasedTriageHeader is set on responses triaged without the AI model, naming the limit that was exceeded
+const RuleBasedTriageHeader = "X-Triage-Mode-Reason"
+
+// RouteLimit limits one route per authenticated client and per source IP
+type RouteLimit struct {
+	Path      string          // Exact path, or a prefix when it ends in "/"; a {id} segment matches any one segment
+	PerClient ratelimit.Limit // Requests without an authenticated client are only limited per IP
+	PerIP     ratelimit.Limit
+}
+
+// DefaultRouteLimits returns the limits for the routes that call the AI model on every request. Live calls
+// are not limited: their audio can only be triaged by the model.
+func DefaultRouteLimits() []RouteLimit {
+	perClient := ratelimit.Limit{Rate: 10.0 / 60, Burst: 20}
+	perIP := ratelimit.Limit{Rate: 30.0 / 60, Burst: 60}
+
+	var limits []RouteLimit
+	for _, path := range []string{
+		"/api/v1/emergency",
+		"/api/v1/emergency/text",
+		"/api/v1/emergency/stream",
+		"/api/v1/emergency/chat",
+		"/api/v1/emergency/{id}/updates",
+		"/api/v1/assessment",
+	} {
+		limits = append(limits, RouteLimit{Path: path, PerClient: perClient, PerIP: perIP})
+	}
+	return limits
+}
+
+// ParseRouteLimits parses route limits such as
+// "/api/v1/emergency client=10/m:20 ip=30/m:60; /api/v1/assessment ip=5/m". A limit left out is unlimited.
+func ParseRouteLimits(spec string) ([]RouteLimit, error) {
+	var limits []RouteLimit
+	for _, entry := range strings.Split(spec, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(fields[0], "/") {
+			return nil, fmt.Errorf("route limit %q must start with a path", strings.TrimSpace(entry))
+		}
+
+		limit := RouteLimit{Path: fields[0]}
+		for _, field := range fields[1:] {
+			kind, value, _ := strings.Cut(field, "=")
+			parsed, err := ratelimit.ParseLimit(value)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: %w", limit.Path, err)
+			}
+			switch kind {
+			case "client":
+				limit.PerClient = parsed
+			case "ip":
+				limit.PerIP = parsed
+			default:
+				return nil, fmt.Errorf("route %s: unknown limit %q; use client= or ip=", limit.Path, kind)
+			}
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// RateLimitConfig contains the settings for RateLimitMiddleware
+type RateLimitConfig struct {
+	Routes []RouteLimit
+
+	// TrustForwardedFor takes the source IP from X-Forwarded-For; enable it only behind a proxy that sets it
+	TrustForwardedFor bool
+
+	// Metrics receives rate limit counters; defaults to metrics.Default
+	Metrics *metrics.Registry
+}
+
+// routeLimiter holds the buckets and counters of one limited route
+type routeLimiter struct {
+	path      string
+	perClient *ratelimit.Limiter
+	perIP     *ratelimit.Limiter
+
+	requests *metrics.Counter
+	degraded *metrics.Counter
+}
+
+// Reasons a request was triaged without the model
+const (
+	limitedByClient = "client_rate_limited"
+	limitedByIP     = "ip_rate_limited"
+)
+
+// RateLimitMiddleware applies token-bucket limits per client and per IP. Every AI model call costs money, but
+// refusing a real emergency costs more, so a request over its limit is still served: it is marked for
+// rule-based triage and never reaches the model.
+func RateLimitMiddleware(config RateLimitConfig, next http.Handler) http.Handler {
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+
+	degradedByClient := registry.Counter("ratelimit_client_degraded_total", "Requests triaged without the AI model because the client exceeded its limit")
+	degradedByIP := registry.Counter("ratelimit_ip_degraded_total", "Requests triaged without the AI model because the source IP exceeded its limit")
+
+	routes := make([]*routeLimiter, 0, len(config.Routes))
+	for _, route := range config.Routes {
+		name := strings.NewReplacer("/", "_", "{", "", "}", "").Replace(strings.TrimPrefix(route.Path, "/api/v1/"))
+		name = strings.Trim(name, "_")
+		routes = append(routes, &routeLimiter{
+			path:      route.Path,
+			perClient: ratelimit.NewLimiter(route.PerClient),
+			perIP:     ratelimit.NewLimiter(route.PerIP),
+			requests:  registry.Counter("ratelimit_"+name+"_requests_total", "Requests to "+route.Path+" checked against its rate limits"),
+			degraded:  registry.Counter("ratelimit_"+name+"_degraded_total", "Requests to "+route.Path+" triaged without the AI model"),
+		})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := matchRouteLimit(routes, r.URL.Path)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route.requests.Inc()
+
+		// A client over its own limit does not also use up its network's allowance
+		ip := sourceIP(r, config.TrustForwardedFor)
+		reason := ""
+		client := auth.ClientFromContext(r.Context())
+		if client != nil && !route.perClient.Allow(client.Principal()) {
+			reason = limitedByClient
+			degradedByClient.Inc()
+		} else if !route.perIP.Allow(ip) {
+			reason = limitedByIP
+			degradedByIP.Inc()
+		}
+		if reason == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route.degraded.Inc()
+		principal := "anonymous"
+		if client != nil {
+			principal = client.Principal()
+		}
+		log.Printf("Rate limit exceeded for %s from %s on %s (%s); triaging without the AI model", principal, ip, r.URL.Path, reason)
+
+		w.Header().Set(RuleBasedTriageHeader, reason)
+		next.ServeHTTP(w, r.WithContext(withRuleBasedTriage(r.Context(), reason)))
+	})
+}
+
+// matchRouteLimit returns the limiter for a path; the first match applies
+func matchRouteLimit(routes []*routeLimiter, path string) *routeLimiter {
+	for _, route := range routes {
+		if matchRoutePath(route.path, path) {
+			return route
+		}
+	}
+	return nil
+}
+
+// matchRoutePath reports whether a path matches a route: exactly, by prefix when the route ends in "/", or
+// with each {id} segment of the route standing for any one non-empty segment of the path
+func matchRoutePath(route, path string) bool {
+	if path == route || strings.HasSuffix(route, "/") && strings.HasPrefix(path, route) {
+		return true
+	}
+	if !strings.Contains(route, "{id}") {
+		return false
+	}
+	routeSegments := strings.Split(route, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(routeSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range routeSegments {
+		if segment == "{id}" && pathSegments[i] != "" || segment == pathSegments[i] {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// sourceIP returns the address a request came from
+func sourceIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		// The proxy appends the address it saw, so the last entry is the one it vouches for
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type ruleBasedTriageKey struct{}
+
+// withRuleBasedTriage marks a request to be triaged without the AI model
+func withRuleBasedTriage(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, ruleBasedTriageKey{}, reason)
+}
+
+// ruleBasedTriage returns why a request must be triaged without the AI model, if it must
+func ruleBasedTriage(ctx context.Context) (string, bool) {
+	reason, ok := ctx.Value(ruleBasedTriageKey{}).(string)
+	return reason, ok
+}
+
+// ruleBasedSituation creates an untriaged situation from the caller's own words. The coordinator's
+// rule-based classifier assigns its code.
+func ruleBasedSituation(description, sourceText, reason string) *models.EmergencySituation {
+	situation := models.NewEmergencySituation(description)
+	situation.SourceText = sourceText
+	markRuleBased(situation, reason)
+	return situation
+}
+
+// markRuleBased records on a situation that it was triaged without the AI model
+func markRuleBased(situation *models.EmergencySituation, reason string) {
+	situation.Metadata["triage_mode"] = "rule_based"
+	situation.Metadata["triage_mode_reason"] = reason
+}
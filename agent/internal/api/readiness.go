@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"agent/internal/ai"
+	"agent/internal/metrics"
+	"agent/internal/tools"
+)
+
+// readinessPath reports whether the server's dependencies are working
+const readinessPath = "/api/v1/health/ready"
+
+// Readiness statuses
+const (
+	ReadinessReady       = "ready"
+	ReadinessDegraded    = "degraded"
+	ReadinessUnavailable = "unavailable"
+)
+
+// HealthCheck probes one dependency
+type HealthCheck struct {
+	Name string
+
+	// Critical checks make the server unavailable when they fail; others only degrade it
+	Critical bool
+
+	Check func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one health check
+type CheckResult struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"` // A category such as "timeout"; the full error is only logged
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// ReadinessReport is the outcome of every health check
+type ReadinessReport struct {
+	Status    string        `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+// ReadinessConfig contains the settings for a ReadinessChecker
+type ReadinessConfig struct {
+	Checks []HealthCheck
+
+	// CacheTTL is how long results are reused, so probes and scrapes don't hit the providers on every call
+	CacheTTL time.Duration
+
+	// Timeout bounds each check
+	Timeout time.Duration
+
+	// Metrics receives readiness metrics; defaults to metrics.Default
+	Metrics *metrics.Registry
+}
+
+// ReadinessChecker runs health checks in parallel and caches the report
+type ReadinessChecker struct {
+	config ReadinessConfig
+
+	mu     sync.Mutex
+	report *ReadinessReport
+
+	ready    *metrics.Gauge
+	failures *metrics.CounterVec
+}
+
+// NewReadinessChecker creates a new readiness checker
+func NewReadinessChecker(config ReadinessConfig) *ReadinessChecker {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 30 * time.Second
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Metrics == nil {
+		config.Metrics = metrics.Default
+	}
+
+	return &ReadinessChecker{
+		config:   config,
+		ready:    config.Metrics.Gauge("readiness_ready", "1 when every critical dependency is healthy, otherwise 0"),
+		failures: config.Metrics.CounterVec("readiness_check_failures_total", "Failed readiness checks by dependency", "check"),
+	}
+}
+
+// Report returns the cached report, running the checks again once it has expired. Callers arriving while
+// the checks run wait for them rather than starting their own.
+func (c *ReadinessChecker) Report() ReadinessReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.config.CacheTTL {
+		return *c.report
+	}
+
+	report := c.run()
+	c.report = &report
+	return report
+}
+
+// run executes every check in parallel. Checks get their own context so one caller hanging up does not
+// cache a failure for everyone.
+func (c *ReadinessChecker) run() ReadinessReport {
+	report := ReadinessReport{
+		Status:    ReadinessReady,
+		CheckedAt: time.Now(),
+		Checks:    make([]CheckResult, len(c.config.Checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range c.config.Checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			result := CheckResult{
+				Name:      check.Name,
+				Healthy:   err == nil,
+				Critical:  check.Critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				// Errors can name internal hosts and endpoints, and the probe is public
+				result.Error = ai.ErrorType(err)
+				log.Printf("Readiness check %s failed: %v", check.Name, err)
+			}
+			report.Checks[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Healthy {
+			continue
+		}
+		c.failures.With(result.Name).Inc()
+		if result.Critical {
+			report.Status = ReadinessUnavailable
+		} else if report.Status == ReadinessReady {
+			report.Status = ReadinessDegraded
+		}
+	}
+
+	if report.Status == ReadinessUnavailable {
+		c.ready.Set(0)
+	} else {
+		c.ready.Set(1)
+	}
+	return report
+}
+
+// ModelChecks pings every model the providers may call. Each provider's default model is critical; routed,
+// shadow and hedge models only degrade the server. Models shared between providers are checked once.
+func ModelChecks(providers ...*ai.Provider) []HealthCheck {
+	var checks []HealthCheck
+	index := make(map[string]int)
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+		for _, dependency := range provider.Dependencies() {
+			model := dependency.Model
+			name := "model:" + string(model.Type()) + "/" + model.Name()
+			critical := dependency.Role == "default"
+			if i, ok := index[name]; ok {
+				checks[i].Critical = checks[i].Critical || critical
+				continue
+			}
+			index[name] = len(checks)
+			checks = append(checks, HealthCheck{
+				Name:     name,
+				Critical: critical,
+				Check: func(ctx context.Context) error {
+					return ai.Ping(ctx, model)
+				},
+			})
+		}
+	}
+	return checks
+}
+
+// ToolChecks asks the service behind each registered tool whether it is up. A failing tool degrades the
+// server without making it unavailable, since triage still works and the response says which tools failed.
+func ToolChecks(registry tools.ToolRegistry) []HealthCheck {
+	var checks []HealthCheck
+	for _, tool := range registry.GetAll() {
+		checker, ok := tool.(tools.HealthChecker)
+		if !ok {
+			continue
+		}
+		checks = append(checks, HealthCheck{
+			Name:  "tool:" + tool.Schema().Name,
+			Check: checker.CheckHealth,
+		})
+	}
+	return checks
+}
+
+// StoreCheck checks a store can accept writes; a store that cannot makes the server unavailable
+func StoreCheck(name string, ping func() error) HealthCheck {
+	return HealthCheck{
+		Name:     "store:" + name,
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			return ping()
+		},
+	}
+}
+
+// ReadinessHandler serves the readiness probe
+type ReadinessHandler struct {
+	checker *ReadinessChecker
+}
+
+// NewReadinessHandler creates a new readiness handler
+func NewReadinessHandler(checker *ReadinessChecker) *ReadinessHandler {
+	return &ReadinessHandler{checker: checker}
+}
+
+// RegisterRoutes registers the readiness route
+func (h *ReadinessHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(readinessPath, h.HandleReadiness)
+}
+
+// HandleReadiness reports each dependency's health. It responds 503 when a critical dependency is failing
+// so load balancers stop sending traffic, and 200 when the server is ready or only degraded.
+func (h *ReadinessHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	report := h.checker.Report()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == ReadinessUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Failed to encode readiness report: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"agent/internal/ai"
+	"agent/internal/models"
+)
+
+// defaultEscalationThreshold is the confidence below which a fast-tier assessment is redone by the strong tier
+const defaultEscalationThreshold = 0.6
+
+// registerFastTier adds the fast-tier model to a processor's provider, if one is configured
+func registerFastTier(provider *ai.Provider, modelType ai.ModelType, modelConfig ai.ModelConfig, fastModelName string) error {
+	if fastModelName == "" || fastModelName == modelConfig.ModelName {
+		return nil
+	}
+
+	modelConfig.ModelName = fastModelName
+	if err := provider.RegisterModel(ai.TierFast, modelType, modelConfig); err != nil {
+		return fmt.Errorf("failed to register fast model %s: %w", fastModelName, err)
+	}
+	return nil
+}
+
+// routeWithEscalation runs an assessment on the fast tier and repeats it on the strong tier when the result
+// is unknown, low-confidence or failed. A caller-chosen model, or a provider with no fast tier, gets one pass.
+func routeWithEscalation(
+	ctx context.Context,
+	provider *ai.Provider,
+	requestType ai.RequestType,
+	threshold float64,
+	assess func(model ai.Model) (*models.EmergencySituation, error),
+) (*models.EmergencySituation, error) {
+	if ai.ModelPreferenceFromContext(ctx) != "" || !provider.HasTier(ai.TierFast, requestType) {
+		return assess(provider.Route(ctx, requestType, ai.TierStrong))
+	}
+
+	fast := provider.Route(ctx, requestType, ai.TierFast)
+	situation, err := assess(fast)
+
+	var reason string
+	switch {
+	case err != nil:
+		reason = "fast_model_error"
+	case situation.Code == models.CodeUnknown:
+		reason = "unknown_code"
+	case situation.Confidence < threshold:
+		reason = "low_confidence"
+	default:
+		situation.Metadata["model_tier"] = string(ai.TierFast)
+		return situation, nil
+	}
+
+	if !provider.HasTier(ai.TierStrong, requestType) {
+		return situation, err
+	}
+
+	strong := provider.Route(ctx, requestType, ai.TierStrong)
+	escalated, strongErr := assess(strong)
+	if strongErr != nil {
+		if err != nil {
+			return nil, strongErr
+		}
+		// Keep the fast answer rather than fail the request outright
+		fmt.Printf("Warning: escalation to %s failed, keeping %s assessment: %v\n", strong.Name(), fast.Name(), strongErr)
+		situation.Metadata["model_tier"] = string(ai.TierFast)
+		situation.Metadata["escalation_failed"] = "true"
+		return situation, nil
+	}
+
+	escalated.Metadata["model_tier"] = string(ai.TierStrong)
+	escalated.Metadata["escalated_from"] = fast.Name()
+	escalated.Metadata["escalation_reason"] = reason
+	return escalated, nil
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"agent/internal/ai"
+	"agent/internal/models"
+)
+
+// ConversationSession holds the state of a multi-turn conversation with the triage agent
+type ConversationSession struct {
+	ID        string
+	Situation *models.EmergencySituation
+	Messages  []ai.Message
+
+	// LastResponse is the most recent coordinated response and DispatchedCode the triage code it was produced for
+	LastResponse   *EmergencyResponse
+	DispatchedCode models.TriageCode
+
+	CreatedAt time.Time
+
+	// owner is the principal of the client that started the conversation
+	owner string
+
+	// UpdatedAt and active are guarded by the store's mutex; active counts requests holding the session, which
+	// is never expired while in use
+	UpdatedAt time.Time
+	active    int
+
+	// mu serializes turns so concurrent messages in one session cannot interleave
+	mu sync.Mutex
+}
+
+// Lock acquires exclusive access to the session for a single conversation turn
+func (s *ConversationSession) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock releases the session after a conversation turn
+func (s *ConversationSession) Unlock() {
+	s.mu.Unlock()
+}
+
+// SessionStore keeps conversation sessions in memory and expires idle ones
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*ConversationSession
+	ttl      time.Duration
+}
+
+// NewSessionStore creates a new session store; sessions idle for longer than ttl are discarded
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	if ttl == 0 {
+		ttl = 30 * time.Minute
+	}
+
+	return &SessionStore{
+		sessions: make(map[string]*ConversationSession),
+		ttl:      ttl,
+	}
+}
+
+// Create starts a new, empty conversation session owned by the request's client; callers must Release it
+// when the request is done
+func (s *SessionStore) Create(ctx context.Context) *ConversationSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+
+	now := time.Now()
+	session := &ConversationSession{
+		ID:        newRandomID("session"),
+		CreatedAt: now,
+		UpdatedAt: now,
+		owner:     clientPrincipal(ctx),
+		active:    1,
+	}
+	s.sessions[session.ID] = session
+
+	return session
+}
+
+// Get returns a session by ID if it exists, belongs to the request's client and has not expired; callers
+// must Release it when the request is done
+func (s *SessionStore) Get(ctx context.Context, id string) (*ConversationSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !isOwner(ctx, session.owner) {
+		return nil, false
+	}
+
+	if s.expiredLocked(session) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+
+	session.active++
+	session.UpdatedAt = time.Now()
+	return session, true
+}
+
+// Release marks the end of a request using the session and restarts its idle timer
+func (s *SessionStore) Release(session *ConversationSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.active--
+	session.UpdatedAt = time.Now()
+}
+
+// pruneLocked removes expired sessions; the caller must hold s.mu
+func (s *SessionStore) pruneLocked() {
+	for id, session := range s.sessions {
+		if s.expiredLocked(session) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// expiredLocked reports whether a session is idle beyond the TTL; the caller must hold s.mu
+func (s *SessionStore) expiredLocked(session *ConversationSession) bool {
+	return session.active == 0 && time.Since(session.UpdatedAt) > s.ttl
+}
+
+// newRandomID returns a prefixed identifier that is unique across concurrent requests
+func newRandomID(prefix string) string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return prefix + "-" + time.Now().Format("20060102-150405.000000000")
+	}
+	return prefix + "-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"agent/internal/ai"
+)
+
+// ShadowHandler exposes shadow model evaluation results for review before a model is promoted
+type ShadowHandler struct {
+	log *ai.ShadowLog
+}
+
+// NewShadowHandler creates a new shadow evaluation API handler
+func NewShadowHandler(shadowLog *ai.ShadowLog) *ShadowHandler {
+	return &ShadowHandler{log: shadowLog}
+}
+
+// RegisterRoutes registers the shadow evaluation API routes
+func (h *ShadowHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/shadow/summary", h.HandleSummary)
+	mux.HandleFunc("/api/v1/shadow/records", h.HandleRecords)
+}
+
+// HandleSummary reports agreement and under-triage counts between the primary and shadow models
+func (h *ShadowHandler) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.log.Summary()); err != nil {
+		log.Printf("Failed to encode shadow summary: %v", err)
+	}
+}
+
+// HandleRecords lists paired outputs, newest first. Query parameters: disagreements=true,
+// under_triage=true and limit (default 100).
+func (h *ShadowHandler) HandleRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := ai.ShadowFilter{
+		DisagreementsOnly: query.Get("disagreements") == "true",
+		UnderTriageOnly:   query.Get("under_triage") == "true",
+		Limit:             100,
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	records := h.log.Records(filter)
+	if records == nil {
+		records = []ai.ShadowRecord{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"records": records,
+		"count":   len(records),
+	}); err != nil {
+		log.Printf("Failed to encode shadow records: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"agent/internal/models"
+)
+
+const (
+	// streamTimeout bounds a streamed request; the open connection lets it run longer than a plain one
+	streamTimeout = 5 * time.Minute
+
+	// streamKeepAlive is how often a comment is sent so proxies do not close an idle stream
+	streamKeepAlive = 15 * time.Second
+)
+
+// HandleEmergencyStream processes an audio, photo or text emergency and streams each pipeline stage to the
+// caller as server-sent events, ending with a completed or error event
+func (h *EmergencyHandler) HandleEmergencyStream(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Streaming is not supported by this server")
+		return
+	}
+
+	// Parse the request before the stream starts so invalid input gets a normal error response
+	var process func(ctx context.Context) (*EmergencyResponse, error)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		upload, ok := h.parseUpload(w, r)
+		if !ok {
+			return
+		}
+		defer upload.close()
+
+		process = func(ctx context.Context) (*EmergencyResponse, error) {
+			return h.processUpload(ctx, upload, func(partial *models.EmergencySituation) {
+				reportProgress(ctx, EventPreliminaryTriage, newTriageProgress(partial))
+			})
+		}
+	} else {
+		request, ok := h.parseTextRequest(w, r)
+		if !ok {
+			return
+		}
+
+		process = func(ctx context.Context) (*EmergencyResponse, error) {
+			return h.processTextRequest(ctx, request)
+		}
+	}
+
+	// The server's write timeout is meant for plain responses; a stream stays open until processing ends
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to extend write deadline for stream: %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), streamTimeout)
+	defer cancel()
+
+	events := make(chan ProgressEvent, 16)
+	ctx := WithProgress(timeoutCtx, func(event ProgressEvent) {
+		select {
+		case events <- event:
+		case <-timeoutCtx.Done():
+		}
+	})
+
+	done := make(chan struct{})
+	var response *EmergencyResponse
+	var err error
+	go func() {
+		defer close(done)
+		response, err = process(ctx)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if writeErr := writeEvent(w, event); writeErr != nil {
+				log.Printf("Failed to write progress event: %v", writeErr)
+				cancel()
+				<-done
+				return
+			}
+			flusher.Flush()
+
+		case <-keepAlive.C:
+			if _, writeErr := fmt.Fprint(w, ": keep-alive\n\n"); writeErr != nil {
+				cancel()
+				<-done
+				return
+			}
+			flusher.Flush()
+
+		case <-done:
+			// Deliver events sent before processing returned
+			for len(events) > 0 {
+				writeEvent(w, <-events)
+			}
+
+			requestID := RequestIDFromContext(r.Context())
+			final := ProgressEvent{Type: EventCompleted, Timestamp: time.Now().Format(time.RFC3339Nano)}
+			if err != nil {
+				final.Type = EventError
+				final.Data, _ = json.Marshal(serviceError("Failed to process emergency", err, requestID).detail(requestID))
+			} else if final.Data, err = json.Marshal(response); err != nil {
+				log.Printf("Failed to encode response: %v", err)
+				final.Type = EventError
+				final.Data, _ = json.Marshal(apiError{code: CodeInternal, message: "an internal error occurred"}.detail(requestID))
+			}
+			if writeErr := writeEvent(w, final); writeErr != nil {
+				log.Printf("Failed to write final progress event: %v", writeErr)
+			}
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// writeEvent writes one server-sent event frame
+func writeEvent(w http.ResponseWriter, event ProgressEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,216 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrNotSplittable is returned when a recording cannot be split without decoding it
+var ErrNotSplittable = errors.New("audio format cannot be split")
+
+// maxFmtChunk is the largest WAV fmt chunk kept for re-use in chunk headers (WAVE_FORMAT_EXTENSIBLE is 40 bytes)
+const maxFmtChunk = 64
+
+// WAV format tags
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// pcmLayout locates the raw samples of a WAV file
+type pcmLayout struct {
+	fmtChunk      []byte
+	formatTag     uint16
+	bitsPerSample int
+	blockAlign    int
+	dataOffset    int64
+	dataSize      int64
+}
+
+// newPCMLayout reads the sample layout from a WAV fmt chunk body
+func newPCMLayout(fmtChunk []byte) *pcmLayout {
+	layout := &pcmLayout{
+		fmtChunk:      fmtChunk,
+		formatTag:     binary.LittleEndian.Uint16(fmtChunk[0:2]),
+		blockAlign:    int(binary.LittleEndian.Uint16(fmtChunk[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(fmtChunk[14:16])),
+	}
+	// Extensible files carry the real format tag at the start of the sub-format GUID
+	if layout.formatTag == wavFormatExtensible && len(fmtChunk) >= 26 {
+		layout.formatTag = binary.LittleEndian.Uint16(fmtChunk[24:26])
+	}
+	return layout
+}
+
+// splittable reports whether the samples can be measured for silence
+func (l *pcmLayout) splittable() bool {
+	if l == nil || l.blockAlign == 0 {
+		return false
+	}
+	switch l.formatTag {
+	case wavFormatPCM:
+		return l.bitsPerSample == 8 || l.bitsPerSample == 16 || l.bitsPerSample == 24 || l.bitsPerSample == 32
+	case wavFormatFloat:
+		return l.bitsPerSample == 32
+	}
+	return false
+}
+
+// sample decodes one sample to the range [-1, 1]
+func (l *pcmLayout) sample(b []byte) float64 {
+	switch {
+	case l.formatTag == wavFormatFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case l.bitsPerSample == 8:
+		return (float64(b[0]) - 128) / 128
+	case l.bitsPerSample == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case l.bitsPerSample == 24:
+		return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+	}
+}
+
+// ChunkOptions controls where a recording is split
+type ChunkOptions struct {
+	TargetLength time.Duration // Preferred chunk length
+	SearchWindow time.Duration // How far either side of the target to look for silence
+	FrameLength  time.Duration // Length of the windows compared for loudness
+}
+
+// Chunk is a self-contained WAV segment of a longer recording
+type Chunk struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+
+	header []byte
+	data   *io.SectionReader
+}
+
+// Reader returns the chunk as a complete WAV file
+func (c *Chunk) Reader() io.Reader {
+	return io.MultiReader(bytes.NewReader(c.header), io.NewSectionReader(c.data, 0, c.data.Size()))
+}
+
+// Size returns the length of the chunk's WAV file in bytes
+func (c *Chunk) Size() int64 {
+	return int64(len(c.header)) + c.data.Size()
+}
+
+// CanSplit reports whether Split supports the recording
+func (info *Info) CanSplit() bool {
+	return info.pcm.splittable()
+}
+
+// Split cuts a PCM WAV recording into chunks of roughly opts.TargetLength, placing each cut at the quietest
+// frame near the target so that words are not split. Chunks share the underlying reader rather than copying it.
+func Split(r io.ReaderAt, info *Info, opts ChunkOptions) ([]Chunk, error) {
+	if !info.CanSplit() {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotSplittable, info.Format.Container, info.Format.Codec)
+	}
+	if opts.TargetLength <= 0 {
+		return nil, fmt.Errorf("chunk target length must be positive")
+	}
+	if opts.SearchWindow == 0 {
+		opts.SearchWindow = opts.TargetLength / 6
+	}
+	if opts.FrameLength == 0 {
+		opts.FrameLength = 20 * time.Millisecond
+	}
+
+	layout := info.pcm
+	bytesPerSecond := float64(info.SampleRate * layout.blockAlign)
+	toBytes := func(d time.Duration) int64 {
+		return int64(d.Seconds()*float64(info.SampleRate)) * int64(layout.blockAlign)
+	}
+	toDuration := func(n int64) time.Duration {
+		return time.Duration(float64(n) / bytesPerSecond * float64(time.Second))
+	}
+
+	target := toBytes(opts.TargetLength)
+	search := toBytes(opts.SearchWindow)
+	frame := max(toBytes(opts.FrameLength), int64(layout.blockAlign))
+	if target <= 0 {
+		return nil, fmt.Errorf("chunk target length is shorter than one sample")
+	}
+	// Keep every cut strictly after the previous one
+	search = min(search, target/2)
+	search -= search % int64(layout.blockAlign)
+
+	var cuts []int64
+	start := int64(0)
+	for layout.dataSize-start > target+search {
+		cut, err := quietestFrame(r, layout, start+target-search, start+target+search, frame)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, cut)
+		start = cut
+	}
+	cuts = append(cuts, layout.dataSize)
+
+	chunks := make([]Chunk, 0, len(cuts))
+	start = 0
+	for i, end := range cuts {
+		chunks = append(chunks, Chunk{
+			Index:  i,
+			Start:  toDuration(start),
+			End:    toDuration(end),
+			header: wavHeader(layout.fmtChunk, end-start),
+			data:   io.NewSectionReader(r, layout.dataOffset+start, end-start),
+		})
+		start = end
+	}
+	return chunks, nil
+}
+
+// quietestFrame returns the byte offset, relative to the data chunk, of the lowest-energy frame in [from, to)
+func quietestFrame(r io.ReaderAt, layout *pcmLayout, from, to, frame int64) (int64, error) {
+	bytesPerSample := int64(layout.bitsPerSample / 8)
+	buf := make([]byte, frame)
+	best, bestEnergy := to, math.Inf(1)
+
+	for offset := from; offset+frame <= to; offset += frame {
+		if _, err := r.ReadAt(buf, layout.dataOffset+offset); err != nil && err != io.EOF {
+			return 0, fmt.Errorf("%w: %v at offset %d", ErrCorrupt, err, layout.dataOffset+offset)
+		}
+
+		var energy float64
+		for i := int64(0); i+bytesPerSample <= frame; i += bytesPerSample {
+			s := layout.sample(buf[i : i+bytesPerSample])
+			energy += s * s
+		}
+		if energy < bestEnergy {
+			best, bestEnergy = offset+frame/2, energy
+		}
+	}
+
+	// Cut on a sample frame boundary so every chunk keeps whole samples for every channel
+	align := int64(layout.blockAlign)
+	return best - best%align, nil
+}
+
+// wavHeader builds a RIFF/WAVE header for dataSize bytes of samples in the original format
+func wavHeader(fmtChunk []byte, dataSize int64) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+len(fmtChunk)&1+8+int(dataSize)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+	if len(fmtChunk)&1 == 1 {
+		buf.WriteByte(0)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	return buf.Bytes()
+}
@@ -0,0 +1,732 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// parseWAV reads the fmt and data chunks of a RIFF/WAVE file
+func parseWAV(r io.ReaderAt, size int64, info *Info) error {
+	var byteRate uint32
+	var layout *pcmLayout
+	offset := int64(12)
+
+	for offset+8 <= size {
+		header, err := readAt(r, offset, 8)
+		if err != nil {
+			return err
+		}
+		chunkID := string(header[0:4])
+		chunkSize := binary.LittleEndian.Uint32(header[4:8])
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return fmt.Errorf("%w: WAV fmt chunk too short", ErrCorrupt)
+			}
+			fmtChunk, err := readAt(r, offset+8, int(min(chunkSize, maxFmtChunk)))
+			if err != nil {
+				return err
+			}
+			info.Channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:12])
+			if chunkSize <= maxFmtChunk {
+				layout = newPCMLayout(fmtChunk)
+			}
+
+		case "data":
+			if byteRate == 0 {
+				return fmt.Errorf("%w: WAV data chunk before a valid fmt chunk", ErrCorrupt)
+			}
+			// Streaming recorders often leave the size as 0 or 0xFFFFFFFF; trust the file length then
+			dataSize := int64(chunkSize)
+			if chunkSize == 0 || chunkSize == math.MaxUint32 || offset+8+dataSize > size {
+				dataSize = size - offset - 8
+			}
+			info.Duration = seconds(uint64(dataSize), uint64(byteRate))
+			if layout != nil {
+				layout.dataOffset = offset + 8
+				layout.dataSize = dataSize
+				info.pcm = layout
+			}
+			return nil
+		}
+
+		offset += 8 + int64(chunkSize) + int64(chunkSize&1)
+	}
+
+	return fmt.Errorf("%w: WAV file has no data chunk", ErrCorrupt)
+}
+
+// parseFLAC reads the STREAMINFO metadata block
+func parseFLAC(r io.ReaderAt, size int64, info *Info) error {
+	block, err := readAt(r, 4, 4+34)
+	if err != nil {
+		return err
+	}
+	if block[0]&0x7F != 0 {
+		return fmt.Errorf("%w: FLAC stream does not start with STREAMINFO", ErrCorrupt)
+	}
+
+	streamInfo := block[4:]
+	sampleRate := uint64(streamInfo[10])<<12 | uint64(streamInfo[11])<<4 | uint64(streamInfo[12])>>4
+	totalSamples := uint64(streamInfo[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(streamInfo[14:18]))
+	if sampleRate == 0 {
+		return fmt.Errorf("%w: FLAC sample rate is zero", ErrCorrupt)
+	}
+	if totalSamples == 0 {
+		return fmt.Errorf("%w: FLAC stream does not declare its length", ErrCorrupt)
+	}
+
+	info.SampleRate = int(sampleRate)
+	info.Channels = int((streamInfo[12]>>1)&0x07) + 1
+	info.Duration = seconds(totalSamples, sampleRate)
+	return nil
+}
+
+// parseOgg reads the codec header from the first page and the granule position of the last page
+func parseOgg(r io.ReaderAt, size int64, info *Info) error {
+	first, err := readAt(r, 0, int(min(size, 4096)))
+	if err != nil {
+		return err
+	}
+
+	var rate, preSkip uint64
+	if i := bytes.Index(first, []byte("OpusHead")); i >= 0 && i+19 <= len(first) {
+		info.Channels = int(first[i+9])
+		preSkip = uint64(binary.LittleEndian.Uint16(first[i+10 : i+12]))
+		info.SampleRate = int(binary.LittleEndian.Uint32(first[i+12 : i+16]))
+		rate = 48000 // Opus granule positions always count 48 kHz samples
+	} else if i := bytes.Index(first, []byte("\x01vorbis")); i >= 0 && i+16 <= len(first) {
+		info.Channels = int(first[i+11])
+		info.SampleRate = int(binary.LittleEndian.Uint32(first[i+12 : i+16]))
+		rate = uint64(info.SampleRate)
+	} else {
+		return fmt.Errorf("%w: Ogg stream is neither Opus nor Vorbis", ErrUnsupportedFormat)
+	}
+	if rate == 0 {
+		return fmt.Errorf("%w: Ogg sample rate is zero", ErrCorrupt)
+	}
+
+	// Search backwards for the last page that carries a granule position
+	const window = 64 * 1024
+	for end := size; end > 0; end -= window - 27 {
+		start := max(end-window, 0)
+		tail, err := readAt(r, start, int(end-start))
+		if err != nil {
+			return err
+		}
+
+		for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+			if i+14 > len(tail) || tail[i+4] != 0 {
+				continue
+			}
+			granule := binary.LittleEndian.Uint64(tail[i+6 : i+14])
+			if granule == math.MaxUint64 {
+				continue
+			}
+			if granule <= preSkip {
+				return nil // Duration stays zero and Analyze reports the recording as empty
+			}
+			info.Duration = seconds(granule-preSkip, rate)
+			return nil
+		}
+
+		if start == 0 {
+			break
+		}
+	}
+
+	return fmt.Errorf("%w: Ogg stream has no page with a granule position", ErrCorrupt)
+}
+
+// EBML element IDs used when walking a WebM file
+const (
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549A966
+	ebmlTimecodeScale = 0x2AD7B1
+	ebmlDuration      = 0x4489
+	ebmlTracks        = 0x1654AE6B
+	ebmlCluster       = 0x1F43B675
+	ebmlTimecode      = 0xE7
+	ebmlSimpleBlock   = 0xA3
+	ebmlBlockGroup    = 0xA0
+	ebmlBlock         = 0xA1
+)
+
+// ebmlTopLevel lists segment children that end a cluster of unknown size
+var ebmlTopLevel = map[uint32]bool{
+	0x114D9B74:  true, // SeekHead
+	ebmlInfo:    true,
+	ebmlTracks:  true,
+	ebmlCluster: true,
+	0x1C53BB6B:  true, // Cues
+	0x1043A770:  true, // Chapters
+	0x1254C367:  true, // Tags
+	0x1941A469:  true, // Attachments
+}
+
+// ebmlElement is a parsed element header
+type ebmlElement struct {
+	id        uint32
+	dataStart int64
+	dataEnd   int64
+	unknown   bool
+}
+
+// readEBMLElement parses the element header at off
+func readEBMLElement(r io.ReaderAt, off, limit int64) (ebmlElement, error) {
+	if limit-off < 2 {
+		return ebmlElement{}, fmt.Errorf("%w: truncated EBML element at offset %d", ErrCorrupt, off)
+	}
+	header, err := readAt(r, off, int(min(12, limit-off)))
+	if err != nil {
+		return ebmlElement{}, err
+	}
+
+	idLen := vintLength(header[0])
+	if idLen == 0 || idLen > 4 || idLen >= len(header) {
+		return ebmlElement{}, fmt.Errorf("%w: invalid EBML element ID at offset %d", ErrCorrupt, off)
+	}
+	var id uint32
+	for _, b := range header[:idLen] {
+		id = id<<8 | uint32(b)
+	}
+
+	sizeLen := vintLength(header[idLen])
+	if sizeLen == 0 || idLen+sizeLen > len(header) {
+		return ebmlElement{}, fmt.Errorf("%w: invalid EBML element size at offset %d", ErrCorrupt, off)
+	}
+	size := uint64(header[idLen] & (0xFF >> sizeLen))
+	allOnes := size == uint64(0xFF>>sizeLen)
+	for _, b := range header[idLen+1 : idLen+sizeLen] {
+		size = size<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+
+	element := ebmlElement{id: id, dataStart: off + int64(idLen+sizeLen), unknown: allOnes}
+	if allOnes || element.dataStart+int64(size) > limit {
+		element.dataEnd = limit
+	} else {
+		element.dataEnd = element.dataStart + int64(size)
+	}
+	return element, nil
+}
+
+// vintLength returns the length of an EBML variable-length integer from its first byte
+func vintLength(first byte) int {
+	for i := 0; i < 8; i++ {
+		if first&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseWebM reads the segment duration, falling back to the last block timestamp for live recordings
+func parseWebM(r io.ReaderAt, size int64, info *Info) error {
+	header, err := readEBMLElement(r, 0, size)
+	if err != nil {
+		return err
+	}
+
+	segment, err := readEBMLElement(r, header.dataEnd, size)
+	if err != nil {
+		return err
+	}
+	if segment.id != ebmlSegment {
+		return fmt.Errorf("%w: WebM file has no segment", ErrCorrupt)
+	}
+
+	timecodeScale := uint64(1000000) // nanoseconds per tick
+	var durationTicks float64
+	var lastTimecode int64
+
+	for pos := segment.dataStart; pos < segment.dataEnd; {
+		element, err := readEBMLElement(r, pos, segment.dataEnd)
+		if err != nil {
+			return err
+		}
+
+		switch element.id {
+		case ebmlInfo:
+			if err := walkEBML(r, element, func(child ebmlElement, data []byte) {
+				switch child.id {
+				case ebmlTimecodeScale:
+					timecodeScale = readUint(data)
+				case ebmlDuration:
+					durationTicks = readFloat(data)
+				}
+			}); err != nil {
+				return err
+			}
+
+		case ebmlTracks:
+			tracks, err := readAt(r, element.dataStart, int(min(element.dataEnd-element.dataStart, 64*1024)))
+			if err != nil {
+				return err
+			}
+			switch {
+			case bytes.Contains(tracks, []byte("A_OPUS")):
+				info.Format.Codec = "opus"
+			case bytes.Contains(tracks, []byte("A_VORBIS")):
+				info.Format.Codec = "vorbis"
+			case bytes.Contains(tracks, []byte("A_AAC")):
+				info.Format.Codec = "aac"
+			}
+
+		case ebmlCluster:
+			end, last, err := scanCluster(r, element)
+			if err != nil {
+				return err
+			}
+			lastTimecode = max(lastTimecode, last)
+			pos = end
+			continue
+		}
+
+		if element.unknown {
+			// Only clusters can be skipped without a size
+			break
+		}
+		pos = element.dataEnd
+	}
+
+	if durationTicks > 0 {
+		info.Duration = time.Duration(durationTicks * float64(timecodeScale))
+	} else {
+		info.Duration = time.Duration(lastTimecode) * time.Duration(timecodeScale)
+	}
+	return nil
+}
+
+// walkEBML calls fn with each small child element of a master element
+func walkEBML(r io.ReaderAt, parent ebmlElement, fn func(child ebmlElement, data []byte)) error {
+	for pos := parent.dataStart; pos < parent.dataEnd; {
+		child, err := readEBMLElement(r, pos, parent.dataEnd)
+		if err != nil {
+			return err
+		}
+		if length := child.dataEnd - child.dataStart; length <= 8 {
+			data, err := readAt(r, child.dataStart, int(length))
+			if err != nil {
+				return err
+			}
+			fn(child, data)
+		}
+		pos = child.dataEnd
+	}
+	return nil
+}
+
+// scanCluster returns where a cluster ends and the latest block timestamp inside it, in ticks
+func scanCluster(r io.ReaderAt, cluster ebmlElement) (int64, int64, error) {
+	var clusterTimecode, last int64
+
+	blockTime := func(blockStart int64, limit int64) error {
+		// Track number (a vint) followed by a signed 16-bit relative timecode
+		data, err := readAt(r, blockStart, int(min(limit-blockStart, 11)))
+		if err != nil {
+			return err
+		}
+		trackLen := vintLength(data[0])
+		if trackLen == 0 || trackLen+2 > len(data) {
+			return fmt.Errorf("%w: invalid WebM block header", ErrCorrupt)
+		}
+		relative := int16(binary.BigEndian.Uint16(data[trackLen : trackLen+2]))
+		last = max(last, clusterTimecode+int64(relative))
+		return nil
+	}
+
+	pos := cluster.dataStart
+	for pos < cluster.dataEnd {
+		child, err := readEBMLElement(r, pos, cluster.dataEnd)
+		if err != nil {
+			return 0, 0, err
+		}
+		if cluster.unknown && ebmlTopLevel[child.id] {
+			return pos, last, nil
+		}
+
+		switch child.id {
+		case ebmlTimecode:
+			data, err := readAt(r, child.dataStart, int(child.dataEnd-child.dataStart))
+			if err != nil {
+				return 0, 0, err
+			}
+			clusterTimecode = int64(readUint(data))
+		case ebmlSimpleBlock:
+			if err := blockTime(child.dataStart, child.dataEnd); err != nil {
+				return 0, 0, err
+			}
+		case ebmlBlockGroup:
+			for inner := child.dataStart; inner < child.dataEnd; {
+				block, err := readEBMLElement(r, inner, child.dataEnd)
+				if err != nil {
+					return 0, 0, err
+				}
+				if block.id == ebmlBlock {
+					if err := blockTime(block.dataStart, block.dataEnd); err != nil {
+						return 0, 0, err
+					}
+				}
+				inner = block.dataEnd
+			}
+		}
+		pos = child.dataEnd
+	}
+	return pos, last, nil
+}
+
+func readUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func readFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
+
+// mp4Box is a parsed ISO base media box header
+type mp4Box struct {
+	boxType   string
+	dataStart int64
+	dataEnd   int64
+}
+
+// readMP4Box parses the box header at off
+func readMP4Box(r io.ReaderAt, off, limit int64) (mp4Box, error) {
+	header, err := readAt(r, off, 8)
+	if err != nil {
+		return mp4Box{}, err
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	box := mp4Box{boxType: string(header[4:8]), dataStart: off + 8}
+	switch size {
+	case 0:
+		size = limit - off
+	case 1:
+		large, err := readAt(r, off+8, 8)
+		if err != nil {
+			return mp4Box{}, err
+		}
+		size = int64(binary.BigEndian.Uint64(large))
+		box.dataStart += 8
+	}
+	if size < box.dataStart-off || off+size > limit {
+		return mp4Box{}, fmt.Errorf("%w: MP4 box %q has an invalid size", ErrCorrupt, box.boxType)
+	}
+	box.dataEnd = off + size
+	return box, nil
+}
+
+// findMP4Box returns the first child box of the given type within [start, end)
+func findMP4Box(r io.ReaderAt, start, end int64, boxType string) (mp4Box, bool, error) {
+	for pos := start; pos+8 <= end; {
+		box, err := readMP4Box(r, pos, end)
+		if err != nil {
+			return mp4Box{}, false, err
+		}
+		if box.boxType == boxType {
+			return box, true, nil
+		}
+		pos = box.dataEnd
+	}
+	return mp4Box{}, false, nil
+}
+
+// parseMP4 reads the movie duration from mvhd and the codec of the first sound track
+func parseMP4(r io.ReaderAt, size int64, info *Info) error {
+	moov, found, err := findMP4Box(r, 0, size, "moov")
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: MP4 file has no moov box", ErrCorrupt)
+	}
+
+	mvhd, found, err := findMP4Box(r, moov.dataStart, moov.dataEnd, "mvhd")
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: MP4 file has no movie header", ErrCorrupt)
+	}
+	header, err := readAt(r, mvhd.dataStart, int(min(mvhd.dataEnd-mvhd.dataStart, 32)))
+	if err != nil {
+		return err
+	}
+
+	var timescale, duration uint64
+	if header[0] == 1 && len(header) >= 32 {
+		timescale = uint64(binary.BigEndian.Uint32(header[20:24]))
+		duration = binary.BigEndian.Uint64(header[24:32])
+	} else if len(header) >= 20 {
+		timescale = uint64(binary.BigEndian.Uint32(header[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(header[16:20]))
+	} else {
+		return fmt.Errorf("%w: MP4 movie header too short", ErrCorrupt)
+	}
+	if timescale == 0 {
+		return fmt.Errorf("%w: MP4 timescale is zero", ErrCorrupt)
+	}
+	info.Duration = seconds(duration, timescale)
+
+	// Find the sound track to confirm this is audio and identify its codec
+	for pos := moov.dataStart; pos+8 <= moov.dataEnd; {
+		trak, err := readMP4Box(r, pos, moov.dataEnd)
+		if err != nil {
+			return err
+		}
+		pos = trak.dataEnd
+		if trak.boxType != "trak" {
+			continue
+		}
+
+		codec, sampleRate, isSound, err := mp4TrackCodec(r, trak)
+		if err != nil {
+			return err
+		}
+		if isSound {
+			info.Format.Codec = codec
+			info.SampleRate = sampleRate
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: MP4 file has no audio track", ErrUnsupportedFormat)
+}
+
+// mp4TrackCodec reports whether a track is a sound track, and if so its codec and sample rate
+func mp4TrackCodec(r io.ReaderAt, trak mp4Box) (string, int, bool, error) {
+	mdia, found, err := findMP4Box(r, trak.dataStart, trak.dataEnd, "mdia")
+	if err != nil || !found {
+		return "", 0, false, err
+	}
+
+	hdlr, found, err := findMP4Box(r, mdia.dataStart, mdia.dataEnd, "hdlr")
+	if err != nil || !found {
+		return "", 0, false, err
+	}
+	handler, err := readAt(r, hdlr.dataStart, 12)
+	if err != nil {
+		return "", 0, false, err
+	}
+	if string(handler[8:12]) != "soun" {
+		return "", 0, false, nil
+	}
+
+	sampleRate := 0
+	if mdhd, found, err := findMP4Box(r, mdia.dataStart, mdia.dataEnd, "mdhd"); err == nil && found {
+		if header, err := readAt(r, mdhd.dataStart, 24); err == nil {
+			if header[0] == 1 {
+				sampleRate = int(binary.BigEndian.Uint32(header[20:24]))
+			} else {
+				sampleRate = int(binary.BigEndian.Uint32(header[12:16]))
+			}
+		}
+	}
+
+	codec := "aac"
+	box := mdia
+	for _, boxType := range []string{"minf", "stbl", "stsd"} {
+		box, found, err = findMP4Box(r, box.dataStart, box.dataEnd, boxType)
+		if err != nil {
+			return "", 0, false, err
+		}
+		if !found {
+			return codec, sampleRate, true, nil
+		}
+	}
+
+	// stsd: version/flags (4), entry count (4), then the first sample entry's size (4) and type (4)
+	entry, err := readAt(r, box.dataStart, 16)
+	if err != nil {
+		return "", 0, false, err
+	}
+	switch string(entry[12:16]) {
+	case "alac":
+		codec = "alac"
+	case "Opus":
+		codec = "opus"
+	case "fLaC":
+		codec = "flac"
+	case "mp4a":
+		codec = "aac"
+	default:
+		return "", 0, false, fmt.Errorf("%w: unsupported MP4 audio codec %q", ErrUnsupportedFormat, string(entry[12:16]))
+	}
+	return codec, sampleRate, true, nil
+}
+
+// MPEG audio tables indexed by version (0: MPEG-1, 1: MPEG-2/2.5) and layer (0: I, 1: II, 2: III)
+var (
+	mp3Bitrates = [2][3][16]int{
+		{
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		},
+		{
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		},
+	}
+	mp3SampleRates = map[byte][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mp3Frame is a decoded MPEG audio frame header
+type mp3Frame struct {
+	bitrate         int // bits per second
+	sampleRate      int
+	samplesPerFrame int
+	length          int
+	channels        int
+	sideInfo        int
+}
+
+// parseMP3Frame decodes a 4-byte frame header
+func parseMP3Frame(h []byte) (mp3Frame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	versionBits := (h[1] >> 3) & 0x03
+	layerBits := (h[1] >> 1) & 0x03
+	bitrateIndex := h[2] >> 4
+	rateIndex := (h[2] >> 2) & 0x03
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	version := 1
+	if versionBits == 3 {
+		version = 0
+	}
+	layer := 3 - int(layerBits) // 0: I, 1: II, 2: III
+	padding := int((h[2] >> 1) & 0x01)
+	mono := h[3]>>6 == 3
+
+	frame := mp3Frame{
+		bitrate:    mp3Bitrates[version][layer][bitrateIndex] * 1000,
+		sampleRate: mp3SampleRates[versionBits][rateIndex],
+		channels:   2,
+	}
+	if mono {
+		frame.channels = 1
+	}
+
+	switch {
+	case layer == 0:
+		frame.samplesPerFrame = 384
+		frame.length = (12*frame.bitrate/frame.sampleRate + padding) * 4
+	case layer == 2 && version == 1:
+		frame.samplesPerFrame = 576
+		frame.length = 72*frame.bitrate/frame.sampleRate + padding
+	default:
+		frame.samplesPerFrame = 1152
+		frame.length = 144*frame.bitrate/frame.sampleRate + padding
+	}
+
+	// Side information precedes the Xing header in layer III frames
+	switch {
+	case version == 0 && !mono:
+		frame.sideInfo = 32
+	case version == 0 || !mono:
+		frame.sideInfo = 17
+	default:
+		frame.sideInfo = 9
+	}
+	return frame, true
+}
+
+// parseMP3 skips any ID3v2 tag, finds the first frame and computes the duration from the Xing/VBRI
+// frame count when present, or from the bitrate otherwise
+func parseMP3(r io.ReaderAt, size int64, info *Info) error {
+	offset := int64(0)
+	if tag, err := readAt(r, 0, 10); err == nil && bytes.Equal(tag[0:3], []byte("ID3")) {
+		tagSize := int64(tag[6]&0x7F)<<21 | int64(tag[7]&0x7F)<<14 | int64(tag[8]&0x7F)<<7 | int64(tag[9]&0x7F)
+		offset = 10 + tagSize
+		if tag[5]&0x10 != 0 {
+			offset += 10 // Footer present
+		}
+	}
+
+	// Find the first frame whose successor is also a valid frame
+	search, err := readAt(r, offset, int(min(size-offset, 64*1024)))
+	if err != nil {
+		return err
+	}
+	var frame mp3Frame
+	start := -1
+	for i := 0; i+4 <= len(search); i++ {
+		candidate, ok := parseMP3Frame(search[i : i+4])
+		if !ok {
+			continue
+		}
+		next := i + candidate.length
+		if next+4 <= len(search) {
+			if _, ok := parseMP3Frame(search[next : next+4]); !ok {
+				continue
+			}
+		} else if offset+int64(next) < size {
+			continue
+		}
+		frame, start = candidate, i
+		break
+	}
+	if start < 0 {
+		return fmt.Errorf("%w: no valid MPEG audio frame found", ErrCorrupt)
+	}
+
+	info.SampleRate = frame.sampleRate
+	info.Channels = frame.channels
+	frameOffset := offset + int64(start)
+
+	// VBR files carry a frame count in a Xing/Info or VBRI header inside the first frame
+	if first, err := readAt(r, frameOffset, int(min(int64(frame.length), size-frameOffset))); err == nil {
+		if x := 4 + frame.sideInfo; x+12 <= len(first) {
+			tag := string(first[x : x+4])
+			if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(first[x+4:x+8])&0x01 != 0 {
+				frames := uint64(binary.BigEndian.Uint32(first[x+8 : x+12]))
+				info.Duration = seconds(frames*uint64(frame.samplesPerFrame), uint64(frame.sampleRate))
+				return nil
+			}
+		}
+		if v := 4 + 32; v+18 <= len(first) && string(first[v:v+4]) == "VBRI" {
+			frames := uint64(binary.BigEndian.Uint32(first[v+14 : v+18]))
+			info.Duration = seconds(frames*uint64(frame.samplesPerFrame), uint64(frame.sampleRate))
+			return nil
+		}
+	}
+
+	// Constant bitrate: the audio payload size divided by the byte rate, excluding an ID3v1 tag
+	audioBytes := size - frameOffset
+	if audioBytes > 128 {
+		if tag, err := readAt(r, size-128, 3); err == nil && string(tag) == "TAG" {
+			audioBytes -= 128
+		}
+	}
+	info.Duration = seconds(uint64(audioBytes)*8, uint64(frame.bitrate))
+	return nil
+}
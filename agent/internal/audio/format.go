@@ -0,0 +1,158 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when the data is not a recognised audio container
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+	// ErrCorrupt is returned when the container is recognised but cannot be parsed
+	ErrCorrupt = errors.New("corrupt audio file")
+
+	// ErrTooLong is returned when a recording exceeds the configured maximum length
+	ErrTooLong = errors.New("audio recording too long")
+
+	// ErrEmpty is returned for recordings with no audio
+	ErrEmpty = errors.New("audio recording is empty")
+)
+
+// Format describes an audio container and codec
+type Format struct {
+	Container string // wav, mp3, ogg, flac, m4a or webm
+	Codec     string // pcm, mp3, opus, vorbis, flac, aac, alac
+	MIMEType  string
+}
+
+// Extension returns the conventional file extension for the container
+func (f Format) Extension() string {
+	return f.Container
+}
+
+// Info describes a parsed recording
+type Info struct {
+	Format     Format
+	Duration   time.Duration
+	SampleRate int
+	Channels   int
+
+	pcm *pcmLayout // set for WAV files whose samples can be split without decoding
+}
+
+// headerSize is how many leading bytes Detect needs
+const headerSize = 64
+
+// Detect identifies the audio container from its leading magic bytes
+func Detect(header []byte) (Format, error) {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return Format{Container: "wav", Codec: "pcm", MIMEType: "audio/wav"}, nil
+
+	case len(header) >= 4 && bytes.Equal(header[0:4], []byte("fLaC")):
+		return Format{Container: "flac", Codec: "flac", MIMEType: "audio/flac"}, nil
+
+	case len(header) >= 4 && bytes.Equal(header[0:4], []byte("OggS")):
+		codec := "vorbis"
+		if bytes.Contains(header, []byte("OpusHead")) {
+			codec = "opus"
+		}
+		return Format{Container: "ogg", Codec: codec, MIMEType: "audio/ogg"}, nil
+
+	case len(header) >= 4 && bytes.Equal(header[0:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return Format{Container: "webm", Codec: "opus", MIMEType: "audio/webm"}, nil
+
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return Format{Container: "m4a", Codec: "aac", MIMEType: "audio/mp4"}, nil
+
+	case len(header) >= 3 && bytes.Equal(header[0:3], []byte("ID3")):
+		return Format{Container: "mp3", Codec: "mp3", MIMEType: "audio/mpeg"}, nil
+
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		// Raw ADTS AAC uses the same sync word with layer bits of zero
+		if header[1]&0x06 == 0 {
+			return Format{}, fmt.Errorf("%w: raw AAC streams must be wrapped in an M4A container", ErrUnsupportedFormat)
+		}
+		return Format{Container: "mp3", Codec: "mp3", MIMEType: "audio/mpeg"}, nil
+	}
+
+	return Format{}, ErrUnsupportedFormat
+}
+
+// Analyze detects the container of a recording and parses its duration without decoding the audio
+func Analyze(r io.ReaderAt, size int64) (*Info, error) {
+	if size == 0 {
+		return nil, ErrEmpty
+	}
+
+	header := make([]byte, headerSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read audio header: %w", err)
+	}
+
+	format, err := Detect(header[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Format: format}
+	switch format.Container {
+	case "wav":
+		err = parseWAV(r, size, info)
+	case "flac":
+		err = parseFLAC(r, size, info)
+	case "ogg":
+		err = parseOgg(r, size, info)
+	case "webm":
+		err = parseWebM(r, size, info)
+	case "m4a":
+		err = parseMP4(r, size, info)
+	case "mp3":
+		err = parseMP3(r, size, info)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Duration <= 0 {
+		return nil, fmt.Errorf("%w: %s recording has no audio", ErrEmpty, format.Container)
+	}
+	return info, nil
+}
+
+// Validate rejects recordings longer than maxLength; a zero maxLength disables the check
+func (info *Info) Validate(maxLength time.Duration) error {
+	if maxLength > 0 && info.Duration > maxLength {
+		return fmt.Errorf("%w: %s exceeds the %s limit", ErrTooLong, info.Duration.Round(time.Second), maxLength)
+	}
+	return nil
+}
+
+// readAt reads exactly n bytes at off, reporting a short read as corruption
+func readAt(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: truncated at offset %d", ErrCorrupt, off)
+	}
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, off)
+	if read == n {
+		return buf, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("%w: %v at offset %d", ErrCorrupt, err, off)
+}
+
+// seconds converts a sample count at a rate into a duration
+func seconds(samples uint64, rate uint64) time.Duration {
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / float64(rate) * float64(time.Second))
+}
@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// LivePCM collects raw PCM samples from a live stream and cuts them into WAV chunks at pauses.
+// It is not safe for concurrent use.
+type LivePCM struct {
+	info   Info
+	layout *pcmLayout
+	data   []byte
+	cut    int64 // bytes already returned in chunks
+	index  int
+}
+
+// NewLivePCM creates a collector for PCM samples laid out as in a WAV file (little-endian, 8-bit unsigned)
+func NewLivePCM(sampleRate, channels, bitsPerSample int) (*LivePCM, error) {
+	if sampleRate < 8000 || sampleRate > 192000 {
+		return nil, fmt.Errorf("%w: sample rate %d", ErrUnsupportedFormat, sampleRate)
+	}
+	if channels < 1 || channels > 8 {
+		return nil, fmt.Errorf("%w: %d channels", ErrUnsupportedFormat, channels)
+	}
+	if bitsPerSample != 8 && bitsPerSample != 16 && bitsPerSample != 24 && bitsPerSample != 32 {
+		return nil, fmt.Errorf("%w: %d bits per sample", ErrUnsupportedFormat, bitsPerSample)
+	}
+
+	blockAlign := channels * bitsPerSample / 8
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:2], wavFormatPCM)
+	binary.LittleEndian.PutUint16(fmtChunk[2:4], uint16(channels))
+	binary.LittleEndian.PutUint32(fmtChunk[4:8], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(fmtChunk[8:12], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[12:14], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[14:16], uint16(bitsPerSample))
+
+	layout := newPCMLayout(fmtChunk)
+	return &LivePCM{
+		info: Info{
+			Format:     Format{Container: "wav", Codec: "pcm", MIMEType: "audio/wav"},
+			SampleRate: sampleRate,
+			Channels:   channels,
+			pcm:        layout,
+		},
+		layout: layout,
+	}, nil
+}
+
+// Write appends samples received from the stream
+func (l *LivePCM) Write(p []byte) (int, error) {
+	l.data = append(l.data, p...)
+	l.info.Duration = l.toDuration(int64(len(l.data)))
+	return len(p), nil
+}
+
+// Info describes the audio collected so far
+func (l *LivePCM) Info() *Info {
+	info := l.info
+	return &info
+}
+
+// Duration returns the length of the audio collected so far
+func (l *LivePCM) Duration() time.Duration {
+	return l.info.Duration
+}
+
+// Next returns the next chunk once at least length of audio is waiting, cutting at the quietest frame in
+// the second half of what is waiting so that words are not split. With final set, whatever remains is
+// returned regardless of length.
+func (l *LivePCM) Next(length time.Duration, final bool) (Chunk, bool) {
+	align := int64(l.layout.blockAlign)
+	end := int64(len(l.data))
+	end -= end % align
+	pending := end - l.cut
+	if pending <= 0 {
+		return Chunk{}, false
+	}
+
+	if !final {
+		target := l.toBytes(length)
+		if pending < target {
+			return Chunk{}, false
+		}
+
+		frame := max(l.toBytes(20*time.Millisecond), align)
+		from := l.cut + target/2
+		from -= from % align
+		layout := *l.layout
+		layout.dataOffset = 0
+		cut, err := quietestFrame(bytes.NewReader(l.data[:end]), &layout, from, end, frame)
+		if err == nil && cut > l.cut {
+			end = cut
+		}
+	}
+
+	// Later writes only append, so the slice stays valid for the chunk's lifetime
+	data := l.data[l.cut:end]
+	chunk := Chunk{
+		Index:  l.index,
+		Start:  l.toDuration(l.cut),
+		End:    l.toDuration(end),
+		header: wavHeader(l.layout.fmtChunk, int64(len(data))),
+		data:   io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))),
+	}
+	l.cut = end
+	l.index++
+	return chunk, true
+}
+
+// toBytes converts a duration into a whole number of sample frames in bytes
+func (l *LivePCM) toBytes(d time.Duration) int64 {
+	return int64(d.Seconds()*float64(l.info.SampleRate)) * int64(l.layout.blockAlign)
+}
+
+// toDuration converts a byte count into a duration
+func (l *LivePCM) toDuration(n int64) time.Duration {
+	return time.Duration(float64(n) / float64(l.info.SampleRate*l.layout.blockAlign) * float64(time.Second))
+}
@@ -0,0 +1,234 @@
+// Package auth authenticates API clients by hashed API key or signed JWT and describes what they may do
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no API key or token
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials is returned for an unknown API key or a token that fails verification
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Scope is a role granted to a client
+type Scope string
+
+const (
+	// ScopeCaller may report emergencies and manage its own patient profiles
+	ScopeCaller Scope = "caller"
+
+	// ScopeDispatcher may review and update reported emergencies
+	ScopeDispatcher Scope = "dispatcher"
+
+	// ScopeClinician may review and update emergencies and read any patient profile
+	ScopeClinician Scope = "clinician"
+
+	// ScopeAdmin may do anything, including operational endpoints
+	ScopeAdmin Scope = "admin"
+)
+
+// AllScopes lists every scope a client can be granted
+var AllScopes = []Scope{ScopeCaller, ScopeDispatcher, ScopeClinician, ScopeAdmin}
+
+// ParseScope validates a scope name
+func ParseScope(name string) (Scope, error) {
+	for _, scope := range AllScopes {
+		if Scope(strings.ToLower(strings.TrimSpace(name))) == scope {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scope %q", name)
+}
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Client is an authenticated caller of the API
+type Client struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name,omitempty"`
+	Method string  `json:"method"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// Principal identifies the client uniquely across authentication methods, such as "jwt:user-42"
+func (c *Client) Principal() string {
+	return c.Method + ":" + c.ID
+}
+
+// HasAny reports whether the client holds any of the scopes; admins hold them all
+func (c *Client) HasAny(scopes ...Scope) bool {
+	for _, held := range c.Scopes {
+		if held == ScopeAdmin {
+			return true
+		}
+		for _, scope := range scopes {
+			if held == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// APIKey is a configured key. Only the key's hash is kept, so a leaked configuration does not leak keys.
+type APIKey struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	KeyHash string  `json:"key_hash"` // "sha256:" followed by the hex digest, as produced by HashKey
+	Scopes  []Scope `json:"scopes"`
+}
+
+// keyPrefix marks generated API keys so they are recognisable in logs and secret scanners
+const keyPrefix = "rtk_"
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashKey returns the form in which an API key is configured. Keys are long and random, so a fast hash
+// is enough to keep them secret.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys reads a JSON array of API keys from a file
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Config contains the credentials an Authenticator accepts
+type Config struct {
+	APIKeys []APIKey
+
+	// JWTSecret verifies HS256 tokens; tokens are rejected when it is empty
+	JWTSecret   []byte
+	JWTIssuer   string        // Required "iss" claim, if set
+	JWTAudience string        // Required "aud" claim, if set
+	Leeway      time.Duration // Allowed clock skew when checking exp and nbf
+}
+
+// minJWTSecret is the shortest HS256 secret accepted; shorter secrets can be brute-forced
+const minJWTSecret = 32
+
+// Authenticator verifies API keys and JWTs
+type Authenticator struct {
+	keys        map[string]*Client // by key hash
+	jwtSecret   []byte
+	jwtIssuer   string
+	jwtAudience string
+	leeway      time.Duration
+	now         func() time.Time
+}
+
+// NewAuthenticator creates an authenticator, rejecting malformed or duplicate keys and weak secrets
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	if len(config.APIKeys) == 0 && len(config.JWTSecret) == 0 {
+		return nil, errors.New("no API keys or JWT secret configured")
+	}
+	if len(config.JWTSecret) > 0 && len(config.JWTSecret) < minJWTSecret {
+		return nil, fmt.Errorf("JWT secret must be at least %d bytes", minJWTSecret)
+	}
+	if config.Leeway == 0 {
+		config.Leeway = 30 * time.Second // Default clock skew
+	}
+
+	a := &Authenticator{
+		keys:        make(map[string]*Client, len(config.APIKeys)),
+		jwtSecret:   config.JWTSecret,
+		jwtIssuer:   config.JWTIssuer,
+		jwtAudience: config.JWTAudience,
+		leeway:      config.Leeway,
+		now:         time.Now,
+	}
+
+	ids := make(map[string]bool, len(config.APIKeys))
+	for _, key := range config.APIKeys {
+		if key.ID == "" {
+			return nil, errors.New("every API key needs an id")
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate API key id %q", key.ID)
+		}
+		ids[key.ID] = true
+
+		digest, ok := strings.CutPrefix(key.KeyHash, "sha256:")
+		if decoded, err := hex.DecodeString(digest); !ok || err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: key_hash must be sha256:<64 hex digits>", key.ID)
+		}
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("API key %q has no scopes", key.ID)
+		}
+		client := &Client{ID: key.ID, Name: key.Name, Method: MethodAPIKey}
+		for _, name := range key.Scopes {
+			scope, err := ParseScope(string(name))
+			if err != nil {
+				return nil, fmt.Errorf("API key %q: %w", key.ID, err)
+			}
+			client.Scopes = append(client.Scopes, scope)
+		}
+
+		a.keys[strings.ToLower(key.KeyHash)] = client
+	}
+	return a, nil
+}
+
+// Authenticate identifies the client presenting credential, which is either an API key or a JWT
+func (a *Authenticator) Authenticate(credential string) (*Client, error) {
+	if credential == "" {
+		return nil, ErrNoCredentials
+	}
+	if strings.Count(credential, ".") == 2 {
+		return a.verifyJWT(credential)
+	}
+
+	client, ok := a.keys[HashKey(credential)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	copied := *client
+	return &copied, nil
+}
+
+type clientKey struct{}
+
+// WithClient returns a context carrying the authenticated client
+func WithClient(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client attached by WithClient, or nil when authentication is disabled
+func ClientFromContext(ctx context.Context) *Client {
+	client, _ := ctx.Value(clientKey{}).(*Client)
+	return client
+}
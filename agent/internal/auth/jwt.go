@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwtHeader is the JOSE header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+// jwtClaims are the registered and private claims read from a token
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Name      string          `json:"name"`
+	Scope     string          `json:"scope"` // Space-separated scopes
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // A string or an array of strings
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// verifyJWT checks an HS256 token's signature and claims and returns the client it names
+func (a *Authenticator) verifyJWT(token string) (*Client, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, fmt.Errorf("%w: tokens are not accepted", ErrInvalidCredentials)
+	}
+
+	parts := strings.Split(token, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrInvalidCredentials)
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: bad token signature", ErrInvalidCredentials)
+	}
+
+	// Checked after the signature, but the algorithm is pinned so a token cannot pick a weaker one
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, fmt.Errorf("%w: token must be signed with HS256", ErrInvalidCredentials)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrInvalidCredentials)
+	}
+
+	now := a.now()
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidCredentials)
+	}
+	if now.After(numericDate(*claims.ExpiresAt).Add(a.leeway)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if claims.NotBefore != nil && now.Add(a.leeway).Before(numericDate(*claims.NotBefore)) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	if a.jwtIssuer != "" && claims.Issuer != a.jwtIssuer {
+		return nil, fmt.Errorf("%w: wrong token issuer", ErrInvalidCredentials)
+	}
+	if a.jwtAudience != "" && !audienceContains(claims.Audience, a.jwtAudience) {
+		return nil, fmt.Errorf("%w: wrong token audience", ErrInvalidCredentials)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	client := &Client{ID: claims.Subject, Name: claims.Name, Method: MethodJWT}
+	for _, name := range strings.Fields(claims.Scope) {
+		// Scopes meant for other services are ignored
+		if scope, err := ParseScope(name); err == nil {
+			client.Scopes = append(client.Scopes, scope)
+		}
+	}
+	return client, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate (seconds since the epoch) to a time
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// audienceContains reports whether an "aud" claim names audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, value := range list {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
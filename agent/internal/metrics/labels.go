@@ -0,0 +1,257 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are histogram upper bounds in seconds, spanning a quick tool call to a slow model request
+var LatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// CounterVec is a family of counters that share a name and are told apart by label values
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+// counterSeries is one counter in a family and its label values
+type counterSeries struct {
+	values  []string
+	counter *Counter
+}
+
+// With returns the counter for the given label values, in the order the labels were declared
+func (v *CounterVec) With(values ...string) *Counter {
+	key := seriesKey(v.name, v.labels, values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if series, ok := v.series[key]; ok {
+		return series.counter
+	}
+	series := &counterSeries{values: append([]string(nil), values...), counter: &Counter{name: v.name, help: v.help}}
+	v.series[key] = series
+	return series.counter
+}
+
+// all returns every counter in the family, ordered by label values
+func (v *CounterVec) all() []*counterSeries {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]*counterSeries, len(keys))
+	for i, key := range keys {
+		all[i] = v.series[key]
+	}
+	return all
+}
+
+// GaugeVec is a family of gauges that share a name and are told apart by label values
+type GaugeVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*gaugeSeries
+}
+
+// gaugeSeries is one gauge in a family and its label values
+type gaugeSeries struct {
+	values []string
+	gauge  *Gauge
+}
+
+// With returns the gauge for the given label values, in the order the labels were declared
+func (v *GaugeVec) With(values ...string) *Gauge {
+	key := seriesKey(v.name, v.labels, values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if series, ok := v.series[key]; ok {
+		return series.gauge
+	}
+	series := &gaugeSeries{values: append([]string(nil), values...), gauge: &Gauge{name: v.name, help: v.help}}
+	v.series[key] = series
+	return series.gauge
+}
+
+// all returns every gauge in the family, ordered by label values
+func (v *GaugeVec) all() []*gaugeSeries {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]*gaugeSeries, len(keys))
+	for i, key := range keys {
+		all[i] = v.series[key]
+	}
+	return all
+}
+
+// Histogram counts observations into buckets by upper bound
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64 // Observations per bucket, with the last counting those above every bound
+	sum    float64
+	count  uint64
+}
+
+// Observe records one value
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.buckets, value)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// read returns the cumulative count at each bucket bound, the sum and the count of observations
+func (h *Histogram) read() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative := make([]uint64, len(h.buckets))
+	var total uint64
+	for i := range h.buckets {
+		total += h.counts[i]
+		cumulative[i] = total
+	}
+	return cumulative, h.sum, h.count
+}
+
+// HistogramVec is a family of histograms that share a name and buckets and are told apart by label values
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries is one histogram in a family and its label values
+type histogramSeries struct {
+	values    []string
+	histogram *Histogram
+}
+
+// With returns the histogram for the given label values, in the order the labels were declared
+func (v *HistogramVec) With(values ...string) *Histogram {
+	key := seriesKey(v.name, v.labels, values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if series, ok := v.series[key]; ok {
+		return series.histogram
+	}
+	histogram := &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets)+1)}
+	v.series[key] = &histogramSeries{values: append([]string(nil), values...), histogram: histogram}
+	return histogram
+}
+
+// all returns every histogram in the family, ordered by label values
+func (v *HistogramVec) all() []*histogramSeries {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]*histogramSeries, len(keys))
+	for i, key := range keys {
+		all[i] = v.series[key]
+	}
+	return all
+}
+
+// seriesKey identifies a series by its label values; a wrong number of values is a programming error
+func seriesKey(name string, labels, values []string) string {
+	if len(values) != len(labels) {
+		panic(fmt.Sprintf("metrics: %s has labels %v but got %d values", name, labels, len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelMap pairs label names with their values
+func labelMap(labels, values []string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	pairs := make(map[string]string, len(labels))
+	for i, label := range labels {
+		pairs[label] = values[i]
+	}
+	return pairs
+}
+
+// labelString formats labels as Prometheus does, sorted by name; it is empty when there are none
+func labelString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(labels[name]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values for the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue formats a sample value as the Prometheus text format expects
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
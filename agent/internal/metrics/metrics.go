@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a monotonically increasing count
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n int64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	name string
+	help string
+	bits atomic.Uint64
+}
+
+// Set replaces the gauge value
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+// Add adjusts the gauge value by delta
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if g.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Value returns the current gauge value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// Metric describes one metric value in a snapshot. A histogram's value is its number of observations.
+type Metric struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help,omitempty"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+	Sum    float64           `json:"sum,omitempty"` // Total of a histogram's observations
+}
+
+// Registry holds named metrics. Asking for an existing name returns the same metric.
+type Registry struct {
+	mu          sync.Mutex
+	counters    map[string]*Counter
+	gauges      map[string]*Gauge
+	counterVecs map[string]*CounterVec
+	gaugeVecs   map[string]*GaugeVec
+	histograms  map[string]*HistogramVec
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:    make(map[string]*Counter),
+		gauges:      make(map[string]*Gauge),
+		counterVecs: make(map[string]*CounterVec),
+		gaugeVecs:   make(map[string]*GaugeVec),
+		histograms:  make(map[string]*HistogramVec),
+	}
+}
+
+// Default is the process-wide registry
+var Default = NewRegistry()
+
+// Counter returns the counter with the given name, creating it if needed
+func (r *Registry) Counter(name, help string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if counter, ok := r.counters[name]; ok {
+		return counter
+	}
+	counter := &Counter{name: name, help: help}
+	r.counters[name] = counter
+	return counter
+}
+
+// Gauge returns the gauge with the given name, creating it if needed
+func (r *Registry) Gauge(name, help string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gauge, ok := r.gauges[name]; ok {
+		return gauge
+	}
+	gauge := &Gauge{name: name, help: help}
+	r.gauges[name] = gauge
+	return gauge
+}
+
+// CounterVec returns the family of counters with the given name and label names, creating it if needed
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if vec, ok := r.counterVecs[name]; ok {
+		return vec
+	}
+	vec := &CounterVec{name: name, help: help, labels: labels, series: make(map[string]*counterSeries)}
+	r.counterVecs[name] = vec
+	return vec
+}
+
+// GaugeVec returns the family of gauges with the given name and label names, creating it if needed
+func (r *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if vec, ok := r.gaugeVecs[name]; ok {
+		return vec
+	}
+	vec := &GaugeVec{name: name, help: help, labels: labels, series: make(map[string]*gaugeSeries)}
+	r.gaugeVecs[name] = vec
+	return vec
+}
+
+// HistogramVec returns the family of histograms with the given name, buckets and label names, creating it
+// if needed. Nil buckets mean LatencyBuckets.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if vec, ok := r.histograms[name]; ok {
+		return vec
+	}
+	if buckets == nil {
+		buckets = LatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	vec := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	r.histograms[name] = vec
+	return vec
+}
+
+// Snapshot returns the current value of every metric, sorted by name and then labels
+func (r *Registry) Snapshot() []Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make([]Metric, 0, len(r.counters)+len(r.gauges))
+	for _, counter := range r.counters {
+		snapshot = append(snapshot, Metric{Name: counter.name, Help: counter.help, Type: "counter", Value: float64(counter.Value())})
+	}
+	for _, gauge := range r.gauges {
+		snapshot = append(snapshot, Metric{Name: gauge.name, Help: gauge.help, Type: "gauge", Value: gauge.Value()})
+	}
+	for _, vec := range r.counterVecs {
+		for _, series := range vec.all() {
+			snapshot = append(snapshot, Metric{
+				Name: vec.name, Help: vec.help, Type: "counter",
+				Labels: labelMap(vec.labels, series.values), Value: float64(series.counter.Value()),
+			})
+		}
+	}
+	for _, vec := range r.gaugeVecs {
+		for _, series := range vec.all() {
+			snapshot = append(snapshot, Metric{
+				Name: vec.name, Help: vec.help, Type: "gauge",
+				Labels: labelMap(vec.labels, series.values), Value: series.gauge.Value(),
+			})
+		}
+	}
+	for _, vec := range r.histograms {
+		for _, series := range vec.all() {
+			_, sum, count := series.histogram.read()
+			snapshot = append(snapshot, Metric{
+				Name: vec.name, Help: vec.help, Type: "histogram",
+				Labels: labelMap(vec.labels, series.values), Value: float64(count), Sum: sum,
+			})
+		}
+	}
+
+	sort.SliceStable(snapshot, func(i, j int) bool {
+		if snapshot[i].Name != snapshot[j].Name {
+			return snapshot[i].Name < snapshot[j].Name
+		}
+		return labelString(snapshot[i].Labels) < labelString(snapshot[j].Labels)
+	})
+	return snapshot
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PrometheusContentType is the media type of the Prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// helpEscaper escapes HELP text for the Prometheus text format
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// family is one metric name with its type, help and samples, ready to be written
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []string
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format, sorted by name and then
+// label values
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	families := r.families()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	buffered := bufio.NewWriter(w)
+	for _, f := range families {
+		if f.help != "" {
+			fmt.Fprintf(buffered, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+		}
+		fmt.Fprintf(buffered, "# TYPE %s %s\n", f.name, f.kind)
+		for _, sample := range f.samples {
+			buffered.WriteString(sample)
+			buffered.WriteByte('\n')
+		}
+	}
+	return buffered.Flush()
+}
+
+// families collects the samples of every metric
+func (r *Registry) families() []*family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	families := make([]*family, 0, len(r.counters)+len(r.gauges)+len(r.counterVecs)+len(r.gaugeVecs)+len(r.histograms))
+	for _, counter := range r.counters {
+		families = append(families, &family{name: counter.name, help: counter.help, kind: "counter",
+			samples: []string{sample(counter.name, nil, float64(counter.Value()))}})
+	}
+	for _, gauge := range r.gauges {
+		families = append(families, &family{name: gauge.name, help: gauge.help, kind: "gauge",
+			samples: []string{sample(gauge.name, nil, gauge.Value())}})
+	}
+	for _, vec := range r.counterVecs {
+		f := &family{name: vec.name, help: vec.help, kind: "counter"}
+		for _, series := range vec.all() {
+			f.samples = append(f.samples, sample(vec.name, labelMap(vec.labels, series.values), float64(series.counter.Value())))
+		}
+		families = append(families, f)
+	}
+	for _, vec := range r.gaugeVecs {
+		f := &family{name: vec.name, help: vec.help, kind: "gauge"}
+		for _, series := range vec.all() {
+			f.samples = append(f.samples, sample(vec.name, labelMap(vec.labels, series.values), series.gauge.Value()))
+		}
+		families = append(families, f)
+	}
+	for _, vec := range r.histograms {
+		f := &family{name: vec.name, help: vec.help, kind: "histogram"}
+		for _, series := range vec.all() {
+			labels := labelMap(vec.labels, series.values)
+			cumulative, sum, count := series.histogram.read()
+			for i, bound := range vec.buckets {
+				f.samples = append(f.samples, sample(vec.name+"_bucket", withLabel(labels, "le", formatValue(bound)), float64(cumulative[i])))
+			}
+			f.samples = append(f.samples,
+				sample(vec.name+"_bucket", withLabel(labels, "le", formatValue(math.Inf(1))), float64(count)),
+				sample(vec.name+"_sum", labels, sum),
+				sample(vec.name+"_count", labels, float64(count)),
+			)
+		}
+		families = append(families, f)
+	}
+	return families
+}
+
+// sample formats one line of the text format
+func sample(name string, labels map[string]string, value float64) string {
+	return name + labelString(labels) + " " + formatValue(value)
+}
+
+// withLabel returns a copy of labels with one more
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	copied := make(map[string]string, len(labels)+1)
+	for label, existing := range labels {
+		copied[label] = existing
+	}
+	copied[name] = value
+	return copied
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Symptom durations offered by the Assessment screen
+const (
+	DurationUnderADay   = "less-than-24"
+	DurationOneToThree  = "1-3-days"
+	DurationThreeToWeek = "3-7-days"
+	DurationOverAWeek   = "more-than-week"
+)
+
+// SymptomAssessment is a structured self-assessment of symptoms
+type SymptomAssessment struct {
+	Symptoms    []string   `json:"symptoms,omitempty"`
+	Description string     `json:"description,omitempty"` // Free text in the patient's own words
+	Onset       *time.Time `json:"onset,omitempty"`
+	Duration    string     `json:"duration,omitempty"`   // One of the Duration constants; Onset takes precedence
+	PainScore   *int       `json:"pain_score,omitempty"` // 0 (none) to 10 (worst imaginable)
+	Age         int        `json:"age,omitempty"`
+	Sex         string     `json:"sex,omitempty"`
+	Pregnant    *bool      `json:"pregnant,omitempty"`
+	Conditions  []string   `json:"conditions,omitempty"`
+	Medications []string   `json:"medications,omitempty"`
+	Vitals      *Vitals    `json:"vitals,omitempty"`
+}
+
+// Acute reports whether the symptoms started within the last day
+func (a *SymptomAssessment) Acute(now time.Time) bool {
+	if a.Onset != nil {
+		return now.Sub(*a.Onset) < 24*time.Hour
+	}
+	return a.Duration == DurationUnderADay
+}
+
+// Longstanding reports whether the symptoms have lasted more than a week
+func (a *SymptomAssessment) Longstanding(now time.Time) bool {
+	if a.Onset != nil {
+		return now.Sub(*a.Onset) > 7*24*time.Hour
+	}
+	return a.Duration == DurationOverAWeek
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/rand"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -21,6 +24,20 @@ const (
 	CodeUnknown TriageCode = "UNKNOWN"
 )
 
+// Severity returns a numeric rank for the triage code, higher meaning more urgent
+func (c TriageCode) Severity() int {
+	switch c {
+	case CodeRed:
+		return 3
+	case CodeYellow:
+		return 2
+	case CodeGreen:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // EmergencySituation represents a medical emergency situation
 type EmergencySituation struct {
 	ID               string             `json:"id"`
@@ -33,6 +50,13 @@ type EmergencySituation struct {
 	EmotionalMarkers map[string]float64 `json:"emotional_markers,omitempty"`
 	Keywords         []string           `json:"keywords,omitempty"`
 	Metadata         map[string]string  `json:"metadata,omitempty"`
+	Transcript       *Transcript        `json:"transcript,omitempty"`
+	Vitals           *Vitals            `json:"vitals,omitempty"`
+	CodeHistory      []CodeChange       `json:"code_history,omitempty"`
+	SubmittedBy      string             `json:"submitted_by,omitempty"` // The authenticated client that reported it
+
+	// SourceText is the caller's own text, kept server-side so rule-based checks don't depend on model output
+	SourceText string `json:"-"`
 }
 
 // Location represents geolocation information
@@ -44,16 +68,109 @@ type Location struct {
 
 // PatientInfo contains basic information about the patient
 type PatientInfo struct {
-	Name      string   `json:"name,omitempty"`
-	Age       int      `json:"age,omitempty"`
-	Gender    string   `json:"gender,omitempty"`
-	Allergies []string `json:"allergies,omitempty"`
+	ProfileID         string             `json:"profile_id,omitempty"` // The patient profile the details came from
+	Name              string             `json:"name,omitempty"`
+	Age               int                `json:"age,omitempty"`
+	Gender            string             `json:"gender,omitempty"`
+	Pregnant          *bool              `json:"pregnant,omitempty"`
+	Allergies         []string           `json:"allergies,omitempty"`
+	Conditions        []string           `json:"conditions,omitempty"`
+	Medications       []string           `json:"medications,omitempty"`
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts,omitempty"`
+	AdvanceDirectives *AdvanceDirectives `json:"advance_directives,omitempty"`
+}
+
+// Vitals are the patient's most recent observations; zero values were not measured
+type Vitals struct {
+	HeartRate        int     `json:"heart_rate,omitempty"`        // beats per minute
+	RespiratoryRate  int     `json:"respiratory_rate,omitempty"`  // breaths per minute
+	SystolicBP       int     `json:"systolic_bp,omitempty"`       // mmHg
+	DiastolicBP      int     `json:"diastolic_bp,omitempty"`      // mmHg
+	OxygenSaturation float64 `json:"oxygen_saturation,omitempty"` // SpO2 percentage
+	Temperature      float64 `json:"temperature,omitempty"`       // degrees Celsius
+	GlasgowComaScale int     `json:"glasgow_coma_scale,omitempty"`
+	Breathing        *bool   `json:"breathing,omitempty"`
+	Conscious        *bool   `json:"conscious,omitempty"`
+}
+
+// Merge overwrites the observations that newer measures, keeping the rest
+func (v *Vitals) Merge(newer *Vitals) {
+	if newer.HeartRate != 0 {
+		v.HeartRate = newer.HeartRate
+	}
+	if newer.RespiratoryRate != 0 {
+		v.RespiratoryRate = newer.RespiratoryRate
+	}
+	if newer.SystolicBP != 0 {
+		v.SystolicBP = newer.SystolicBP
+	}
+	if newer.DiastolicBP != 0 {
+		v.DiastolicBP = newer.DiastolicBP
+	}
+	if newer.OxygenSaturation != 0 {
+		v.OxygenSaturation = newer.OxygenSaturation
+	}
+	if newer.Temperature != 0 {
+		v.Temperature = newer.Temperature
+	}
+	if newer.GlasgowComaScale != 0 {
+		v.GlasgowComaScale = newer.GlasgowComaScale
+	}
+	if newer.Breathing != nil {
+		v.Breathing = newer.Breathing
+	}
+	if newer.Conscious != nil {
+		v.Conscious = newer.Conscious
+	}
+}
+
+// String lists the measured observations, most critical first
+func (v *Vitals) String() string {
+	var parts []string
+	if v.Breathing != nil {
+		parts = append(parts, map[bool]string{true: "breathing", false: "NOT BREATHING"}[*v.Breathing])
+	}
+	if v.Conscious != nil {
+		parts = append(parts, map[bool]string{true: "conscious", false: "UNCONSCIOUS"}[*v.Conscious])
+	}
+	if v.HeartRate > 0 {
+		parts = append(parts, fmt.Sprintf("heart rate %d bpm", v.HeartRate))
+	}
+	if v.RespiratoryRate > 0 {
+		parts = append(parts, fmt.Sprintf("respiratory rate %d/min", v.RespiratoryRate))
+	}
+	if v.SystolicBP > 0 {
+		parts = append(parts, fmt.Sprintf("blood pressure %d/%d mmHg", v.SystolicBP, v.DiastolicBP))
+	}
+	if v.OxygenSaturation > 0 {
+		parts = append(parts, fmt.Sprintf("SpO2 %.0f%%", v.OxygenSaturation))
+	}
+	if v.Temperature > 0 {
+		parts = append(parts, fmt.Sprintf("temperature %.1f C", v.Temperature))
+	}
+	if v.GlasgowComaScale > 0 {
+		parts = append(parts, fmt.Sprintf("GCS %d", v.GlasgowComaScale))
+	}
+	if len(parts) == 0 {
+		return "none measured"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CodeChange is one entry in the history of a situation's triage code
+type CodeChange struct {
+	Version    int        `json:"version"`
+	Code       TriageCode `json:"code"`
+	Previous   TriageCode `json:"previous_code,omitempty"`
+	Confidence float64    `json:"confidence"`
+	Reason     string     `json:"reason"`
+	ChangedAt  time.Time  `json:"changed_at"`
 }
 
 // NewEmergencySituation creates a new emergency situation with default values
 func NewEmergencySituation(description string) *EmergencySituation {
 	return &EmergencySituation{
-		ID:          generateUUID(),
+		ID:          generateUUID("emergency"),
 		Description: description,
 		Code:        CodeUnknown,
 		Confidence:  0.0,
@@ -62,10 +179,15 @@ func NewEmergencySituation(description string) *EmergencySituation {
 	}
 }
 
-// Helper function to generate a UUID
-func generateUUID() string {
-	// In a real implementation, you'd use a proper UUID library
-	return "emergency-" + time.Now().Format("20060102-150405.000")
+// generateUUID returns a prefixed random (version 4) UUID, unique across concurrent requests
+func generateUUID(prefix string) string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return prefix + "-" + time.Now().Format("20060102-150405.000000000")
+	}
+	b[6] = b[6]&0x0F | 0x40 // version 4
+	b[8] = b[8]&0x3F | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%s-%x-%x-%x-%x-%x", prefix, b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 // SetTriageCode sets the triage code and confidence level
@@ -78,3 +200,25 @@ func (e *EmergencySituation) SetTriageCode(code TriageCode, confidence float64)
 func (e *EmergencySituation) IsLifeThreatening() bool {
 	return e.Code == CodeRed
 }
+
+// RecordCode appends the current triage code to the history if it differs from the last recorded one,
+// reporting whether it did
+func (e *EmergencySituation) RecordCode(reason string) bool {
+	var previous TriageCode
+	if n := len(e.CodeHistory); n > 0 {
+		previous = e.CodeHistory[n-1].Code
+		if previous == e.Code {
+			return false
+		}
+	}
+
+	e.CodeHistory = append(e.CodeHistory, CodeChange{
+		Version:    len(e.CodeHistory) + 1,
+		Code:       e.Code,
+		Previous:   previous,
+		Confidence: e.Confidence,
+		Reason:     reason,
+		ChangedAt:  time.Now(),
+	})
+	return true
+}
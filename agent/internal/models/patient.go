@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PatientProfile is a patient's standing medical record, referenced by emergency requests
+type PatientProfile struct {
+	ID                string             `json:"id"`
+	Name              string             `json:"name,omitempty"`
+	DateOfBirth       string             `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	Gender            string             `json:"gender,omitempty"`
+	Allergies         []string           `json:"allergies,omitempty"`
+	Medications       []string           `json:"medications,omitempty"`
+	Conditions        []string           `json:"conditions,omitempty"`
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts,omitempty"`
+	AdvanceDirectives *AdvanceDirectives `json:"advance_directives,omitempty"`
+	Owner             string             `json:"owner,omitempty"` // The client that created the profile
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+// EmergencyContact is someone to inform when the patient has an emergency
+type EmergencyContact struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship,omitempty"`
+	Phone        string `json:"phone"`
+}
+
+// AdvanceDirectives are the patient's recorded wishes about treatment
+type AdvanceDirectives struct {
+	DoNotResuscitate bool   `json:"do_not_resuscitate"`
+	HealthcareProxy  string `json:"healthcare_proxy,omitempty"` // Who decides when the patient cannot
+	Notes            string `json:"notes,omitempty"`
+}
+
+// NewPatientProfileID returns a unique ID for a new profile
+func NewPatientProfileID() string {
+	return generateUUID("patient")
+}
+
+// dateOfBirthLayout is the format of PatientProfile.DateOfBirth
+const dateOfBirthLayout = "2006-01-02"
+
+// Birth parses the date of birth
+func (p *PatientProfile) Birth() (time.Time, error) {
+	return time.Parse(dateOfBirthLayout, p.DateOfBirth)
+}
+
+// Age returns the patient's age in whole years at now, or 0 when the date of birth is unknown
+func (p *PatientProfile) Age(now time.Time) int {
+	birth, err := p.Birth()
+	if err != nil || birth.After(now) {
+		return 0
+	}
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || now.Month() == birth.Month() && now.Day() < birth.Day() {
+		age--
+	}
+	return age
+}
+
+// PatientInfo returns the profile as the patient details of an emergency
+func (p *PatientProfile) PatientInfo(now time.Time) *PatientInfo {
+	return &PatientInfo{
+		ProfileID:         p.ID,
+		Name:              p.Name,
+		Age:               p.Age(now),
+		Gender:            p.Gender,
+		Allergies:         p.Allergies,
+		Medications:       p.Medications,
+		Conditions:        p.Conditions,
+		EmergencyContacts: p.EmergencyContacts,
+		AdvanceDirectives: p.AdvanceDirectives,
+	}
+}
+
+// Merge fills the details info lacks from other, keeping info's own values and combining its lists
+func (info *PatientInfo) Merge(other *PatientInfo) {
+	if info.ProfileID == "" {
+		info.ProfileID = other.ProfileID
+	}
+	if info.Name == "" {
+		info.Name = other.Name
+	}
+	if info.Age == 0 {
+		info.Age = other.Age
+	}
+	if info.Gender == "" {
+		info.Gender = other.Gender
+	}
+	if info.Pregnant == nil {
+		info.Pregnant = other.Pregnant
+	}
+	info.Allergies = mergeStrings(info.Allergies, other.Allergies)
+	info.Medications = mergeStrings(info.Medications, other.Medications)
+	info.Conditions = mergeStrings(info.Conditions, other.Conditions)
+	if len(info.EmergencyContacts) == 0 {
+		info.EmergencyContacts = other.EmergencyContacts
+	}
+	if info.AdvanceDirectives == nil {
+		info.AdvanceDirectives = other.AdvanceDirectives
+	}
+}
+
+// Describe lists the clinically relevant details, one per line
+func (info *PatientInfo) Describe() string {
+	var lines []string
+	if info.Age > 0 {
+		lines = append(lines, fmt.Sprintf("Age: %d", info.Age))
+	}
+	if info.Gender != "" {
+		lines = append(lines, "Gender: "+info.Gender)
+	}
+	if info.Pregnant != nil && *info.Pregnant {
+		lines = append(lines, "Pregnant: yes")
+	}
+	if len(info.Allergies) > 0 {
+		lines = append(lines, "Allergies: "+strings.Join(info.Allergies, ", "))
+	}
+	if len(info.Medications) > 0 {
+		lines = append(lines, "Medications: "+strings.Join(info.Medications, ", "))
+	}
+	if len(info.Conditions) > 0 {
+		lines = append(lines, "Chronic conditions: "+strings.Join(info.Conditions, ", "))
+	}
+	if d := info.AdvanceDirectives; d != nil {
+		if d.DoNotResuscitate {
+			lines = append(lines, "Advance directive: DO NOT RESUSCITATE")
+		}
+		if d.HealthcareProxy != "" {
+			lines = append(lines, "Healthcare proxy: "+d.HealthcareProxy)
+		}
+		if d.Notes != "" {
+			lines = append(lines, "Directive notes: "+d.Notes)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mergeStrings appends the values of extra not already in values, ignoring case
+func mergeStrings(values, extra []string) []string {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[strings.ToLower(value)] = true
+	}
+	for _, value := range extra {
+		if !seen[strings.ToLower(value)] {
+			values = append(values, value)
+			seen[strings.ToLower(value)] = true
+		}
+	}
+	return values
+}
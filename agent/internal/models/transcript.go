@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Speaker identifies who is talking in a transcript segment
+type Speaker string
+
+const (
+	// SpeakerCaller is the person who placed the call
+	SpeakerCaller Speaker = "caller"
+
+	// SpeakerBystander is anyone else audible on the call, including the patient
+	SpeakerBystander Speaker = "bystander"
+
+	// SpeakerUnknown is used when the provider cannot tell speakers apart
+	SpeakerUnknown Speaker = "unknown"
+)
+
+// ParseSpeaker maps a provider's speaker label onto a Speaker
+func ParseSpeaker(label string) Speaker {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "caller":
+		return SpeakerCaller
+	case "bystander", "patient", "other":
+		return SpeakerBystander
+	default:
+		return SpeakerUnknown
+	}
+}
+
+// TranscriptSegment is one timed utterance of a call
+type TranscriptSegment struct {
+	Index        int     `json:"index"`
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Speaker      Speaker `json:"speaker"`
+	Text         string  `json:"text"`
+}
+
+// KeywordSource links an extracted keyword to the transcript segments it was heard in
+type KeywordSource struct {
+	Keyword  string `json:"keyword"`
+	Segments []int  `json:"segments"`
+}
+
+// Transcript is the verbatim record of a voice call
+type Transcript struct {
+	Segments       []TranscriptSegment `json:"segments"`
+	Diarized       bool                `json:"diarized"` // whether speakers were told apart by the provider
+	KeywordSources []KeywordSource     `json:"keyword_sources,omitempty"`
+}
+
+// Text renders the transcript as one timestamped line per segment
+func (t *Transcript) Text() string {
+	var b strings.Builder
+	for _, segment := range t.Segments {
+		fmt.Fprintf(&b, "[%s-%s]", formatOffset(segment.StartSeconds), formatOffset(segment.EndSeconds))
+		if t.Diarized {
+			fmt.Fprintf(&b, " %s:", segment.Speaker)
+		}
+		fmt.Fprintf(&b, " %s\n", segment.Text)
+	}
+	return b.String()
+}
+
+// LinkKeywords records, for each keyword, the segments that mention it. A segment matches if it contains
+// the keyword as a phrase or, failing that, every word of it.
+func (t *Transcript) LinkKeywords(keywords []string) {
+	t.KeywordSources = nil
+	for _, keyword := range keywords {
+		phrase := strings.ToLower(strings.TrimSpace(keyword))
+		if phrase == "" {
+			continue
+		}
+
+		var matches []int
+		for _, segment := range t.Segments {
+			if strings.Contains(strings.ToLower(segment.Text), phrase) {
+				matches = append(matches, segment.Index)
+			}
+		}
+		if len(matches) == 0 {
+			words := strings.Fields(phrase)
+			for _, segment := range t.Segments {
+				if containsAll(strings.ToLower(segment.Text), words) {
+					matches = append(matches, segment.Index)
+				}
+			}
+		}
+
+		if len(matches) > 0 {
+			t.KeywordSources = append(t.KeywordSources, KeywordSource{Keyword: keyword, Segments: matches})
+		}
+	}
+}
+
+// containsAll reports whether text contains every word
+func containsAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+// formatOffset formats a position in a recording as mm:ss
+func formatOffset(seconds float64) string {
+	s := int(seconds + 0.5)
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
@@ -0,0 +1,328 @@
+// Package openapi builds OpenAPI 3 documents from Go types and validates request bodies against them
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI specification version documents are written in
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the schemas and security schemes operations refer to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes one way of authenticating
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on one path, keyed by lower-case HTTP method
+type PathItem map[string]*Operation
+
+// Operation describes one method on one path
+type Operation struct {
+	Summary     string               `json:"summary"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+
+	// Public operations need no credentials, overriding the document's security requirement
+	Public bool `json:"-"`
+}
+
+// MarshalJSON writes the operation, with an empty security requirement when it is public
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	type operation Operation
+	if !o.Public {
+		return json.Marshal((*operation)(o))
+	}
+	return json.Marshal(struct {
+		*operation
+		Security []map[string][]string `json:"security"`
+	}{(*operation)(o), []map[string][]string{}})
+}
+
+// Parameter describes a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the accepted request bodies by media type
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 uses it
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Property returns a property of an object schema, or nil
+func (s *Schema) Property(name string) *Schema {
+	return s.Properties[name]
+}
+
+// Require marks properties as required and returns the schema
+func (s *Schema) Require(names ...string) *Schema {
+	s.Required = append(s.Required, names...)
+	return s
+}
+
+// Range sets the inclusive bounds of a number and returns the schema
+func (s *Schema) Range(min, max float64) *Schema {
+	s.Minimum, s.Maximum = &min, &max
+	return s
+}
+
+// NonEmpty requires a string to have at least one character, or an array one item, and returns the schema
+func (s *Schema) NonEmpty() *Schema {
+	one := 1
+	if s.Type == "array" {
+		s.MinItems = &one
+	} else {
+		s.MinLength = &one
+	}
+	return s
+}
+
+// OneOf restricts the schema to the given values and returns it
+func (s *Schema) OneOf(values ...interface{}) *Schema {
+	s.Enum = values
+	return s
+}
+
+// Describe sets the description and returns the schema
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+// New creates an empty document
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+	}
+}
+
+// Add documents an operation; path templates use {name} segments
+func (d *Document) Add(method, path string, operation *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// Find returns the operation documented for a request. Template segments such as {id} match any one
+// segment, and the path with the most literal segments wins, so /emergency/text is not taken for /emergency/{id}.
+func (d *Document) Find(method, path string) *Operation {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	method = strings.ToLower(method)
+
+	var found *Operation
+	best := -1
+	for template, item := range d.Paths {
+		operation, ok := (*item)[method]
+		if !ok {
+			continue
+		}
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+
+		literals := 0
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				if segments[i] == "" {
+					literals = -1
+					break
+				}
+				continue
+			}
+			if part != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+		if literals > best {
+			found, best = operation, literals
+		}
+	}
+	return found
+}
+
+// Component returns the named component schema, or nil
+func (d *Document) Component(name string) *Schema {
+	return d.Components.Schemas[name]
+}
+
+// Ref returns a reference to a component schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// SchemaOf returns the schema of v's type. Named struct types are added to the components, once, and
+// referred to; everything else is inlined.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor builds the schema of a type following encoding/json's rules
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		return d.schemaFor(t.Elem())
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t == rawMessageType {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Registered before the fields are walked so recursive types terminate
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return Ref(name)
+	}
+	// Interfaces and anything else accept any value
+	return &Schema{}
+}
+
+// structSchema builds the object schema of a struct, flattening embedded structs as encoding/json does
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for property, value := range d.structSchema(embedded).Properties {
+					schema.Properties[property] = value
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.schemaFor(field.Type)
+		if property.Ref == "" {
+			property.Nullable = field.Type.Kind() == reflect.Pointer || field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
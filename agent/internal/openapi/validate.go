@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldError describes one way a value fails its schema
+type FieldError struct {
+	Field   string `json:"field,omitempty"` // Dotted path such as "location.latitude" or "symptoms[2]"; empty for the whole body
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Validate checks a JSON body against a schema and returns every field that fails it
+func (d *Document) Validate(schema *Schema, body []byte) []FieldError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{Message: "body is not valid JSON"}}
+	}
+
+	var errs []FieldError
+	d.validate(schema, value, "", &errs)
+	return errs
+}
+
+// resolve follows a component reference
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// validate appends the ways value fails schema to errs
+func (d *Document) validate(schema *Schema, value interface{}, field string, errs *[]FieldError) {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		// Optional fields may be sent as null; required ones are checked by their parent
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if property, present := object[name]; !present || property == nil {
+				*errs = append(*errs, FieldError{Field: join(field, name), Message: "is required"})
+			}
+		}
+		// Sorted so errors come out in a stable order
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				d.validate(property, object[name], join(field, name), errs)
+			} else if schema.AdditionalProperties != nil {
+				d.validate(schema.AdditionalProperties, object[name], join(field, name), errs)
+			}
+			// Other unknown properties are ignored so older clients keep working
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			fail("must have at least %d item(s)", *schema.MinItems)
+		}
+		for i, item := range items {
+			d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if schema.MinLength != nil && len(strings.TrimSpace(text)) < *schema.MinLength {
+			fail("must not be empty")
+		}
+		if schema.MaxLength != nil && len(text) > *schema.MaxLength {
+			fail("must be at most %d characters", *schema.MaxLength)
+		}
+		if text == "" {
+			// An empty string means the field was left out, as encoding/json's omitempty has it
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				fail("must be an RFC 3339 date-time such as 2024-04-23T14:05:00Z")
+			}
+		}
+		if schema.Format == "date" {
+			if _, err := time.Parse("2006-01-02", text); err != nil {
+				fail("must be a date such as 2024-04-23")
+			}
+		}
+		d.validateEnum(schema, text, fail)
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		if schema.Type == "integer" {
+			if _, err := number.Int64(); err != nil {
+				fail("must be a whole number")
+				return
+			}
+		}
+		n, err := number.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			fail("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			fail("must be at most %v", *schema.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be true or false")
+		}
+	}
+}
+
+// validateEnum checks a string against the schema's allowed values
+func (d *Document) validateEnum(schema *Schema, text string, fail func(string, ...interface{})) {
+	if len(schema.Enum) == 0 {
+		return
+	}
+	allowed := make([]string, 0, len(schema.Enum))
+	for _, value := range schema.Enum {
+		if fmt.Sprint(value) == text {
+			return
+		}
+		allowed = append(allowed, fmt.Sprint(value))
+	}
+	fail("must be one of %s", strings.Join(allowed, ", "))
+}
+
+// join appends a property name to a field path
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
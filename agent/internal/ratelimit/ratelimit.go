@@ -0,0 +1,165 @@
+// Package ratelimit provides token-bucket rate limiters keyed by client
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a sustained rate with a burst allowance. The zero Limit allows everything.
+type Limit struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket size: requests allowed at once after a quiet period
+}
+
+// Unlimited reports whether the limit allows every request
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 && l.Burst <= 0
+}
+
+// String formats the limit as ParseLimit accepts it, in requests per minute
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%s/m:%d", strconv.FormatFloat(l.Rate*60, 'f', -1, 64), l.Burst)
+}
+
+// ParseLimit parses a limit such as "10/m:20": ten requests a minute with bursts of twenty. The unit may be
+// s, m or h, and the burst defaults to one period's worth of requests.
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "unlimited" {
+		return Limit{}, nil
+	}
+
+	rateSpec, burstSpec, hasBurst := strings.Cut(spec, ":")
+	countSpec, unit, ok := strings.Cut(rateSpec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like 10/m or 10/m:20", spec)
+	}
+
+	count, err := strconv.ParseFloat(countSpec, 64)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: request count must be a positive number", spec)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("limit %q: unit must be s, m or h", spec)
+	}
+
+	limit := Limit{Rate: count / period.Seconds(), Burst: int(count)}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstSpec)
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("limit %q: burst must be a positive integer", spec)
+		}
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return limit, nil
+}
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// bucket is one key's token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter applies one limit separately to every key
+type Limiter struct {
+	limit Limit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter creates a limiter that gives every key its own bucket
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Limit returns the limit applied to each key
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow takes a token from key's bucket, reporting false when it is empty
+func (l *Limiter) Allow(key string) bool {
+	if l.limit.Unlimited() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = l.refill(b, now)
+		b.updated = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Len returns the number of keys being tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// refill returns the tokens in b at now, capped at the burst
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*l.limit.Rate
+	if tokens > float64(l.limit.Burst) {
+		tokens = float64(l.limit.Burst)
+	}
+	return tokens
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket would be identical.
+// The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
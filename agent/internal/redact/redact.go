@@ -0,0 +1,286 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EntityType identifies a category of personally identifiable information
+type EntityType string
+
+const (
+	// EntityName is a person's name
+	EntityName EntityType = "NAME"
+
+	// EntityPhone is a telephone number
+	EntityPhone EntityType = "PHONE"
+
+	// EntityAddress is a street address
+	EntityAddress EntityType = "ADDRESS"
+
+	// EntityDateOfBirth is a date of birth
+	EntityDateOfBirth EntityType = "DOB"
+
+	// EntityIDNumber is a government, insurance or medical record number
+	EntityIDNumber EntityType = "ID"
+)
+
+// AllEntityTypes lists every entity type the redactor can detect
+var AllEntityTypes = []EntityType{EntityName, EntityPhone, EntityAddress, EntityDateOfBirth, EntityIDNumber}
+
+// Entity is a known sensitive value, such as a name from a patient profile, that should always be redacted
+type Entity struct {
+	Type  EntityType
+	Value string
+}
+
+// Policy decides which entity types are allowed to leave the server unredacted
+type Policy struct {
+	allowed map[EntityType]bool
+}
+
+// ParsePolicy builds a policy from a comma-separated list of entity types that may be sent as-is,
+// for example "ADDRESS,DOB". An empty list redacts everything.
+func ParsePolicy(allowed string) (Policy, error) {
+	policy := Policy{allowed: make(map[EntityType]bool)}
+
+	for _, item := range strings.Split(allowed, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		valid := false
+		for _, entityType := range AllEntityTypes {
+			if EntityType(item) == entityType {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return Policy{}, fmt.Errorf("unknown PII entity type %q", item)
+		}
+
+		policy.allowed[EntityType(item)] = true
+	}
+
+	return policy, nil
+}
+
+// Allows reports whether the entity type may leave the server unredacted
+func (p Policy) Allows(entityType EntityType) bool {
+	return p.allowed[entityType]
+}
+
+// detector finds one entity type in text. If group is non-zero only that submatch is sensitive.
+type detector struct {
+	entityType EntityType
+	pattern    *regexp.Regexp
+	group      int
+	minDigits  int
+}
+
+// Redactor detects PII and replaces it with placeholders according to a policy
+type Redactor struct {
+	policy    Policy
+	detectors []detector
+}
+
+// New creates a redactor with the given policy
+func New(policy Policy) *Redactor {
+	const date = `(?:\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4}|\d{4}-\d{2}-\d{2}|(?i:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2},?\s+\d{4}|\d{1,2}\s+(?i:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{4})`
+
+	// A street is a house number and capitalised name words; unit optionally follows the suffix
+	const (
+		street            = `\b\d{1,5}\s+(?:[A-Z][A-Za-z]*\s+){1,4}`
+		streetSuffixes    = `street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|terrace|crescent|highway|hwy`
+		ambiguousSuffixes = `close|way|court|ct|dr|place|pl`
+		unit              = `\b\.?(?:,?\s+(?i:apt|apartment|unit|suite|flat)\.?\s*#?\w+)?`
+	)
+
+	// Detectors run in order; earlier, more specific patterns claim text before broader ones
+	return &Redactor{
+		policy: policy,
+		detectors: []detector{
+			{entityType: EntityIDNumber, pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+			{entityType: EntityIDNumber, pattern: regexp.MustCompile(`(?i)\b(?:ssn|nhs|mrn|medicare|medicaid|passport|licen[cs]e|insurance|member|patient|id)(?:\s+(?:number|no\.?|num|#))?\s*[:#]?\s*([A-Z0-9][A-Z0-9-]{4,})\b`), group: 1, minDigits: 4},
+			{entityType: EntityDateOfBirth, pattern: regexp.MustCompile(`(?i)\b(?:dob|d\.o\.b\.?|date of birth|born(?:\s+on)?|birthday(?:\s+is)?)\s*[:\-]?\s*(` + date + `)`), group: 1},
+			{entityType: EntityPhone, pattern: regexp.MustCompile(`\+?\(?\d[\d\s().-]{7,}\d`), minDigits: 9},
+			// Suffixes that are also everyday words ("getting close", "the wrong way") only count after an address cue
+			{entityType: EntityAddress, pattern: regexp.MustCompile(`(?i:\blives? at|\bliving at|\baddress(?:\s+is)?:?)\s+(` + street + `(?i:` + streetSuffixes + `|` + ambiguousSuffixes + `)` + unit + `)`), group: 1},
+			{entityType: EntityAddress, pattern: regexp.MustCompile(street + `(?i:` + streetSuffixes + `)` + unit)},
+			{entityType: EntityName, pattern: regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Dr|Mx)\.?\s+([A-Z][a-z]+(?:\s+[A-Z][a-z]+)?)`), group: 1},
+			// Only explicit name cues: "I'm", "patient is" and the like are followed by symptoms as often as names
+			{entityType: EntityName, pattern: regexp.MustCompile(`(?i:\bname is|\bname's|\bname:)\s+([A-Z][a-z]+(?:\s+[A-Z][a-z]+)?)`), group: 1},
+		},
+	}
+}
+
+// Session tokenises values consistently for one model call so placeholders can be restored in its output
+type Session struct {
+	redactor *Redactor
+	known    []Entity
+
+	mu       sync.Mutex
+	tokens   map[string]string // placeholder -> original value
+	values   map[string]string // type + value -> placeholder
+	counters map[EntityType]int
+}
+
+// NewSession starts a redaction session. Known entities are always redacted unless the policy allows their type.
+func (r *Redactor) NewSession(known ...Entity) *Session {
+	// Longest values first so that "Jane Doe" is replaced before "Jane"
+	sorted := append([]Entity(nil), known...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].Value) > len(sorted[j].Value) })
+
+	return &Session{
+		redactor: r,
+		known:    sorted,
+		tokens:   make(map[string]string),
+		values:   make(map[string]string),
+		counters: make(map[EntityType]int),
+	}
+}
+
+// Redact replaces detected PII in text with placeholders such as [PHONE_1]
+func (s *Session) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entity := range s.known {
+		if entity.Value == "" || s.redactor.policy.Allows(entity.Type) {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(entity.Value) + `\b`)
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			return s.placeholderLocked(entity.Type, match)
+		})
+	}
+
+	for _, d := range s.redactor.detectors {
+		if s.redactor.policy.Allows(d.entityType) {
+			continue
+		}
+		text = s.applyDetectorLocked(d, text)
+	}
+
+	return text
+}
+
+// applyDetectorLocked replaces every match of a detector; the caller must hold s.mu
+func (s *Session) applyDetectorLocked(d detector, text string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, match := range d.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if d.group > 0 {
+			start, end = match[2*d.group], match[2*d.group+1]
+			if start < 0 {
+				continue
+			}
+		}
+
+		value := text[start:end]
+		if d.minDigits > 0 && countDigits(value) < d.minDigits {
+			continue
+		}
+
+		sb.WriteString(text[last:start])
+		sb.WriteString(s.placeholderLocked(d.entityType, strings.TrimSpace(value)))
+		last = end
+	}
+
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// placeholderLocked returns the placeholder for a value, allocating one if needed; the caller must hold s.mu
+func (s *Session) placeholderLocked(entityType EntityType, value string) string {
+	key := string(entityType) + "\x00" + strings.ToLower(value)
+	if token, ok := s.values[key]; ok {
+		return token
+	}
+
+	s.counters[entityType]++
+	token := fmt.Sprintf("[%s_%d]", entityType, s.counters[entityType])
+	s.values[key] = token
+	s.tokens[token] = value
+	return token
+}
+
+// Restore replaces placeholders in model output with the original values
+func (s *Session) Restore(text string) string {
+	return s.restore(text, func(value string) string { return value })
+}
+
+// RestoreJSON replaces placeholders inside a JSON document, escaping the original values so the document stays valid
+func (s *Session) RestoreJSON(text string) string {
+	return s.restore(text, func(value string) string {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		return string(encoded[1 : len(encoded)-1])
+	})
+}
+
+func (s *Session) restore(text string, encode func(string) string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.tokens) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(s.tokens)*2)
+	for token, value := range s.tokens {
+		pairs = append(pairs, token, encode(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Count returns the number of distinct values redacted in this session
+func (s *Session) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tokens)
+}
+
+// knownEntitiesKey is the context key for request-specific known entities
+type knownEntitiesKey struct{}
+
+// WithKnownEntities returns a context carrying values, such as a patient's name, that must always be redacted
+func WithKnownEntities(ctx context.Context, entities ...Entity) context.Context {
+	existing := KnownEntitiesFromContext(ctx)
+	combined := append(append([]Entity(nil), existing...), entities...)
+	return context.WithValue(ctx, knownEntitiesKey{}, combined)
+}
+
+// KnownEntitiesFromContext returns the known entities attached to the context
+func KnownEntitiesFromContext(ctx context.Context) []Entity {
+	entities, _ := ctx.Value(knownEntitiesKey{}).([]Entity)
+	return entities
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
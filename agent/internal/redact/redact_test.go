@@ -0,0 +1,30 @@
+package redact
+
+import "testing"
+
+func TestRedactAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"street suffix", "send help to 12 Main Street now", "send help to [ADDRESS_1] now"},
+		{"abbreviated suffix with unit", "we're at 42 Elm Ave, apt 3B", "we're at [ADDRESS_1]"},
+		{"ambiguous suffix after cue", "I live at 7 Rose Close", "I live at [ADDRESS_1]"},
+		{"ambiguous suffix after address cue", "address: 19 Harbour Way", "address: [ADDRESS_1]"},
+		{"contractions getting close", "contractions 5 minutes apart and getting close", "contractions 5 minutes apart and getting close"},
+		{"wrong way", "fell 3 steps the wrong way", "fell 3 steps the wrong way"},
+		{"saw the dr", "saw 2 days ago the dr", "saw 2 days ago the dr"},
+		{"capitalised ambiguous suffix without cue", "He Fell 3 Steps The Wrong Way", "He Fell 3 Steps The Wrong Way"},
+		{"lowercase street words", "took 2 pills on the road", "took 2 pills on the road"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(Policy{}).NewSession().Redact(tt.text)
+			if got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
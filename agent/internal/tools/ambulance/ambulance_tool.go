@@ -84,6 +84,33 @@ func (t *AmbulanceTool) Name() string {
 	return "Ambulance Dispatch Tool"
 }
 
+// CheckHealth asks the ambulance dispatch service whether it is up
+func (t *AmbulanceTool) CheckHealth(ctx context.Context) error {
+	return tools.CheckHTTPHealth(ctx, "ambulance dispatch service", t.config.APIEndpoint, t.config.APIKey)
+}
+
+// Schema describes the tool for model-driven tool selection
+func (t *AmbulanceTool) Schema() tools.ToolSchema {
+	return tools.ToolSchema{
+		Name:        tools.FunctionDispatchAmbulance,
+		Description: "Dispatch an ambulance to the caller's location. Required for every life-threatening (RED) emergency.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"priority": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"HIGH", "MEDIUM", "LOW"},
+					"description": "Dispatch priority; RED emergencies are always dispatched HIGH",
+				},
+				"crew_notes": map[string]interface{}{
+					"type":        "string",
+					"description": "What the crew should know before arrival: hazards, access, patient condition",
+				},
+			},
+		},
+	}
+}
+
 // IsApplicable determines if this tool is applicable for the given emergency
 func (t *AmbulanceTool) IsApplicable(situation *models.EmergencySituation) bool {
 	// Ambulance is only applicable for critical (RED) cases
@@ -92,12 +119,23 @@ func (t *AmbulanceTool) IsApplicable(situation *models.EmergencySituation) bool
 
 // Execute dispatches an ambulance to the emergency location
 func (t *AmbulanceTool) Execute(ctx context.Context, situation *models.EmergencySituation) (*tools.ToolResponse, error) {
+	// The model may suggest a priority, but never below HIGH for a life-threatening emergency
+	priority := tools.StringArgument(ctx, "priority")
+	if priority == "" || situation.Code == models.CodeRed {
+		priority = getPriorityFromCode(situation.Code)
+	}
+
+	data := map[string]string{"priority": priority}
+	if notes := tools.StringArgument(ctx, "crew_notes"); notes != "" {
+		data["crew_notes"] = notes
+	}
+
 	// For now, just return a placeholder message as requested
 	return &tools.ToolResponse{
 		ToolName:  t.Name(),
 		Success:   true,
 		Message:   "Called Ambulance Dispatch Tool",
-		Data:      map[string]string{},
+		Data:      data,
 		Timestamp: time.Now().Format(time.RFC3339),
 	}, nil
 }
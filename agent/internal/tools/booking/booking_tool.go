@@ -84,6 +84,28 @@ func (t *BookingTool) Name() string {
 	return "Hospital Booking Tool"
 }
 
+// CheckHealth asks the appointment booking service whether it is up
+func (t *BookingTool) CheckHealth(ctx context.Context) error {
+	return tools.CheckHTTPHealth(ctx, "appointment booking service", t.config.APIEndpoint, t.config.APIKey)
+}
+
+// Schema describes the tool for model-driven tool selection
+func (t *BookingTool) Schema() tools.ToolSchema {
+	return tools.ToolSchema{
+		Name:        tools.FunctionBookAppointment,
+		Description: "Find a non-urgent appointment slot at a nearby hospital or clinic. Only for GREEN cases.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "Reason for the visit in plain language",
+				},
+			},
+		},
+	}
+}
+
 // IsApplicable determines if this tool is applicable for the given emergency
 func (t *BookingTool) IsApplicable(situation *models.EmergencySituation) bool {
 	// This tool is only applicable for non-urgent cases
@@ -92,16 +114,21 @@ func (t *BookingTool) IsApplicable(situation *models.EmergencySituation) bool {
 
 // Execute retrieves booking URLs for the nearest hospitals
 func (t *BookingTool) Execute(ctx context.Context, situation *models.EmergencySituation) (*tools.ToolResponse, error) {
+	data := map[string]string{
+		"booking_url": "https://hospital-booking.example.com",
+		"hospital_id": "nearest-hospital-123",
+		"wait_time":   "30 minutes",
+	}
+	if reason := tools.StringArgument(ctx, "reason"); reason != "" {
+		data["reason"] = reason
+	}
+
 	// For now, just return a placeholder message
 	return &tools.ToolResponse{
-		ToolName: t.Name(),
-		Success:  true,
-		Message:  "Called Hospital Booking Tool",
-		Data: map[string]string{
-			"booking_url": "https://hospital-booking.example.com",
-			"hospital_id": "nearest-hospital-123",
-			"wait_time":   "30 minutes",
-		},
+		ToolName:  t.Name(),
+		Success:   true,
+		Message:   "Called Hospital Booking Tool",
+		Data:      data,
 		Timestamp: time.Now().Format(time.RFC3339),
 	}, nil
 }
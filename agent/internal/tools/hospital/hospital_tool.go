@@ -3,6 +3,8 @@ package hospital
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"agent/internal/models"
@@ -84,6 +86,33 @@ func (t *HospitalTool) Name() string {
 	return "Hospital Communication Tool"
 }
 
+// CheckHealth asks the hospital notification service whether it is up
+func (t *HospitalTool) CheckHealth(ctx context.Context) error {
+	return tools.CheckHTTPHealth(ctx, "hospital notification service", t.config.APIEndpoint, t.config.APIKey)
+}
+
+// Schema describes the tool for model-driven tool selection
+func (t *HospitalTool) Schema() tools.ToolSchema {
+	return tools.ToolSchema{
+		Name:        tools.FunctionNotifyHospital,
+		Description: "Pre-alert the nearest emergency department so it can prepare for the incoming patient. Use for RED and YELLOW emergencies.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"handoff_notes": map[string]interface{}{
+					"type":        "string",
+					"description": "Short clinical handoff for the receiving team: suspected condition, key symptoms, relevant history",
+				},
+				"required_specialty": map[string]interface{}{
+					"type":        "string",
+					"description": "Specialist team the hospital should prepare, e.g. cardiology, trauma, stroke, burns",
+				},
+			},
+			"required": []string{"handoff_notes"},
+		},
+	}
+}
+
 // IsApplicable determines if this tool is applicable for the given emergency
 func (t *HospitalTool) IsApplicable(situation *models.EmergencySituation) bool {
 	// Hospital tool is applicable for urgent (RED/YELLOW) cases
@@ -92,12 +121,46 @@ func (t *HospitalTool) IsApplicable(situation *models.EmergencySituation) bool {
 
 // Execute sends the emergency information to the hospital
 func (t *HospitalTool) Execute(ctx context.Context, situation *models.EmergencySituation) (*tools.ToolResponse, error) {
+	data := map[string]string{}
+	if notes := tools.StringArgument(ctx, "handoff_notes"); notes != "" {
+		data["handoff_notes"] = notes
+	}
+	if specialty := tools.StringArgument(ctx, "required_specialty"); specialty != "" {
+		data["required_specialty"] = specialty
+	}
+
+	// Hand over what the receiving team needs to know about the patient before arrival
+	if patient := situation.PatientInfo; patient != nil {
+		if len(patient.Allergies) > 0 {
+			data["patient_allergies"] = strings.Join(patient.Allergies, ", ")
+		}
+		if len(patient.Medications) > 0 {
+			data["patient_medications"] = strings.Join(patient.Medications, ", ")
+		}
+		if len(patient.Conditions) > 0 {
+			data["patient_conditions"] = strings.Join(patient.Conditions, ", ")
+		}
+		if d := patient.AdvanceDirectives; d != nil {
+			data["do_not_resuscitate"] = strconv.FormatBool(d.DoNotResuscitate)
+			if d.HealthcareProxy != "" {
+				data["healthcare_proxy"] = d.HealthcareProxy
+			}
+		}
+		if len(patient.EmergencyContacts) > 0 {
+			contact := patient.EmergencyContacts[0]
+			data["emergency_contact"] = contact.Name + " " + contact.Phone
+			if contact.Relationship != "" {
+				data["emergency_contact"] = fmt.Sprintf("%s (%s) %s", contact.Name, contact.Relationship, contact.Phone)
+			}
+		}
+	}
+
 	// For now, just return a placeholder message as requested
 	return &tools.ToolResponse{
 		ToolName:  t.Name(),
 		Success:   true,
 		Message:   "Called Hospital Communication Tool",
-		Data:      map[string]string{},
+		Data:      data,
 		Timestamp: time.Now().Format(time.RFC3339),
 	}, nil
 }
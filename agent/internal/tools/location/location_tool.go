@@ -114,6 +114,23 @@ func (t *LocationTool) Name() string {
 	return "Location Services Tool"
 }
 
+// CheckHealth asks the location service whether it is up
+func (t *LocationTool) CheckHealth(ctx context.Context) error {
+	return tools.CheckHTTPHealth(ctx, "location service", t.config.APIEndpoint, t.config.APIKey)
+}
+
+// Schema describes the tool for model-driven tool selection
+func (t *LocationTool) Schema() tools.ToolSchema {
+	return tools.ToolSchema{
+		Name:        tools.FunctionFindFacilities,
+		Description: "Look up hospitals and ambulance stations near the caller's location, sorted by distance.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}
+}
+
 // IsApplicable determines if this tool is applicable for the given emergency
 func (t *LocationTool) IsApplicable(situation *models.EmergencySituation) bool {
 	// This tool is applicable if the emergency situation has location information
@@ -184,7 +201,7 @@ func (t *LocationTool) Execute(ctx context.Context, situation *models.EmergencyS
 
 	// Calculate distances and sort by distance
 	for i := range facilities {
-		facilities[i].Distance = calculateDistance(
+		facilities[i].Distance = CalculateDistance(
 			situation.Location.Latitude,
 			situation.Location.Longitude,
 			facilities[i].Latitude,
@@ -292,8 +309,8 @@ func (t *LocationTool) createResponse(situation *models.EmergencySituation, faci
 	}, nil
 }
 
-// calculateDistance uses the Haversine formula to calculate distance between coordinates in kilometers
-func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
+// CalculateDistance uses the Haversine formula to calculate distance between coordinates in kilometers
+func CalculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371.0 // Earth radius in kilometers
 
 	dLat := toRadians(lat2 - lat1)
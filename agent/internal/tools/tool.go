@@ -2,6 +2,9 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
 
 	"agent/internal/models"
 )
@@ -15,11 +18,34 @@ type ToolResponse struct {
 	Timestamp string            `json:"timestamp"`
 }
 
+// Function names under which tools are offered to language models
+const (
+	FunctionFindFacilities    = "find_nearby_facilities"
+	FunctionNotifyHospital    = "notify_hospital"
+	FunctionDispatchAmbulance = "dispatch_ambulance"
+	FunctionBookAppointment   = "book_appointment"
+)
+
+// ToolSchema describes a tool to a language model for function calling
+type ToolSchema struct {
+	// Name is the function name, restricted to letters, digits and underscores
+	Name string
+
+	// Description tells the model when the tool should be used
+	Description string
+
+	// Parameters is a JSON schema object describing the arguments the tool accepts
+	Parameters map[string]interface{}
+}
+
 // EmergencyTool defines the interface for emergency response tools
 type EmergencyTool interface {
 	// Name returns the name of the tool
 	Name() string
 
+	// Schema describes the tool and its parameters for model-driven tool selection
+	Schema() ToolSchema
+
 	// IsApplicable determines if this tool is applicable for the given emergency situation
 	IsApplicable(situation *models.EmergencySituation) bool
 
@@ -27,6 +53,37 @@ type EmergencyTool interface {
 	Execute(ctx context.Context, situation *models.EmergencySituation) (*ToolResponse, error)
 }
 
+// HealthChecker is implemented by tools that can check the service behind them is up
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckHTTPHealth asks the service at endpoint whether it is up by calling its /health route. The request
+// is bounded by ctx rather than a tool's own client timeout, so a hung service cannot stall readiness probes.
+func CheckHTTPHealth(ctx context.Context, service, endpoint, apiKey string) error {
+	if endpoint == "" {
+		return fmt.Errorf("no %s endpoint is configured", service)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create %s health request: %w", service, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", service, resp.StatusCode)
+	}
+	return nil
+}
+
 // ToolRegistry maintains a registry of available emergency tools
 type ToolRegistry interface {
 	// Register adds a tool to the registry
@@ -38,3 +95,26 @@ type ToolRegistry interface {
 	// GetApplicable returns tools applicable to the given emergency situation
 	GetApplicable(situation *models.EmergencySituation) []EmergencyTool
 }
+
+// argumentsKey is the context key for model-supplied tool arguments
+type argumentsKey struct{}
+
+// WithArguments returns a context carrying the arguments a model supplied for a tool call
+func WithArguments(ctx context.Context, args map[string]interface{}) context.Context {
+	return context.WithValue(ctx, argumentsKey{}, args)
+}
+
+// ArgumentsFromContext returns the model-supplied arguments for the current tool call, if any
+func ArgumentsFromContext(ctx context.Context) map[string]interface{} {
+	args, _ := ctx.Value(argumentsKey{}).(map[string]interface{})
+	return args
+}
+
+// StringArgument returns a model-supplied argument formatted as a string, or an empty string if absent
+func StringArgument(ctx context.Context, name string) string {
+	value, ok := ArgumentsFromContext(ctx)[name]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,156 @@
+package triage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"agent/internal/models"
+)
+
+// Points at which a structured assessment becomes urgent or critical
+const (
+	assessmentYellowPoints = 3
+	assessmentRedPoints    = 6
+)
+
+// symptomPoints weighs symptoms that need prompt care even when the patient reports little pain
+var symptomPoints = []struct {
+	symptom string
+	points  int
+}{
+	{"chest pain", 4},
+	{"difficulty breathing", 4},
+	{"shortness of breath", 4},
+	{"slurred speech", 5},
+	{"facial droop", 5},
+	{"one-sided weakness", 5},
+	{"confusion", 3},
+	{"vomiting blood", 4},
+	{"coughing up blood", 4},
+	{"blood in stool", 3},
+	{"head injury", 3},
+	{"severe headache", 3},
+	{"high fever", 2},
+	{"abdominal pain", 2},
+	{"bleeding", 2},
+	{"fainting", 2},
+	{"vomiting", 1},
+	{"dizziness", 1},
+}
+
+// highRiskConditions make the same symptoms more dangerous
+var highRiskConditions = []string{
+	"heart", "copd", "asthma", "diabetes", "immunocompromised", "cancer", "kidney", "sickle cell",
+}
+
+// anticoagulants raise the danger of any bleeding or head injury
+var anticoagulants = []string{
+	"warfarin", "apixaban", "rivaroxaban", "dabigatran", "edoxaban", "heparin", "clopidogrel",
+}
+
+// AssessmentScore is the deterministic result of scoring a structured assessment
+type AssessmentScore struct {
+	Code       models.TriageCode
+	Confidence float64
+	Points     int
+	Findings   []string
+}
+
+// ScoreAssessment triages a structured assessment with fixed rules: red-flag symptoms and critical vitals
+// are RED outright, and otherwise points for symptoms, pain, onset, age, pregnancy, conditions and
+// medications decide between GREEN, YELLOW and RED.
+func ScoreAssessment(a *models.SymptomAssessment, now time.Time) AssessmentScore {
+	var score AssessmentScore
+	add := func(points int, finding string) {
+		score.Points += points
+		score.Findings = append(score.Findings, fmt.Sprintf("%s (+%d)", finding, points))
+	}
+
+	text := strings.ToLower(strings.Join(a.Symptoms, "\n") + "\n" + a.Description)
+	redFlag := SuspectsCritical(text)
+	if redFlag {
+		score.Findings = append(score.Findings, "red-flag symptom")
+	}
+
+	for _, weight := range symptomPoints {
+		if strings.Contains(text, weight.symptom) {
+			add(weight.points, weight.symptom)
+		}
+	}
+
+	if a.PainScore != nil {
+		switch pain := *a.PainScore; {
+		case pain >= 8:
+			add(3, fmt.Sprintf("pain %d/10", pain))
+		case pain >= 5:
+			add(2, fmt.Sprintf("pain %d/10", pain))
+		}
+		if *a.PainScore >= 5 && a.Acute(now) {
+			add(1, "sudden onset")
+		}
+	}
+	if a.Longstanding(now) && score.Points > 0 {
+		score.Points--
+		score.Findings = append(score.Findings, "longstanding (-1)")
+	}
+
+	switch {
+	case a.Age >= 75:
+		add(2, fmt.Sprintf("age %d", a.Age))
+	case a.Age >= 65:
+		add(1, fmt.Sprintf("age %d", a.Age))
+	}
+
+	if a.Pregnant != nil && *a.Pregnant {
+		if strings.Contains(text, "bleeding") || strings.Contains(text, "abdominal pain") {
+			redFlag = true
+			score.Findings = append(score.Findings, "pregnant with bleeding or abdominal pain")
+		} else {
+			add(1, "pregnant")
+		}
+	}
+
+	risky := 0
+	for _, condition := range a.Conditions {
+		if containsAny(strings.ToLower(condition), highRiskConditions) && risky < 2 {
+			risky++
+			add(1, condition)
+		}
+	}
+
+	for _, medication := range a.Medications {
+		if containsAny(strings.ToLower(medication), anticoagulants) &&
+			(strings.Contains(text, "bleeding") || strings.Contains(text, "head injury")) {
+			add(3, medication+" with bleeding risk")
+			break
+		}
+	}
+
+	vitalsCode, vitalsFindings := VitalsFloor(a.Vitals)
+	score.Findings = append(score.Findings, vitalsFindings...)
+
+	switch {
+	case redFlag || vitalsCode == models.CodeRed || score.Points >= assessmentRedPoints:
+		score.Code = models.CodeRed
+	case vitalsCode == models.CodeYellow || score.Points >= assessmentYellowPoints:
+		score.Code = models.CodeYellow
+	default:
+		score.Code = models.CodeGreen
+	}
+	score.Confidence = min(0.6+0.05*float64(score.Points), 0.95)
+	if redFlag || vitalsCode != models.CodeUnknown {
+		score.Confidence = 0.95
+	}
+	return score
+}
+
+// containsAny reports whether text contains any of the terms
+func containsAny(text string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,24 @@ import (
 	"agent/internal/models"
 )
 
+// criticalKeywords indicate a potentially life-threatening emergency
+var criticalKeywords = []string{
+	"not breathing", "heart attack", "stroke", "unconscious", "severe bleeding",
+	"choking", "drowning", "seizure", "anaphylaxis", "overdose",
+}
+
+// SuspectsCritical reports whether text mentions any sign of a life-threatening emergency.
+// It is a cheap pre-screen used before any model has assessed the call.
+func SuspectsCritical(text string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range criticalKeywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
 // RuleBasedClassifier implements a simple rule-based classifier
 type RuleBasedClassifier struct {
 	redKeywords    []string
@@ -24,10 +42,7 @@ func NewRuleBasedClassifier(config ClassifierConfig) *RuleBasedClassifier {
 
 	return &RuleBasedClassifier{
 		// These are very simplified examples - in a real system, these would be much more comprehensive
-		redKeywords: []string{
-			"not breathing", "heart attack", "stroke", "unconscious", "severe bleeding",
-			"choking", "drowning", "seizure", "anaphylaxis", "overdose",
-		},
+		redKeywords: criticalKeywords,
 		yellowKeywords: []string{
 			"broken bone", "deep cut", "burn", "concussion", "severe pain",
 			"high fever", "difficulty breathing", "chest pain", "allergic reaction",
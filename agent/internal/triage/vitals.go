@@ -0,0 +1,80 @@
+package triage
+
+import (
+	"fmt"
+
+	"agent/internal/models"
+)
+
+// VitalsFloor returns the lowest triage code the patient's vitals allow and the findings behind it.
+// The thresholds follow common adult early-warning criteria; nil or unmeasured vitals give CodeUnknown.
+func VitalsFloor(v *models.Vitals) (models.TriageCode, []string) {
+	if v == nil {
+		return models.CodeUnknown, nil
+	}
+
+	var critical, urgent []string
+
+	if v.Breathing != nil && !*v.Breathing {
+		critical = append(critical, "not breathing")
+	}
+	if v.Conscious != nil && !*v.Conscious {
+		critical = append(critical, "unconscious")
+	}
+
+	switch {
+	case v.HeartRate == 0:
+	case v.HeartRate < 40 || v.HeartRate > 130:
+		critical = append(critical, fmt.Sprintf("heart rate %d", v.HeartRate))
+	case v.HeartRate < 50 || v.HeartRate > 110:
+		urgent = append(urgent, fmt.Sprintf("heart rate %d", v.HeartRate))
+	}
+
+	switch {
+	case v.RespiratoryRate == 0:
+	case v.RespiratoryRate < 8 || v.RespiratoryRate > 30:
+		critical = append(critical, fmt.Sprintf("respiratory rate %d", v.RespiratoryRate))
+	case v.RespiratoryRate < 12 || v.RespiratoryRate > 24:
+		urgent = append(urgent, fmt.Sprintf("respiratory rate %d", v.RespiratoryRate))
+	}
+
+	switch {
+	case v.SystolicBP == 0:
+	case v.SystolicBP < 90 || v.SystolicBP > 220:
+		critical = append(critical, fmt.Sprintf("systolic blood pressure %d", v.SystolicBP))
+	case v.SystolicBP < 100 || v.SystolicBP > 180:
+		urgent = append(urgent, fmt.Sprintf("systolic blood pressure %d", v.SystolicBP))
+	}
+
+	switch {
+	case v.OxygenSaturation == 0:
+	case v.OxygenSaturation < 90:
+		critical = append(critical, fmt.Sprintf("oxygen saturation %.0f%%", v.OxygenSaturation))
+	case v.OxygenSaturation < 94:
+		urgent = append(urgent, fmt.Sprintf("oxygen saturation %.0f%%", v.OxygenSaturation))
+	}
+
+	switch {
+	case v.Temperature == 0:
+	case v.Temperature < 35 || v.Temperature >= 40:
+		critical = append(critical, fmt.Sprintf("temperature %.1f°C", v.Temperature))
+	case v.Temperature < 36 || v.Temperature >= 38.5:
+		urgent = append(urgent, fmt.Sprintf("temperature %.1f°C", v.Temperature))
+	}
+
+	switch {
+	case v.GlasgowComaScale == 0:
+	case v.GlasgowComaScale <= 8:
+		critical = append(critical, fmt.Sprintf("GCS %d", v.GlasgowComaScale))
+	case v.GlasgowComaScale < 15:
+		urgent = append(urgent, fmt.Sprintf("GCS %d", v.GlasgowComaScale))
+	}
+
+	switch {
+	case len(critical) > 0:
+		return models.CodeRed, critical
+	case len(urgent) > 0:
+		return models.CodeYellow, urgent
+	}
+	return models.CodeUnknown, nil
+}
@@ -11,7 +11,7 @@ import {
 import { Picker } from '@react-native-picker/picker';
 import Slider from '@react-native-community/slider';
 import VoiceRecorder from '../components/VoiceRecorder';
-import { API_ENDPOINTS } from '../utils/config';
+import { API_ENDPOINTS, authHeaders } from '../utils/config';
 
 const AssessmentScreen = ({ navigation }) => {
   const [symptoms, setSymptoms] = useState('');
@@ -26,12 +26,34 @@ const AssessmentScreen = ({ navigation }) => {
       setIsSubmitting(true);
       setError('');
       
-      // For now, just navigate to the emergency screen
-      setTimeout(() => {
+      if (symptoms.trim() === '') {
+        setError('Please describe your symptoms.');
         setIsSubmitting(false);
-        navigation.navigate('EmergencyResults');
-      }, 1500);
-      
+        return;
+      }
+
+      const response = await fetch(API_ENDPOINTS.ASSESSMENT, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Accept': 'application/json',
+          ...authHeaders(),
+        },
+        body: JSON.stringify({
+          description: symptoms.trim(),
+          duration,
+          pain_score: painLevel,
+        })
+      });
+
+      const result = await response.json();
+      if (!response.ok) {
+        throw new Error(result.error?.message || `Server returned ${response.status}`);
+      }
+
+      setIsSubmitting(false);
+      navigation.navigate('EmergencyResults', { assessment: result });
+
     } catch (err) {
       console.error('Error submitting assessment:', err);
       setError('Failed to submit assessment. Please try again.');
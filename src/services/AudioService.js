@@ -2,6 +2,7 @@ import * as FileSystem from 'expo-file-system';
 import { Audio } from 'expo-av';
 import { Platform } from 'react-native';
 import LocationService from './LocationService';
+import { authHeaders } from '../utils/config';
 
 class AudioService {
   constructor() {
@@ -134,11 +135,19 @@ class AudioService {
         body: formData,
         headers: {
           'Content-Type': 'multipart/form-data',
+          ...authHeaders(),
         },
       });
       
       if (!response.ok) {
-        throw new Error(`Server responded with ${response.status}`);
+        let errorMessage = `Server responded with ${response.status}`;
+        try {
+          const errorData = await response.json();
+          errorMessage = errorData.error?.message || errorMessage;
+        } catch (e) {
+          // Not a JSON error body; keep the status message
+        }
+        throw new Error(errorMessage);
       }
       
       const result = await response.json();
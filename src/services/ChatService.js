@@ -1,8 +1,20 @@
-import { API_ENDPOINTS } from '../utils/config';
+import { API_ENDPOINTS, authHeaders } from '../utils/config';
 
 class ChatService {
+  constructor() {
+    // Server-side conversation session so follow-up messages refine the same emergency
+    this.sessionId = null;
+  }
+
   /**
-   * Send text message to emergency endpoint
+   * Forget the current conversation so the next message starts a new emergency
+   */
+  resetConversation() {
+    this.sessionId = null;
+  }
+
+  /**
+   * Send text message to the emergency chat endpoint
    * @param {string} message - The emergency message from the user
    * @param {Object} location - Object containing latitude and longitude
    * @returns {Promise} - Promise that resolves with formatted emergency response
@@ -11,9 +23,12 @@ class ChatService {
     try {
       const payload = {
         text: message,
-        timestamp: new Date().toISOString(),
       };
 
+      if (this.sessionId) {
+        payload.session_id = this.sessionId;
+      }
+
       // Add location data if available
       if (location && location.latitude && location.longitude) {
         payload.location = {
@@ -23,13 +38,14 @@ class ChatService {
       }
 
       console.log('Sending emergency message with payload:', JSON.stringify(payload));
-      console.log('API Endpoint:', API_ENDPOINTS.EMERGENCY_TEXT);
+      console.log('API Endpoint:', API_ENDPOINTS.EMERGENCY_CHAT);
 
-      const response = await fetch(API_ENDPOINTS.EMERGENCY_TEXT, {
+      const response = await fetch(API_ENDPOINTS.EMERGENCY_CHAT, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
-          'Accept': 'application/json'
+          'Accept': 'application/json',
+          ...authHeaders(),
         },
         body: JSON.stringify(payload)
       });
@@ -44,7 +60,7 @@ class ChatService {
           // It's JSON, try to parse
           try {
             const errorData = await response.json();
-            errorMessage = errorData.error || `Server error: ${response.status}`;
+            errorMessage = errorData.error?.message || `Server error: ${response.status}`;
           } catch (e) {
             errorMessage = `Server error: ${response.status}`;
           }
@@ -58,6 +74,11 @@ class ChatService {
           }
         }
 
+        // The session has expired on the server, start a fresh conversation next time
+        if (response.status === 404) {
+          this.resetConversation();
+        }
+
         throw new Error(errorMessage);
       }
       
@@ -72,10 +93,15 @@ class ChatService {
 
       const data = await response.json();
       console.log('Emergency API response:', data);
-      
-      // Format the response for display
-      const formattedResponse = this.formatEmergencyResponse(data);
-      return formattedResponse;
+
+      this.sessionId = data.session_id || this.sessionId;
+
+      // Format the response for display, leading with the agent's reply
+      const formattedResponse = this.formatEmergencyResponse(data.emergency);
+      if (data.reply) {
+        formattedResponse.message = `${data.reply}\n\n${formattedResponse.message}`;
+      }
+      return { ...formattedResponse, reply: data.reply, session_id: data.session_id };
     } catch (error) {
       console.error('Emergency message error:', error);
       throw error;
@@ -2,12 +2,22 @@
 // Using API endpoints exclusively from environment variables
 import { 
   API_BASE_URL, 
+  API_KEY,
   GOOGLE_PLACES_API_KEY,
 } from "@env";
 
 // Use the base API URL from environment variable
 const BASE_URL = API_BASE_URL || '';
 
+// API key the backend issued to this app; every backend request must carry it
+export const BACKEND_API_KEY = API_KEY || '';
+
+// Headers that authenticate a request to the backend
+export const authHeaders = () => (BACKEND_API_KEY ? { 'X-API-Key': BACKEND_API_KEY } : {});
+
+// WebSockets cannot send headers, so the live call passes the key as a query parameter
+const LIVE_AUTH = BACKEND_API_KEY ? `?access_token=${encodeURIComponent(BACKEND_API_KEY)}` : '';
+
 export const API_ENDPOINTS = {
   // Health check endpoint (or construct from base URL if not specifically provided)
   HEALTH: `${BASE_URL}/health`,
@@ -15,6 +25,11 @@ export const API_ENDPOINTS = {
   // Emergency endpoints
   EMERGENCY: `${BASE_URL}/emergency`,
   EMERGENCY_TEXT: `${BASE_URL}/emergency/text`,
+  EMERGENCY_CHAT: `${BASE_URL}/emergency/chat`,
+  ASSESSMENT: `${BASE_URL}/assessment`,
+
+  // WebSocket for streaming audio while the caller is still recording
+  EMERGENCY_LIVE: `${BASE_URL.replace(/^http/, 'ws')}/emergency/live${LIVE_AUTH}`,
   
   // Google Places API key from environment variables
   GOOGLE_PLACES_API_KEY: GOOGLE_PLACES_API_KEY || '',